@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"zene/core/logger"
@@ -27,6 +28,7 @@ var ArtworkFolder string
 var AlbumArtFolder string
 var ArtistArtFolder string
 var PodcastArtFolder string
+var WaveformFolder string
 var AudioCacheFolder string
 var AudioCacheMaxMB int
 var AudioCacheMaxDays int
@@ -36,6 +38,22 @@ var AdminEmail string
 var UserAvatarFolder string
 var DefaultBitRate int
 var FfprobeConcurrentProcesses int
+var NativeTagReader bool
+var ReplayGainAnalysis bool
+var SyntheticIds bool
+var WatchMusicDirs bool
+var WatchDebounceSeconds int
+var JobSchedules map[string]string
+var ScanMaxDeletionRatio float64
+var DeletedTrackRetentionDays int
+var HlsBitRates []int
+var TranscodeMaxConcurrent int
+var TranscodeMaxPerUser int
+var TranscodeQueueTimeoutSeconds int
+var CacheWarmupConcurrency int
+var CacheWarmupTracks int
+var CacheWarmupAlbums int
+var WaveformPrecompute bool
 
 func LoadConfig() {
 
@@ -70,6 +88,7 @@ func LoadConfig() {
 	AlbumArtFolder = filepath.Join(ArtworkFolder, "album")
 	ArtistArtFolder = filepath.Join(ArtworkFolder, "artist")
 	PodcastArtFolder = filepath.Join(ArtworkFolder, "podcasts")
+	WaveformFolder = filepath.Join(ArtworkFolder, "waveforms")
 
 	podcastDirectory := os.Getenv("PODCAST_DIRECTORY")
 	if podcastDirectory == "" {
@@ -144,6 +163,18 @@ func LoadConfig() {
 		FfprobeConcurrentProcesses = ffprobeConcurrentProcessesInt
 	}
 
+	NativeTagReader, err = strconv.ParseBool(cmp.Or(os.Getenv("NATIVE_TAG_READER"), "true"))
+	if err != nil {
+		logger.Printf("Invalid NATIVE_TAG_READER environment variable, defaulting to true: %v", err)
+		NativeTagReader = true
+	}
+
+	ReplayGainAnalysis, err = strconv.ParseBool(cmp.Or(os.Getenv("REPLAYGAIN_ANALYSIS"), "true"))
+	if err != nil {
+		logger.Printf("Invalid REPLAYGAIN_ANALYSIS environment variable, defaulting to true: %v", err)
+		ReplayGainAnalysis = true
+	}
+
 	audioFileTypesEnv := cmp.Or(os.Getenv("AUDIO_FILE_TYPES"), ".aac,.alac,.flac,.m4a,.mp3,.ogg,.opus,.wav,.wma")
 	AudioFileTypes = strings.Split(audioFileTypesEnv, ",")
 	for i, ext := range AudioFileTypes {
@@ -151,6 +182,104 @@ func LoadConfig() {
 	}
 	logger.Printf("Audio file types: %v", AudioFileTypes)
 
+	SyntheticIds, _ = strconv.ParseBool(os.Getenv("SYNTHETIC_IDS"))
+	if SyntheticIds {
+		logger.Printf("Synthetic IDs enabled, files without MusicBrainz tags will be imported")
+	}
+
+	WatchMusicDirs, err = strconv.ParseBool(cmp.Or(os.Getenv("WATCH_MUSIC_DIRS"), "true"))
+	if err != nil {
+		logger.Printf("Invalid WATCH_MUSIC_DIRS environment variable, defaulting to true: %v", err)
+		WatchMusicDirs = true
+	}
+
+	watchDebounceSeconds := os.Getenv("WATCH_DEBOUNCE_SECONDS")
+	WatchDebounceSeconds, err = strconv.Atoi(watchDebounceSeconds)
+	if err != nil || WatchDebounceSeconds < 1 {
+		WatchDebounceSeconds = 5
+	}
+
+	// a scan refuses to delete more than this fraction of a music folder's files, in case a mount has dropped
+	ScanMaxDeletionRatio, err = strconv.ParseFloat(cmp.Or(os.Getenv("SCAN_MAX_DELETION_RATIO"), "0.5"), 64)
+	if err != nil || ScanMaxDeletionRatio <= 0 {
+		logger.Printf("Invalid SCAN_MAX_DELETION_RATIO environment variable, defaulting to 0.5")
+		ScanMaxDeletionRatio = 0.5
+	}
+
+	deletedTrackRetentionDays := os.Getenv("DELETED_TRACK_RETENTION_DAYS")
+	DeletedTrackRetentionDays, err = strconv.Atoi(deletedTrackRetentionDays)
+	if err != nil || DeletedTrackRetentionDays < 0 {
+		DeletedTrackRetentionDays = 30
+	}
+
+	// HLS playlists offer a variant for each bit rate, so clients can switch between them as their connection changes
+	HlsBitRates = []int{}
+	for _, bitRate := range strings.Split(cmp.Or(os.Getenv("HLS_BIT_RATES"), "96,160,320"), ",") {
+		bitRateInt, err := strconv.Atoi(strings.TrimSpace(bitRate))
+		if err != nil || bitRateInt <= 0 {
+			logger.Printf("Ignoring invalid bit rate %q in HLS_BIT_RATES", bitRate)
+			continue
+		}
+		HlsBitRates = append(HlsBitRates, bitRateInt)
+	}
+	if len(HlsBitRates) == 0 {
+		HlsBitRates = []int{96, 160, 320}
+	}
+	slices.Sort(HlsBitRates)
+
+	// transcodes past these limits wait in a queue, and fail if they wait for longer than TranscodeQueueTimeoutSeconds
+	TranscodeMaxConcurrent, err = strconv.Atoi(os.Getenv("TRANSCODE_MAX_CONCURRENT"))
+	if err != nil || TranscodeMaxConcurrent < 1 {
+		TranscodeMaxConcurrent = runtime.NumCPU()
+	}
+
+	TranscodeMaxPerUser, err = strconv.Atoi(cmp.Or(os.Getenv("TRANSCODE_MAX_PER_USER"), "2"))
+	if err != nil || TranscodeMaxPerUser < 0 {
+		logger.Printf("Invalid TRANSCODE_MAX_PER_USER environment variable, defaulting to 2")
+		TranscodeMaxPerUser = 2
+	}
+
+	TranscodeQueueTimeoutSeconds, err = strconv.Atoi(os.Getenv("TRANSCODE_QUEUE_TIMEOUT_SECONDS"))
+	if err != nil || TranscodeQueueTimeoutSeconds < 1 {
+		TranscodeQueueTimeoutSeconds = 30
+	}
+
+	// the cache warm-up job pre-transcodes the next CacheWarmupTracks of each user's play queue and the tracks of their
+	// CacheWarmupAlbums most recently starred and added albums, CacheWarmupConcurrency at a time, and is off unless it is set
+	CacheWarmupConcurrency, err = strconv.Atoi(cmp.Or(os.Getenv("CACHE_WARMUP_CONCURRENCY"), "0"))
+	if err != nil || CacheWarmupConcurrency < 0 {
+		logger.Printf("Invalid CACHE_WARMUP_CONCURRENCY environment variable, defaulting to 0")
+		CacheWarmupConcurrency = 0
+	}
+
+	CacheWarmupTracks, err = strconv.Atoi(cmp.Or(os.Getenv("CACHE_WARMUP_TRACKS"), "10"))
+	if err != nil || CacheWarmupTracks < 0 {
+		logger.Printf("Invalid CACHE_WARMUP_TRACKS environment variable, defaulting to 10")
+		CacheWarmupTracks = 10
+	}
+
+	CacheWarmupAlbums, err = strconv.Atoi(cmp.Or(os.Getenv("CACHE_WARMUP_ALBUMS"), "5"))
+	if err != nil || CacheWarmupAlbums < 0 {
+		logger.Printf("Invalid CACHE_WARMUP_ALBUMS environment variable, defaulting to 5")
+		CacheWarmupAlbums = 5
+	}
+
+	// waveforms are computed when they are first requested, or for the tracks each scan adds or changes when WaveformPrecompute is true
+	WaveformPrecompute, err = strconv.ParseBool(cmp.Or(os.Getenv("WAVEFORM_PRECOMPUTE"), "false"))
+	if err != nil {
+		logger.Printf("Invalid WAVEFORM_PRECOMPUTE environment variable, defaulting to false: %v", err)
+		WaveformPrecompute = false
+	}
+
+	// SCHEDULE_<JOB_NAME> overrides the schedule of a scheduled job, eg SCHEDULE_AUDIO_CACHE_CLEANUP="0 4 * * *"
+	JobSchedules = map[string]string{}
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		if jobName, found := strings.CutPrefix(key, "SCHEDULE_"); found && strings.TrimSpace(value) != "" {
+			JobSchedules[strings.ToLower(jobName)] = strings.TrimSpace(value)
+		}
+	}
+
 	AdminUsername = os.Getenv("ADMIN_USERNAME")
 	AdminPassword = os.Getenv("ADMIN_PASSWORD")
 	AdminEmail = os.Getenv("ADMIN_EMAIL")
@@ -0,0 +1,249 @@
+package cue
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"zene/core/io"
+	"zene/core/types"
+)
+
+// INDEX timestamps are mm:ss:ff, where ff is a CD frame
+const framesPerSecond = 75
+
+// Parse reads a CUE sheet. Sheets that are not valid UTF-8 are read as Latin-1, which most ripping tools write by default.
+func Parse(data []byte) (types.CueSheet, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	if !utf8.ValidString(text) {
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		text = string(runes)
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.ReplaceAll(text, "\t", " ")
+
+	sheet := types.CueSheet{}
+	var file *types.CueFile
+	var track *types.CueTrack
+	for lineNumber, line := range strings.Split(text, "\n") {
+		command, arguments := splitCommand(strings.TrimSpace(line))
+		switch command {
+		case "REM":
+			key, value := splitCommand(arguments)
+			switch key {
+			case "GENRE":
+				sheet.Genre = unquote(value)
+			case "DATE":
+				sheet.Date = unquote(value)
+			}
+		case "TITLE":
+			if track != nil {
+				track.Title = unquote(arguments)
+			} else {
+				sheet.Title = unquote(arguments)
+			}
+		case "PERFORMER":
+			if track != nil {
+				track.Performer = unquote(arguments)
+			} else {
+				sheet.Performer = unquote(arguments)
+			}
+		case "SONGWRITER":
+			if track != nil {
+				track.Songwriter = unquote(arguments)
+			} else {
+				sheet.Songwriter = unquote(arguments)
+			}
+		case "ISRC":
+			if track != nil {
+				track.Isrc = unquote(arguments)
+			}
+		case "FILE":
+			// the file type is the last word, the name may be quoted or not
+			name := arguments
+			if index := strings.LastIndex(arguments, " "); index > 0 {
+				name = arguments[:index]
+			}
+			sheet.Files = append(sheet.Files, types.CueFile{Name: unquote(name)})
+			file = &sheet.Files[len(sheet.Files)-1]
+			track = nil
+		case "TRACK":
+			if file == nil {
+				return types.CueSheet{}, fmt.Errorf("line %d: TRACK before FILE", lineNumber+1)
+			}
+			numberString, trackType := splitCommand(arguments)
+			if trackType != "AUDIO" {
+				track = nil
+				continue
+			}
+			number, err := strconv.Atoi(numberString)
+			if err != nil {
+				return types.CueSheet{}, fmt.Errorf("line %d: invalid track number %q", lineNumber+1, numberString)
+			}
+			file.Tracks = append(file.Tracks, types.CueTrack{Number: number, Start: -1})
+			track = &file.Tracks[len(file.Tracks)-1]
+		case "INDEX":
+			if track == nil {
+				continue
+			}
+			index, timestamp := splitCommand(arguments)
+			if index != "01" {
+				continue
+			}
+			start, err := parseTimestamp(timestamp)
+			if err != nil {
+				return types.CueSheet{}, fmt.Errorf("line %d: %v", lineNumber+1, err)
+			}
+			track.Start = start
+		}
+	}
+
+	for i := range sheet.Files {
+		tracks := sheet.Files[i].Tracks
+		for j := range tracks {
+			if tracks[j].Start < 0 {
+				return types.CueSheet{}, fmt.Errorf("track %d has no INDEX 01", tracks[j].Number)
+			}
+			// any pregap before the next track's INDEX 01 is played at the end of this track
+			if j+1 < len(tracks) {
+				tracks[j].End = tracks[j+1].Start
+			}
+		}
+	}
+
+	return sheet, nil
+}
+
+// ReadFile reads and parses a CUE sheet file
+func ReadFile(cuePath string) (types.CueSheet, error) {
+	data, err := os.ReadFile(cuePath)
+	if err != nil {
+		return types.CueSheet{}, err
+	}
+	sheet, err := Parse(data)
+	if err != nil {
+		return types.CueSheet{}, fmt.Errorf("parsing %s: %v", cuePath, err)
+	}
+	return sheet, nil
+}
+
+// FindSheetForAudioFile looks for a CUE sheet in the same directory as an audio file that splits it into tracks.
+// A sheet matches if one of its FILE entries has the audio file's name, ignoring the extension
+// because rips are often converted to another format after the sheet is written.
+func FindSheetForAudioFile(audioFilePath string) (types.CueSheet, types.CueFile, bool) {
+	directoryEntries, err := os.ReadDir(filepath.Dir(audioFilePath))
+	if err != nil {
+		return types.CueSheet{}, types.CueFile{}, false
+	}
+
+	for _, entry := range directoryEntries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".cue") {
+			continue
+		}
+		sheet, err := ReadFile(filepath.Join(filepath.Dir(audioFilePath), entry.Name()))
+		if err != nil {
+			continue
+		}
+		if cueFile, found := GetFileForAudioFile(sheet, audioFilePath); found {
+			return sheet, cueFile, true
+		}
+	}
+	return types.CueSheet{}, types.CueFile{}, false
+}
+
+// GetFileForAudioFile returns the FILE entry in a sheet for an audio file.
+// Files with a single track are ignored, as they are already one track per file.
+func GetFileForAudioFile(sheet types.CueSheet, audioFilePath string) (types.CueFile, bool) {
+	audioFileName := filepath.Base(audioFilePath)
+	audioBaseName := strings.TrimSuffix(audioFileName, filepath.Ext(audioFileName))
+
+	var match types.CueFile
+	found := false
+	for _, file := range sheet.Files {
+		// FILE names can be relative paths with either separator
+		fileName := filepath.Base(strings.ReplaceAll(file.Name, "\\", "/"))
+		if strings.EqualFold(fileName, audioFileName) {
+			match, found = file, true
+			break
+		}
+		if strings.EqualFold(strings.TrimSuffix(fileName, filepath.Ext(fileName)), audioBaseName) {
+			match, found = file, true
+		}
+	}
+
+	if !found || len(match.Tracks) < 2 {
+		return types.CueFile{}, false
+	}
+	return match, true
+}
+
+// GetLatestSheetChangedTime returns the latest changed time of the CUE sheets in a directory,
+// or false if it has none
+func GetLatestSheetChangedTime(directory string) (time.Time, bool) {
+	directoryEntries, err := os.ReadDir(directory)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var latest time.Time
+	found := false
+	for _, entry := range directoryEntries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".cue") {
+			continue
+		}
+		changedTime, err := io.GetChangedTime(filepath.Join(directory, entry.Name()))
+		if err != nil {
+			continue
+		}
+		if !found || changedTime.After(latest) {
+			latest = changedTime
+		}
+		found = true
+	}
+	return latest, found
+}
+
+// GetTrackFilePath returns the file path stored for a CUE sheet track, which is the source file path
+// with the track number before the extension so that it keeps the source's directory and suffix
+func GetTrackFilePath(audioFilePath string, trackNumber int) string {
+	extension := filepath.Ext(audioFilePath)
+	return fmt.Sprintf("%s#%02d%s", strings.TrimSuffix(audioFilePath, extension), trackNumber, extension)
+}
+
+func splitCommand(line string) (string, string) {
+	command, arguments, _ := strings.Cut(line, " ")
+	return strings.ToUpper(command), strings.TrimSpace(arguments)
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// parseTimestamp converts an mm:ss:ff INDEX timestamp to seconds
+func parseTimestamp(timestamp string) (float64, error) {
+	parts := strings.Split(timestamp, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	values := make([]int, 3)
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", timestamp)
+		}
+		values[i] = value
+	}
+	return float64(values[0]*60+values[1]) + float64(values[2])/framesPerSecond, nil
+}
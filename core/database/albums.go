@@ -24,7 +24,7 @@ func SelectAlbumIdByTrackId(ctx context.Context, musicbrainz_track_id string) (s
 }
 
 func SelectAllAlbumsForMusicDir(ctx context.Context, musicDir string, random string, limit string, recent string) ([]types.AlbumsResponse, error) {
-	query := "SELECT DISTINCT album, musicbrainz_album_id, album_artist, musicbrainz_artist_id, genre, release_date, date_added FROM metadata m JOIN music_folders f ON m.music_folder_id = f.id WHERE f.name = ? group by album"
+	query := "SELECT DISTINCT album, musicbrainz_album_id, album_artist, musicbrainz_artist_id, genre, release_date, date_added FROM metadata m JOIN music_folders f ON m.music_folder_id = f.id WHERE f.path = ? group by album"
 
 	if recent == "true" {
 		query += " ORDER BY date_added desc"
@@ -206,7 +206,12 @@ func GetAlbum(ctx context.Context, musicbrainzAlbumId string) (types.AlbumId3, e
 
 	album.Songs = []types.SubsonicChild{}
 
-	return album, nil
+	albums := []types.AlbumId3{album}
+	if err := populateAlbumCredits(ctx, albums); err != nil {
+		logger.Printf("Error populating credits for album %s: %v", musicbrainzAlbumId, err)
+	}
+
+	return albums[0], nil
 }
 
 func GetAlbumByArtistNameAndAlbumName(ctx context.Context, artistName string, albumName string) (types.AlbumId3, error) {
@@ -35,6 +35,12 @@ func SelectArtistByMusicBrainzArtistId(ctx context.Context, musicbrainzArtistId
 	err = DB.QueryRowContext(ctx, query, user.Id, musicbrainzArtistId).Scan(
 		&result.Id, &result.Name, &starred, &result.UserRating, &result.AverageRating,
 	)
+	if err == sql.ErrNoRows {
+		// artists that are only featured or credited as contributors have no metadata rows of their own
+		err = DB.QueryRowContext(ctx, creditedArtistQuery, user.Id, musicbrainzArtistId).Scan(
+			&result.Id, &result.Name, &starred, &result.UserRating, &result.AverageRating,
+		)
+	}
 	if err == sql.ErrNoRows {
 		return types.Artist{}, nil
 	} else if err != nil {
@@ -62,9 +68,30 @@ func SelectArtistByMusicBrainzArtistId(ctx context.Context, musicbrainzArtistId
 
 	result.AlbumCount = len(albums)
 
+	appearsOn, err := GetSongsCreditedToArtist(ctx, musicbrainzArtistId)
+	if err != nil {
+		return result, fmt.Errorf("getting songs credited to artist: %v", err)
+	}
+	result.AppearsOn = appearsOn
+
 	return result, nil
 }
 
+const creditedArtistQuery = `SELECT ta.musicbrainz_artist_id as id,
+		ta.artist_name as name,
+		s.created_at as starred,
+		COALESCE(ur.rating, 0) AS user_rating,
+		COALESCE(AVG(gr.rating),0.0) AS average_rating
+	FROM user_music_folders u
+	join metadata m on m.music_folder_id = u.folder_id
+	join track_artists ta on ta.file_path = m.file_path
+	LEFT JOIN user_stars s ON ta.musicbrainz_artist_id = s.metadata_id AND s.user_id = u.user_id
+	LEFT JOIN user_ratings ur ON ta.musicbrainz_artist_id = ur.metadata_id AND ur.user_id = u.user_id
+	LEFT JOIN user_ratings gr ON ta.musicbrainz_artist_id = gr.metadata_id
+	where u.user_id = ?
+	and ta.musicbrainz_artist_id = ?
+	group by ta.musicbrainz_artist_id`
+
 func GetArtistNameByMusicBrainzArtistId(ctx context.Context, musicBrainzArtistId string) string {
 	var artistName string
 	query := `SELECT artist FROM metadata WHERE musicbrainz_artist_id = ? limit 1`
@@ -15,7 +15,7 @@ func migrateArt(ctx context.Context) {
 }
 
 func SelectAlbumArtByMusicBrainzAlbumId(ctx context.Context, musicbrainzAlbumId string) (types.AlbumArtRow, error) {
-	query := `SELECT aa.date_modified, m.file_path
+	query := `SELECT aa.date_modified, coalesce(m.source_file_path, m.file_path)
 		FROM metadata m
 		left join album_art aa on aa.musicbrainz_album_id = m.musicbrainz_album_id
 		WHERE m.musicbrainz_album_id = ?
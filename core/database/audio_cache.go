@@ -1,7 +1,9 @@
 package database
 
 import (
+	"cmp"
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 	"zene/core/logger"
@@ -12,9 +14,14 @@ import (
 func migrateAudioCache(ctx context.Context) {
 	schema := `CREATE TABLE audio_cache (
 		cache_key TEXT PRIMARY KEY,
-		last_accessed TEXT NOT NULL
+		last_accessed TEXT NOT NULL,
+		source_file_path TEXT NOT NULL DEFAULT '',
+		source_version TEXT NOT NULL DEFAULT ''
 	);`
 	createTable(ctx, schema)
+	addColumn(ctx, "audio_cache", "source_file_path", "TEXT NOT NULL DEFAULT ''")
+	addColumn(ctx, "audio_cache", "source_version", "TEXT NOT NULL DEFAULT ''")
+	createIndex(ctx, "idx_audio_cache_source_file_path", "audio_cache", []string{"source_file_path"}, false)
 }
 
 func SelectStaleAudioCacheEntries(ctx context.Context, olderThan time.Time) ([]string, error) {
@@ -46,15 +53,17 @@ func SelectStaleAudioCacheEntries(ctx context.Context, olderThan time.Time) ([]s
 	return results, nil
 }
 
-func UpsertAudioCacheEntry(ctx context.Context, cache_key string) error {
+// UpsertAudioCacheEntry records a transcode in the cache, and the version of the source file it was transcoded from
+func UpsertAudioCacheEntry(ctx context.Context, cache_key string, sourceFilePath string, sourceVersion string) error {
 	stmt := `
-		INSERT INTO audio_cache (cache_key, last_accessed)
-		VALUES (?, ?)
-		ON CONFLICT(cache_key) DO UPDATE SET last_accessed = ?
+		INSERT INTO audio_cache (cache_key, last_accessed, source_file_path, source_version)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(cache_key) DO UPDATE SET last_accessed = excluded.last_accessed,
+			source_file_path = excluded.source_file_path, source_version = excluded.source_version
 	`
 
 	lastAccessed := logic.GetCurrentTimeFormatted()
-	_, err := DB.ExecContext(ctx, stmt, cache_key, lastAccessed, lastAccessed)
+	_, err := DB.ExecContext(ctx, stmt, cache_key, lastAccessed, sourceFilePath, sourceVersion)
 
 	if err != nil {
 		return fmt.Errorf("upserting audio cache row: %v", err)
@@ -63,6 +72,66 @@ func UpsertAudioCacheEntry(ctx context.Context, cache_key string) error {
 	return nil
 }
 
+// UpdateAudioCacheLastAccessed records that a cached transcode has been served
+func UpdateAudioCacheLastAccessed(ctx context.Context, cache_key string) error {
+	stmt := "UPDATE audio_cache SET last_accessed = ? WHERE cache_key = ?"
+	_, err := DB.ExecContext(ctx, stmt, logic.GetCurrentTimeFormatted(), cache_key)
+
+	if err != nil {
+		return fmt.Errorf("updating audio cache row: %v", err)
+	}
+
+	return nil
+}
+
+func GetAudioCacheEntry(ctx context.Context, cache_key string) (types.AudioCacheEntry, error) {
+	query := "SELECT cache_key, last_accessed, source_file_path, source_version FROM audio_cache WHERE cache_key = ?"
+
+	var result types.AudioCacheEntry
+	var lastAccessedString string
+	err := DB.QueryRowContext(ctx, query, cache_key).Scan(&result.CacheKey, &lastAccessedString, &result.SourceFilePath, &result.SourceVersion)
+	if err != nil {
+		return types.AudioCacheEntry{}, fmt.Errorf("querying audio cache row for %s: %v", cache_key, err)
+	}
+
+	if lastAccessedString != "" {
+		result.LastAccessed, err = time.Parse(time.RFC3339Nano, lastAccessedString)
+		if err != nil {
+			return types.AudioCacheEntry{}, fmt.Errorf("parsing last_accessed time: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// invalidateAudioCache deletes the cache rows of transcodes of files that have changed since they were last scanned,
+// so that they are transcoded again, and the cleanup job removes the files of the old transcodes.
+// It must be called before the changed files are upserted.
+func invalidateAudioCache(ctx context.Context, tx *sql.Tx, metadataSlice []types.Metadata) (int64, error) {
+	stmt := `DELETE FROM audio_cache WHERE source_file_path = ? AND EXISTS (
+		SELECT 1 FROM metadata WHERE file_path = ? AND (date_modified != ? OR coalesce(size, '') != ?)
+	)`
+
+	var invalidated int64
+	invalidatedSources := map[string]bool{}
+	for _, m := range metadataSlice {
+		sourceFilePath := cmp.Or(m.SourceFilePath, m.FilePath)
+		if invalidatedSources[sourceFilePath] {
+			continue
+		}
+		result, err := tx.ExecContext(ctx, stmt, sourceFilePath, m.FilePath, m.DateModified, m.Size)
+		if err != nil {
+			return invalidated, fmt.Errorf("deleting audio cache rows for %s: %v", sourceFilePath, err)
+		}
+		if rows, _ := result.RowsAffected(); rows > 0 {
+			invalidatedSources[sourceFilePath] = true
+			invalidated += rows
+		}
+	}
+
+	return invalidated, nil
+}
+
 func DeleteAudioCacheEntry(cache_key string) error {
 	stmt := "DELETE FROM audio_cache WHERE cache_key = ?"
 	_, err := DB.Exec(stmt, cache_key)
@@ -86,7 +155,7 @@ func DeleteAllAudioCache(ctx context.Context) error {
 }
 
 func SelectAllAudioCacheEntries(ctx context.Context) ([]types.AudioCacheEntry, error) {
-	query := "SELECT cache_key, last_accessed FROM audio_cache"
+	query := "SELECT cache_key, last_accessed, source_file_path, source_version FROM audio_cache"
 
 	rows, err := DB.QueryContext(ctx, query)
 	if err != nil {
@@ -101,7 +170,7 @@ func SelectAllAudioCacheEntries(ctx context.Context) ([]types.AudioCacheEntry, e
 		var result types.AudioCacheEntry
 		var cacheKey string
 		var lastAccessedString string
-		if err := rows.Scan(&cacheKey, &lastAccessedString); err != nil {
+		if err := rows.Scan(&cacheKey, &lastAccessedString, &result.SourceFilePath, &result.SourceVersion); err != nil {
 			logger.Printf("Failed to scan row in SelectAllAudioCacheEntries: %v", err)
 			return []types.AudioCacheEntry{}, err
 		}
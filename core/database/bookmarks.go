@@ -64,6 +64,7 @@ func GetBookmarks(ctx context.Context) ([]types.Bookmark, error) {
 		m.size, m.label,
 		m.duration, m.bitrate, m.file_path as path, m.date_added as created, m.disc_number, m.musicbrainz_artist_id as artist_id,
 		m.genre, m.album_artist, m.bit_depth, m.sample_rate, m.channels,
+		m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak,
 		COALESCE(ur.rating, 0) AS user_rating,
 		COALESCE(AVG(gr.rating), 0.0) AS average_rating,
 		COALESCE(SUM(pc.play_count), 0) AS play_count,
@@ -104,11 +105,13 @@ func GetBookmarks(ctx context.Context) ([]types.Bookmark, error) {
 		var playedString sql.NullString
 		var starredString sql.NullString
 		var durationFloat float64
+		var replayGain replayGainColumns
 
 		err := rows.Scan(&row.Entry.Id, &row.Entry.Parent, &row.Entry.Title, &row.Entry.Album, &row.Entry.Artist,
 			&row.Entry.Track, &row.Entry.Year, &row.Entry.Genre, &row.Entry.CoverArt, &row.Entry.Size, &labelString,
 			&durationFloat, &row.Entry.BitRate, &row.Entry.Path, &row.Entry.Created, &row.Entry.DiscNumber, &row.Entry.ArtistId,
 			&genreString, &albumArtistName, &row.Entry.BitDepth, &row.Entry.SamplingRate, &row.Entry.ChannelCount,
+			&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
 			&row.Entry.UserRating, &row.Entry.AverageRating, &row.Entry.PlayCount, &playedString,
 			&starredString, &albumArtistId,
 			&row.Username,
@@ -142,6 +145,7 @@ func GetBookmarks(ctx context.Context) ([]types.Bookmark, error) {
 		row.Entry.RecordLabels = append(row.Entry.RecordLabels, types.ChildRecordLabel{Name: labelString})
 
 		row.Entry.Duration = int(durationFloat)
+		row.Entry.ReplayGain = replayGain.toChild()
 		row.Entry.Title = row.Entry.Album
 		row.Entry.IsDir = false
 
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// SelectStarredAlbumTrackIds returns the tracks of a user's most recently starred albums, in album and track order
+func SelectStarredAlbumTrackIds(ctx context.Context, userId int, albumLimit int) ([]string, error) {
+	albumQuery := `select s.metadata_id as album_id, s.created_at as sort_key
+		from user_stars s
+		where s.user_id = ? and exists (select 1 from metadata m2 where m2.musicbrainz_album_id = s.metadata_id)
+		order by s.created_at desc
+		limit ?`
+	return selectAlbumTrackIds(ctx, userId, albumQuery, userId, albumLimit)
+}
+
+// SelectRecentAlbumTrackIds returns the tracks of the most recently added albums in a user's music folders, in album and track order
+func SelectRecentAlbumTrackIds(ctx context.Context, userId int, albumLimit int) ([]string, error) {
+	albumQuery := `select m2.musicbrainz_album_id as album_id, min(m2.date_added) as sort_key
+		from metadata m2
+		join user_music_folders f2 on f2.folder_id = m2.music_folder_id
+		where f2.user_id = ?
+		group by m2.musicbrainz_album_id
+		order by sort_key desc
+		limit ?`
+	return selectAlbumTrackIds(ctx, userId, albumQuery, userId, albumLimit)
+}
+
+// selectAlbumTrackIds returns the tracks in a user's music folders of the albums selected by a query,
+// which returns an album_id column and a sort_key column that is highest for the first album
+func selectAlbumTrackIds(ctx context.Context, userId int, albumQuery string, albumArgs ...any) ([]string, error) {
+	query := `with albums as (` + albumQuery + `)
+		select m.musicbrainz_track_id
+		from metadata m
+		join albums a on a.album_id = m.musicbrainz_album_id
+		join user_music_folders f on f.folder_id = m.music_folder_id
+		where f.user_id = ?
+		group by m.musicbrainz_track_id
+		order by max(a.sort_key) desc, min(cast(m.disc_number as integer)), min(cast(m.track_number as integer))`
+
+	args := append(albumArgs, userId)
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying album tracks for user %d: %v", userId, err)
+	}
+	defer rows.Close()
+
+	trackIds := []string{}
+	for rows.Next() {
+		var trackId string
+		if err := rows.Scan(&trackId); err != nil {
+			return nil, fmt.Errorf("scanning album track: %v", err)
+		}
+		trackIds = append(trackIds, trackId)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over album tracks: %v", err)
+	}
+
+	return trackIds, nil
+}
@@ -85,6 +85,23 @@ func createTable(ctx context.Context, schema string) {
 	}
 }
 
+// addColumn adds a column to a table created before the column was part of its schema
+func addColumn(ctx context.Context, tableName string, columnName string, columnDefinition string) {
+	query := "SELECT name FROM pragma_table_info(?) WHERE name = ?"
+	var name string
+	err := DB.QueryRowContext(ctx, query, tableName, columnName).Scan(&name)
+
+	if err == sql.ErrNoRows {
+		_, err := DB.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, columnName, columnDefinition))
+		if err != nil {
+			log.Fatalf("Database: error adding %s column to %s table: %v", columnName, tableName, err)
+		}
+		logger.Printf("Database: %s column added to %s table", columnName, tableName)
+	} else if err != nil {
+		log.Fatalf("Database: error checking for %s column in %s table: %v", columnName, tableName, err)
+	}
+}
+
 func createView(ctx context.Context, schema string) {
 	viewName, err := getViewNameFromSchema(schema)
 	if err != nil {
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"zene/core/config"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+// deleted_tracks keeps the tracks whose files disappeared during a scan for DELETED_TRACK_RETENTION_DAYS,
+// so that playlist entries are not removed and the tracks keep their date added if the files come back
+func migrateDeletedTracks(ctx context.Context) {
+	schema := `CREATE TABLE deleted_tracks (
+		file_path TEXT PRIMARY KEY,
+		musicbrainz_track_id TEXT NOT NULL,
+		music_folder_id INTEGER,
+		date_added TEXT NOT NULL,
+		deleted_at TEXT NOT NULL,
+		FOREIGN KEY (music_folder_id) REFERENCES music_folders(id) ON DELETE CASCADE
+	);`
+	createTable(ctx, schema)
+	createIndex(ctx, "idx_deleted_tracks_track_id", "deleted_tracks", []string{"musicbrainz_track_id"}, false)
+	createIndex(ctx, "idx_deleted_tracks_deleted_at", "deleted_tracks", []string{"deleted_at"}, false)
+}
+
+// softDeleteMetadataRows records the metadata rows for files, including every CUE sheet track of a source file, as deleted tracks.
+// It is called in the same transaction as the rows are deleted, with one placeholder per file path.
+func softDeleteMetadataRows(ctx context.Context, tx *sql.Tx, placeholders string, filePaths []interface{}) error {
+	query := fmt.Sprintf(`INSERT INTO deleted_tracks (file_path, musicbrainz_track_id, music_folder_id, date_added, deleted_at)
+		SELECT file_path, musicbrainz_track_id, music_folder_id, date_added, ? FROM metadata
+		WHERE file_path IN (%s) OR source_file_path IN (%s)
+		ON CONFLICT(file_path) DO UPDATE SET
+			musicbrainz_track_id = excluded.musicbrainz_track_id,
+			music_folder_id = excluded.music_folder_id,
+			deleted_at = excluded.deleted_at`, placeholders, placeholders)
+
+	args := make([]interface{}, 0, len(filePaths)*2+1)
+	args = append(args, logic.GetCurrentTimeFormatted())
+	args = append(args, filePaths...)
+	args = append(args, filePaths...)
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("soft deleting metadata rows: %w", err)
+	}
+	return nil
+}
+
+// restoreDeletedTracks gives upserted tracks that were deleted back their original date added, and removes their deleted track rows.
+// Tracks are matched by MusicBrainz track id, so a file that comes back in a different folder is restored too.
+func restoreDeletedTracks(ctx context.Context, tx *sql.Tx, metadataSlice []types.Metadata) (int, error) {
+	const batchSize = 100
+	restored := 0
+	for start := 0; start < len(metadataSlice); start += batchSize {
+		end := min(start+batchSize, len(metadataSlice))
+
+		batch := metadataSlice[start:end]
+		placeholders := make([]string, len(batch))
+		args := make([]interface{}, len(batch))
+		for i, m := range batch {
+			placeholders[i] = "?"
+			args[i] = m.MusicBrainzTrackID
+		}
+		inClause := strings.Join(placeholders, ",")
+
+		query := fmt.Sprintf(`UPDATE metadata SET date_added = (
+				SELECT min(d.date_added) FROM deleted_tracks d WHERE d.musicbrainz_track_id = metadata.musicbrainz_track_id
+			)
+			WHERE musicbrainz_track_id IN (%s)
+			AND musicbrainz_track_id IN (SELECT musicbrainz_track_id FROM deleted_tracks)`, inClause)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return restored, fmt.Errorf("restoring date added of deleted tracks: %w", err)
+		}
+
+		query = fmt.Sprintf(`DELETE FROM deleted_tracks WHERE musicbrainz_track_id IN (%s)`, inClause)
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return restored, fmt.Errorf("removing restored deleted tracks: %w", err)
+		}
+		rowsAffected, _ := result.RowsAffected()
+		restored += int(rowsAffected)
+	}
+	return restored, nil
+}
+
+// PurgeDeletedTracks forgets deleted tracks after DELETED_TRACK_RETENTION_DAYS, then removes the playlist entries that were kept for them
+func PurgeDeletedTracks(ctx context.Context) error {
+	cutoff := logic.FormatTimeAsString(time.Now().AddDate(0, 0, -config.DeletedTrackRetentionDays))
+	result, err := DB.ExecContext(ctx, `DELETE FROM deleted_tracks WHERE deleted_at < ?`, cutoff)
+	if err != nil {
+		return fmt.Errorf("purging deleted tracks: %v", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected > 0 {
+		logger.Printf("Purged %d tracks deleted more than %d days ago", rowsAffected, config.DeletedTrackRetentionDays)
+	}
+	return RemoveOrphanedPlaylistEntries(ctx)
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"zene/core/types"
+)
+
+// downloadTrackColumns are the columns of a download track, from any one version of the track
+const downloadTrackColumns = `m.musicbrainz_track_id, coalesce(m.title, ''), coalesce(m.artist, ''), coalesce(m.album, ''),
+	coalesce(m.musicbrainz_album_id, ''), coalesce(substr(m.release_date, 1, 4), ''), coalesce(nullif(m.album_artist, ''), m.artist, ''), coalesce(cast(m.track_number as integer), 0), coalesce(cast(m.disc_number as integer), 0)`
+
+// SelectAlbumDownloadTracks returns the tracks of an album in a user's music folders, in disc and track order
+func SelectAlbumDownloadTracks(ctx context.Context, userId int, musicbrainzAlbumId string) ([]types.DownloadTrack, error) {
+	query := `select ` + downloadTrackColumns + `
+		from metadata m
+		join user_music_folders f on f.folder_id = m.music_folder_id
+		where f.user_id = ? and m.musicbrainz_album_id = ?
+		group by m.musicbrainz_track_id
+		order by coalesce(cast(m.disc_number as integer), 0), coalesce(cast(m.track_number as integer), 0), m.title`
+	return selectDownloadTracks(ctx, query, userId, musicbrainzAlbumId)
+}
+
+// SelectArtistDownloadTracks returns the tracks of an artist in a user's music folders, album by album in release order
+func SelectArtistDownloadTracks(ctx context.Context, userId int, musicbrainzArtistId string) ([]types.DownloadTrack, error) {
+	query := `select ` + downloadTrackColumns + `
+		from metadata m
+		join user_music_folders f on f.folder_id = m.music_folder_id
+		where f.user_id = ? and m.musicbrainz_artist_id = ?
+		group by m.musicbrainz_track_id
+		order by m.release_date, m.album, m.musicbrainz_album_id,
+			coalesce(cast(m.disc_number as integer), 0), coalesce(cast(m.track_number as integer), 0), m.title`
+	return selectDownloadTracks(ctx, query, userId, musicbrainzArtistId)
+}
+
+// SelectPlaylistDownloadTracks returns the entries of a playlist that are in a user's music folders, in playlist order
+func SelectPlaylistDownloadTracks(ctx context.Context, userId int, playlistId int) ([]types.DownloadTrack, error) {
+	query := `select ` + downloadTrackColumns + `
+		from playlist_entries pe
+		join metadata m on m.musicbrainz_track_id = pe.musicbrainz_track_id
+		join user_music_folders f on f.folder_id = m.music_folder_id
+		where f.user_id = ? and pe.playlist_id = ?
+		group by pe.id
+		order by pe.sort_order`
+	return selectDownloadTracks(ctx, query, userId, playlistId)
+}
+
+func selectDownloadTracks(ctx context.Context, query string, args ...any) ([]types.DownloadTrack, error) {
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying download tracks: %v", err)
+	}
+	defer rows.Close()
+
+	tracks := []types.DownloadTrack{}
+	for rows.Next() {
+		var track types.DownloadTrack
+		if err := rows.Scan(&track.TrackId, &track.Title, &track.Artist, &track.Album, &track.AlbumId, &track.Year, &track.AlbumArtist, &track.TrackNumber, &track.DiscNumber); err != nil {
+			return nil, fmt.Errorf("scanning download track: %v", err)
+		}
+		tracks = append(tracks, track)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over download tracks: %v", err)
+	}
+
+	return tracks, nil
+}
@@ -219,6 +219,7 @@ func GetStarredSongs(ctx context.Context, musicFolderId int) ([]types.SubsonicCh
 		REPLACE(PRINTF('%4s', substr(m.release_date,1,4)), ' ', '0') as year, substr(m.genre,1,(instr(m.genre,';')-1)) as genre, m.musicbrainz_track_id as cover_art,
 		m.size, m.duration, m.bitrate, m.file_path as path, m.date_added as created, m.disc_number, m.musicbrainz_artist_id as artist_id,
 		m.genre, m.album_artist, m.bit_depth, m.sample_rate, m.channels,
+		m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak,
 		COALESCE(ur.rating, 0) AS user_rating,
 		COALESCE(AVG(gr.rating), 0.0) AS average_rating,
 		COALESCE(SUM(pc.play_count), 0) AS play_count,
@@ -264,6 +265,7 @@ func GetStarredSongs(ctx context.Context, musicFolderId int) ([]types.SubsonicCh
 		var albumArtistId sql.NullString
 		var genreString string
 		var durationFloat float64
+		var replayGain replayGainColumns
 		var played sql.NullString
 		var starred sql.NullString
 
@@ -271,6 +273,7 @@ func GetStarredSongs(ctx context.Context, musicFolderId int) ([]types.SubsonicCh
 			&result.Year, &result.Genre, &result.CoverArt,
 			&result.Size, &durationFloat, &result.BitRate, &result.Path, &result.Created, &result.DiscNumber, &result.ArtistId,
 			&genreString, &albumArtistName, &result.BitDepth, &result.SamplingRate, &result.ChannelCount,
+			&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
 			&result.UserRating, &result.AverageRating, &result.PlayCount, &played, &starred, &albumArtistId); err != nil {
 			return nil, err
 		}
@@ -287,6 +290,7 @@ func GetStarredSongs(ctx context.Context, musicFolderId int) ([]types.SubsonicCh
 		}
 
 		result.Duration = int(durationFloat)
+		result.ReplayGain = replayGain.toChild()
 		result.IsDir = false
 		result.MusicBrainzId = result.Id
 		result.AlbumId = result.Parent
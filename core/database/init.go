@@ -25,18 +25,24 @@ func Initialise(ctx context.Context) {
 	migrateMusicFolders(ctx)
 	migrateUsers(ctx)
 	migrateApiKeys(ctx)
+	migratePlayers(ctx)
+	migrateTranscodeSettings(ctx)
 	_ = CreateAdminUserIfRequired(ctx)
 	migrateMetadata(ctx)
+	migrateDeletedTracks(ctx)
 	migratePlayCounts(ctx)
 	migrateChats(ctx)
 	migrateLyrics(ctx)
+	migrateLocalLyrics(ctx)
 	migrateArt(ctx)
 	migrateTrackGenres(ctx)
+	migrateTrackArtists(ctx)
 	migrateGenreCounts(ctx)
 	migrateAudioCache(ctx)
 	migrateUserStars(ctx)
 	migrateUserRatings(ctx)
 	migrateScans(ctx)
+	migrateScanResults(ctx)
 	migrateNowPlaying(ctx)
 	migrateSimilarArtists(ctx)
 	migrateTopSongs(ctx)
@@ -45,6 +51,7 @@ func Initialise(ctx context.Context) {
 	migrateBookmarks(ctx)
 	migratePlayqueues(ctx)
 	migratePodcasts(ctx)
+	migrateScheduledJobs(ctx)
 
 	checkVersion(ctx)
 }
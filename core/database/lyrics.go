@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"zene/core/logger"
 	"zene/core/types"
 )
@@ -17,6 +18,89 @@ func migrateLyrics(ctx context.Context) {
 	createTable(ctx, schema)
 }
 
+func migrateLocalLyrics(ctx context.Context) {
+	schema := `CREATE TABLE local_lyrics (
+		file_path TEXT NOT NULL,
+		source TEXT NOT NULL,
+		priority INTEGER NOT NULL,
+		lang TEXT NOT NULL DEFAULT '',
+		plain_lyrics TEXT NOT NULL DEFAULT '',
+		synced_lyrics TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (file_path, source),
+		FOREIGN KEY(file_path) REFERENCES metadata(file_path) ON DELETE CASCADE
+	);`
+	createTable(ctx, schema)
+}
+
+// replaceLocalLyrics replaces the sidecar and embedded lyrics for a batch of upserted metadata rows within the upsert transaction
+func replaceLocalLyrics(ctx context.Context, tx *sql.Tx, batch []types.Metadata) error {
+	filePathPlaceholders := make([]string, len(batch))
+	filePathArgs := make([]interface{}, len(batch))
+	lyricsPlaceholders := []string{}
+	lyricsArgs := []interface{}{}
+
+	for i, m := range batch {
+		filePathPlaceholders[i] = "?"
+		filePathArgs[i] = m.FilePath
+
+		for _, lyrics := range m.Lyrics {
+			lyricsPlaceholders = append(lyricsPlaceholders, "(?, ?, ?, ?, ?, ?)")
+			lyricsArgs = append(lyricsArgs, m.FilePath, lyrics.Source, lyrics.Priority, lyrics.Lang, lyrics.PlainLyrics, lyrics.SyncedLyrics)
+		}
+	}
+
+	query := fmt.Sprintf("DELETE FROM local_lyrics WHERE file_path IN (%s)", strings.Join(filePathPlaceholders, ", "))
+	if _, err := tx.ExecContext(ctx, query, filePathArgs...); err != nil {
+		return fmt.Errorf("deleting local lyrics: %w", err)
+	}
+
+	if len(lyricsPlaceholders) == 0 {
+		return nil
+	}
+
+	query = fmt.Sprintf(`INSERT INTO local_lyrics (file_path, source, priority, lang, plain_lyrics, synced_lyrics) VALUES %s
+		ON CONFLICT(file_path, source) DO NOTHING`, strings.Join(lyricsPlaceholders, ", "))
+	if _, err := tx.ExecContext(ctx, query, lyricsArgs...); err != nil {
+		return fmt.Errorf("inserting local lyrics: %w", err)
+	}
+	return nil
+}
+
+// InsertLocalLyrics imports lyrics for a single file, keeping any that were already imported from the same source
+func InsertLocalLyrics(ctx context.Context, filePath string, lyrics []types.LocalLyrics) error {
+	query := `INSERT INTO local_lyrics (file_path, source, priority, lang, plain_lyrics, synced_lyrics)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(file_path, source) DO NOTHING`
+
+	for _, row := range lyrics {
+		_, err := DB.ExecContext(ctx, query, filePath, row.Source, row.Priority, row.Lang, row.PlainLyrics, row.SyncedLyrics)
+		if err != nil {
+			return fmt.Errorf("inserting local lyrics row: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetLocalLyrics returns the highest priority sidecar or embedded lyrics for a track, or an empty row if there are none
+func GetLocalLyrics(ctx context.Context, musicbrainzTrackId string) (types.LyricsDatabaseRow, error) {
+	query := `SELECT m.musicbrainz_track_id, l.plain_lyrics, l.synced_lyrics, l.source, l.lang
+		FROM local_lyrics l
+		JOIN metadata m ON m.file_path = l.file_path
+		WHERE m.musicbrainz_track_id = ?
+		ORDER BY l.priority
+		LIMIT 1`
+
+	var lyrics types.LyricsDatabaseRow
+	err := DB.QueryRowContext(ctx, query, musicbrainzTrackId).Scan(&lyrics.MusicBrainzTrackID, &lyrics.PlainLyrics, &lyrics.SyncedLyrics, &lyrics.Source, &lyrics.Lang)
+	if err == sql.ErrNoRows {
+		return types.LyricsDatabaseRow{}, nil
+	} else if err != nil {
+		logger.Printf("Error querying local lyrics for %s: %v", musicbrainzTrackId, err)
+		return types.LyricsDatabaseRow{}, err
+	}
+	return lyrics, nil
+}
+
 func UpsertTrackLyrics(ctx context.Context, musicbrainzTrackId string, lyrics types.LyricsDatabaseRow) error {
 	query := `INSERT INTO track_lyrics (musicbrainz_track_id, plain_lyrics, synced_lyrics)
 		VALUES (?, ?, ?)
@@ -45,6 +129,7 @@ func GetTrackLyrics(ctx context.Context, musicbrainzTrackId string) (types.Lyric
 		MusicBrainzTrackID: musicbrainzTrackID,
 		PlainLyrics:        plainLyrics,
 		SyncedLyrics:       syncedLyrics,
+		Source:             types.LyricsSourceLrclib,
 	}
 	logger.Printf("Retrieved lyrics for %s", musicbrainzTrackId)
 	return lyrics, nil
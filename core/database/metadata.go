@@ -1,7 +1,9 @@
 package database
 
 import (
+	"cmp"
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 	"zene/core/logger"
@@ -37,9 +39,25 @@ func migrateMetadata(ctx context.Context) {
 		bit_depth INTEGER,
 		sample_rate INTEGER,
 		channels INTEGER,
+		replaygain_track_gain REAL,
+		replaygain_track_peak REAL,
+		replaygain_album_gain REAL,
+		replaygain_album_peak REAL,
+		replaygain_source TEXT,
+		source_file_path TEXT,
+		cue_start REAL,
+		cue_end REAL,
 		FOREIGN KEY (music_folder_id) REFERENCES music_folders(id) ON DELETE CASCADE
 	);`
 	createTable(ctx, schema)
+	addColumn(ctx, "metadata", "replaygain_track_gain", "REAL")
+	addColumn(ctx, "metadata", "replaygain_track_peak", "REAL")
+	addColumn(ctx, "metadata", "replaygain_album_gain", "REAL")
+	addColumn(ctx, "metadata", "replaygain_album_peak", "REAL")
+	addColumn(ctx, "metadata", "replaygain_source", "TEXT")
+	addColumn(ctx, "metadata", "source_file_path", "TEXT")
+	addColumn(ctx, "metadata", "cue_start", "REAL")
+	addColumn(ctx, "metadata", "cue_end", "REAL")
 	createIndex(ctx, "idx_metadata_track_id", "metadata", []string{"musicbrainz_track_id"}, false)
 	createIndex(ctx, "idx_metadata_album_id", "metadata", []string{"musicbrainz_album_id"}, false)
 	createIndex(ctx, "idx_metadata_artist_id", "metadata", []string{"musicbrainz_artist_id"}, false)
@@ -51,6 +69,7 @@ func migrateMetadata(ctx context.Context) {
 	createIndex(ctx, "idx_metadata_title_lower", "metadata", []string{"lower(title)"}, false)
 	createIndex(ctx, "idx_metadata_date_added_album_id", "metadata", []string{"date_added", "musicbrainz_album_id"}, false)
 	createIndex(ctx, "idx_metadata_album_artist", "metadata", []string{"album_artist", "musicbrainz_album_id"}, false)
+	createIndex(ctx, "idx_metadata_source_file_path", "metadata", []string{"source_file_path"}, false)
 }
 
 func UpsertMetadataRows(ctx context.Context, metadataSlice []types.Metadata) error {
@@ -59,13 +78,28 @@ func UpsertMetadataRows(ctx context.Context, metadataSlice []types.Metadata) err
 	}
 
 	const batchSize = 30
-	numberOfColumns := 26 // TODO: derive this from the metadata struct rather than hardcoding it
+	numberOfColumns := 35 // TODO: derive this from the metadata struct rather than hardcoding it
 
 	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
 
+	if err := deleteReplacedMetadataRows(ctx, tx, metadataSlice); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("deleting replaced rows: %w; rollback error: %v", err, rbErr)
+		}
+		return fmt.Errorf("deleting replaced rows: %w", err)
+	}
+
+	invalidated, err := invalidateAudioCache(ctx, tx, metadataSlice)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("invalidating audio cache: %w; rollback error: %v", err, rbErr)
+		}
+		return fmt.Errorf("invalidating audio cache: %w", err)
+	}
+
 	for start := 0; start < len(metadataSlice); start += batchSize {
 		end := start + batchSize
 		if end > len(metadataSlice) {
@@ -79,6 +113,14 @@ func UpsertMetadataRows(ctx context.Context, metadataSlice []types.Metadata) err
 
 		for i, m := range batch {
 			placeholders[i] = "(" + strings.Repeat("?, ", numberOfColumns-1) + "?" + ")"
+			// whole files have no source file or time slice, and the last track of a CUE sheet plays to the end of the file
+			var sourceFilePath, cueStart, cueEnd any
+			if m.SourceFilePath != "" {
+				sourceFilePath, cueStart = m.SourceFilePath, m.CueStart
+				if m.CueEnd > 0 {
+					cueEnd = m.CueEnd
+				}
+			}
 			args = append(args,
 				m.FilePath, m.DateAdded, m.DateModified, m.FileName,
 				m.Format, m.Duration, m.Size, m.Bitrate,
@@ -86,15 +128,20 @@ func UpsertMetadataRows(ctx context.Context, metadataSlice []types.Metadata) err
 				m.Genre, m.TrackNumber, m.TotalTracks, m.DiscNumber,
 				m.TotalDiscs, m.ReleaseDate, m.MusicBrainzArtistID,
 				m.MusicBrainzAlbumID, m.MusicBrainzTrackID, m.Label,
-				m.Codec, m.BitDepth, m.SampleRate, m.Channels,
+				m.Codec, m.BitDepth, m.SampleRate, m.Channels, m.MusicFolderId,
+				m.ReplayGain.TrackGain, m.ReplayGain.TrackPeak, m.ReplayGain.AlbumGain, m.ReplayGain.AlbumPeak, m.ReplayGain.Source,
+				sourceFilePath, cueStart, cueEnd,
 			)
 		}
 
+		// ReplayGain values from the analysis job are kept when an unchanged file without ReplayGain tags is rescanned
 		query := fmt.Sprintf(`
 			INSERT INTO metadata (
 				file_path, date_added, date_modified, file_name, format, duration, size, bitrate, title, artist, album,
 				album_artist, genre, track_number, total_tracks, disc_number, total_discs, release_date,
-				musicbrainz_artist_id, musicbrainz_album_id, musicbrainz_track_id, label, codec, bit_depth, sample_rate, channels
+				musicbrainz_artist_id, musicbrainz_album_id, musicbrainz_track_id, label, codec, bit_depth, sample_rate, channels, music_folder_id,
+				replaygain_track_gain, replaygain_track_peak, replaygain_album_gain, replaygain_album_peak, replaygain_source,
+				source_file_path, cue_start, cue_end
 			) VALUES %s
 			ON CONFLICT(file_path) DO UPDATE SET
 				date_modified = excluded.date_modified,
@@ -120,7 +167,21 @@ func UpsertMetadataRows(ctx context.Context, metadataSlice []types.Metadata) err
 				codec = excluded.codec,
 				bit_depth = excluded.bit_depth,
 				sample_rate = excluded.sample_rate,
-				channels = excluded.channels
+				channels = excluded.channels,
+				music_folder_id = excluded.music_folder_id,
+				source_file_path = excluded.source_file_path,
+				cue_start = excluded.cue_start,
+				cue_end = excluded.cue_end,
+				replaygain_track_gain = CASE WHEN excluded.replaygain_source = '' AND metadata.date_modified = excluded.date_modified
+					THEN metadata.replaygain_track_gain ELSE excluded.replaygain_track_gain END,
+				replaygain_track_peak = CASE WHEN excluded.replaygain_source = '' AND metadata.date_modified = excluded.date_modified
+					THEN metadata.replaygain_track_peak ELSE excluded.replaygain_track_peak END,
+				replaygain_album_gain = CASE WHEN excluded.replaygain_source = '' AND metadata.date_modified = excluded.date_modified
+					THEN metadata.replaygain_album_gain ELSE excluded.replaygain_album_gain END,
+				replaygain_album_peak = CASE WHEN excluded.replaygain_source = '' AND metadata.date_modified = excluded.date_modified
+					THEN metadata.replaygain_album_peak ELSE excluded.replaygain_album_peak END,
+				replaygain_source = CASE WHEN excluded.replaygain_source = '' AND metadata.date_modified = excluded.date_modified
+					THEN metadata.replaygain_source ELSE excluded.replaygain_source END
 		`, strings.Join(placeholders, ", "))
 
 		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
@@ -129,15 +190,72 @@ func UpsertMetadataRows(ctx context.Context, metadataSlice []types.Metadata) err
 			}
 			return fmt.Errorf("insert batch %d-%d: %w", start, end, err)
 		}
+
+		if err := replaceTrackArtists(ctx, tx, batch); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return fmt.Errorf("credits batch %d-%d: %w; rollback error: %v", start, end, err, rbErr)
+			}
+			return fmt.Errorf("credits batch %d-%d: %w", start, end, err)
+		}
+
+		if err := replaceLocalLyrics(ctx, tx, batch); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return fmt.Errorf("lyrics batch %d-%d: %w; rollback error: %v", start, end, err, rbErr)
+			}
+			return fmt.Errorf("lyrics batch %d-%d: %w", start, end, err)
+		}
+	}
+
+	restored, err := restoreDeletedTracks(ctx, tx, metadataSlice)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("restoring deleted tracks: %w; rollback error: %v", err, rbErr)
+		}
+		return fmt.Errorf("restoring deleted tracks: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit transaction: %w", err)
 	}
 
+	if restored > 0 {
+		logger.Printf("Restored %d deleted tracks whose files came back", restored)
+	}
+	if invalidated > 0 {
+		logger.Printf("Invalidated %d cached transcodes of changed files", invalidated)
+	}
+	return nil
+}
+
+// deleteReplacedMetadataRows deletes the rows for the upserted files that are not being upserted again,
+// like the row for a whole file that is now split by a CUE sheet, or tracks removed from a CUE sheet
+func deleteReplacedMetadataRows(ctx context.Context, tx *sql.Tx, metadataSlice []types.Metadata) error {
+	filePathsBySource := map[string][]string{}
+	for _, m := range metadataSlice {
+		source := cmp.Or(m.SourceFilePath, m.FilePath)
+		filePathsBySource[source] = append(filePathsBySource[source], m.FilePath)
+	}
+
+	for source, filePaths := range filePathsBySource {
+		placeholders := make([]string, len(filePaths))
+		args := make([]interface{}, 0, len(filePaths)+2)
+		args = append(args, source, source)
+		for i, filePath := range filePaths {
+			placeholders[i] = "?"
+			args = append(args, filePath)
+		}
+
+		query := fmt.Sprintf(`DELETE FROM metadata WHERE (file_path = ? OR source_file_path = ?) AND file_path NOT IN (%s)`,
+			strings.Join(placeholders, ", "))
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("deleting replaced rows for %s: %w", source, err)
+		}
+	}
 	return nil
 }
 
+// DeleteMetadataRows deletes the rows for files, including every CUE sheet track of a source file.
+// The tracks are kept as deleted tracks until DELETED_TRACK_RETENTION_DAYS has passed, in case the files come back.
 func DeleteMetadataRows(ctx context.Context, filepaths []string) error {
 	if len(filepaths) == 0 {
 		return nil
@@ -163,12 +281,19 @@ func DeleteMetadataRows(ctx context.Context, filepaths []string) error {
 			args[i] = fp
 		}
 
+		if err := softDeleteMetadataRows(ctx, tx, strings.Join(placeholders, ","), args); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return fmt.Errorf("soft delete batch %d-%d: %w; rollback error: %v", start, end, err, rbErr)
+			}
+			return fmt.Errorf("soft deleting metadata rows (batch %d-%d): %w", start, end, err)
+		}
+
 		query := fmt.Sprintf(
-			`DELETE FROM metadata WHERE file_path IN (%s)`,
-			strings.Join(placeholders, ","),
+			`DELETE FROM metadata WHERE file_path IN (%s) OR source_file_path IN (%s)`,
+			strings.Join(placeholders, ","), strings.Join(placeholders, ","),
 		)
 
-		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		if _, err := tx.ExecContext(ctx, query, append(args, args...)...); err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
 				return fmt.Errorf("insert batch %d-%d: %w; rollback error: %v", start, end, err, rbErr)
 			}
@@ -176,6 +301,14 @@ func DeleteMetadataRows(ctx context.Context, filepaths []string) error {
 		}
 	}
 
+	// a file that was moved is upserted at its new path before its old path is deleted, so it was never really deleted
+	if _, err := tx.ExecContext(ctx, `DELETE FROM deleted_tracks WHERE musicbrainz_track_id IN (SELECT musicbrainz_track_id FROM metadata)`); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("removing moved tracks from deleted tracks: %w; rollback error: %v", err, rbErr)
+		}
+		return fmt.Errorf("removing moved tracks from deleted tracks: %w", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit transaction: %w", err)
 	}
@@ -4,26 +4,63 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"zene/core/config"
 	"zene/core/logger"
+	"zene/core/logic"
 	"zene/core/types"
 )
 
+// DefaultScanInterval is in minutes, folders are scanned on the same schedule as the whole library was before per-folder schedules
+const DefaultScanInterval = 45
+
 func migrateMusicFolders(ctx context.Context) {
 	schema := `CREATE TABLE music_folders (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name TEXT NOT NULL
+    name TEXT NOT NULL,
+		path TEXT,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		scan_interval INTEGER NOT NULL DEFAULT 45,
+		file_types TEXT NOT NULL DEFAULT '',
+		last_scanned TEXT
 	);`
 	createTable(ctx, schema)
+	addColumn(ctx, "music_folders", "path", "TEXT")
+	addColumn(ctx, "music_folders", "enabled", "BOOLEAN NOT NULL DEFAULT 1")
+	addColumn(ctx, "music_folders", "scan_interval", "INTEGER NOT NULL DEFAULT 45")
+	addColumn(ctx, "music_folders", "file_types", "TEXT NOT NULL DEFAULT ''")
+	addColumn(ctx, "music_folders", "last_scanned", "TEXT")
+
+	// folders created before they could be renamed used their path as their name
+	if _, err := DB.ExecContext(ctx, `UPDATE music_folders SET path = name WHERE path IS NULL`); err != nil {
+		logger.Printf("Error setting music folder paths: %v", err)
+	}
+	createIndex(ctx, "idx_music_folders_path", "music_folders", []string{"path"}, true)
 
+	// MUSIC_DIRS only creates the first music folders, after that they are managed through the API
+	var count int
+	if err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM music_folders`).Scan(&count); err != nil {
+		logger.Printf("Error counting music folders: %v", err)
+		return
+	}
+	if count > 0 {
+		logger.Printf("Music folders already exist, MUSIC_DIRS is only used on first boot")
+		return
+	}
+
+	// no users exist yet, the admin user is given access to every folder when it is created
 	for _, dir := range config.MusicDirs {
 		if dir != "" {
-			if len(dir) > 255 {
-				dir = dir[:255] // limit directory name to 255 characters
+			name := dir
+			if len(name) > 255 {
+				name = name[:255] // limit directory name to 255 characters
 			}
-			err := InsertMusicFolder(ctx, dir)
+			query := `INSERT INTO music_folders (name, path, scan_interval) VALUES (?, ?, ?)`
+			_, err := DB.ExecContext(ctx, query, name, dir, DefaultScanInterval)
 			if err != nil {
 				fmt.Printf("Error inserting music folder %s: %v\n", dir, err)
+			} else {
+				logger.Printf("Inserted music folder: %s", dir)
 			}
 		}
 	}
@@ -57,6 +94,34 @@ func GetMusicFolders(ctx context.Context) ([]types.MusicFolder, error) {
 	return folders, nil
 }
 
+// GetMusicFoldersForUser returns the music folders a user has been given access to
+func GetMusicFoldersForUser(ctx context.Context, userId int) ([]types.MusicFolder, error) {
+	query := `SELECT f.id, f.name FROM music_folders f
+		JOIN user_music_folders u ON u.folder_id = f.id
+		WHERE u.user_id = ?
+		ORDER BY f.id`
+	rows, err := DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("querying music folders for user: %v", err)
+	}
+	defer rows.Close()
+
+	folders := []types.MusicFolder{}
+	for rows.Next() {
+		var folder types.MusicFolder
+		if err := rows.Scan(&folder.Id, &folder.Name); err != nil {
+			return nil, fmt.Errorf("scanning music folder: %v", err)
+		}
+		folders = append(folders, folder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over music folders: %v", err)
+	}
+
+	return folders, nil
+}
+
 func GetMusicFolderById(ctx context.Context, id int) (types.MusicFolder, error) {
 	query := `SELECT id, name FROM music_folders where id = ?`
 	var row types.MusicFolder
@@ -69,23 +134,164 @@ func GetMusicFolderById(ctx context.Context, id int) (types.MusicFolder, error)
 	return row, nil
 }
 
-func InsertMusicFolder(ctx context.Context, name string) error {
-	query := `SELECT COUNT(*) FROM music_folders WHERE name = ?`
+const musicFolderSettingsColumns = `id, name, path, enabled, scan_interval, file_types, coalesce(last_scanned, '')`
+
+func scanMusicFolderSettings(scanner interface{ Scan(...any) error }) (types.MusicFolderSettings, error) {
+	var folder types.MusicFolderSettings
+	var fileTypes string
+	err := scanner.Scan(&folder.Id, &folder.Name, &folder.Path, &folder.Enabled, &folder.ScanInterval, &fileTypes, &folder.LastScanned)
+	if err != nil {
+		return types.MusicFolderSettings{}, err
+	}
+	folder.FileTypes = []string{}
+	if fileTypes != "" {
+		folder.FileTypes = strings.Split(fileTypes, ",")
+	}
+	return folder, nil
+}
+
+// GetMusicFolderSettings returns every music folder with its path and scan settings
+func GetMusicFolderSettings(ctx context.Context) ([]types.MusicFolderSettings, error) {
+	query := `SELECT ` + musicFolderSettingsColumns + ` FROM music_folders ORDER BY id`
+	rows, err := DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying music folder settings: %v", err)
+	}
+	defer rows.Close()
+
+	folders := []types.MusicFolderSettings{}
+	for rows.Next() {
+		folder, err := scanMusicFolderSettings(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning music folder settings: %v", err)
+		}
+		folders = append(folders, folder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over music folder settings: %v", err)
+	}
+
+	return folders, nil
+}
+
+// GetEnabledMusicFolderSettings returns the music folders that are scanned and watched
+func GetEnabledMusicFolderSettings(ctx context.Context) ([]types.MusicFolderSettings, error) {
+	folders, err := GetMusicFolderSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enabledFolders := []types.MusicFolderSettings{}
+	for _, folder := range folders {
+		if folder.Enabled {
+			enabledFolders = append(enabledFolders, folder)
+		}
+	}
+	return enabledFolders, nil
+}
+
+func GetMusicFolderSettingsById(ctx context.Context, id int) (types.MusicFolderSettings, error) {
+	query := `SELECT ` + musicFolderSettingsColumns + ` FROM music_folders WHERE id = ?`
+	folder, err := scanMusicFolderSettings(DB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return types.MusicFolderSettings{}, fmt.Errorf("music folder with id %d not found", id)
+	} else if err != nil {
+		return types.MusicFolderSettings{}, fmt.Errorf("querying music folder settings: %v", err)
+	}
+	return folder, nil
+}
+
+// InsertMusicFolder creates a music folder, and gives every admin and every user who can access all existing folders access to it
+func InsertMusicFolder(ctx context.Context, folder types.MusicFolderSettings) (int, error) {
+	query := `SELECT COUNT(*) FROM music_folders WHERE path = ?`
 	var count int
-	err := DB.QueryRowContext(ctx, query, name).Scan(&count)
+	err := DB.QueryRowContext(ctx, query, folder.Path).Scan(&count)
 	if err != nil {
-		return fmt.Errorf("checking if music folder exists: %v", err)
+		return 0, fmt.Errorf("checking if music folder exists: %v", err)
 	}
 	if count > 0 {
-		logger.Printf("Music folder '%s' already exists, skipping insert.", name)
-		return nil
+		return 0, fmt.Errorf("music folder %s already exists", folder.Path)
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// users who can see every folder keep seeing every folder, so they are found before the new folder is inserted
+	query = `SELECT u.id FROM users u
+		WHERE u.admin_role = 1
+		OR (SELECT COUNT(*) FROM user_music_folders f WHERE f.user_id = u.id) >= (SELECT COUNT(*) FROM music_folders)`
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("selecting users for music folder: %v", err)
+	}
+	userIds := []int{}
+	for rows.Next() {
+		var userId int
+		if err := rows.Scan(&userId); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning user id: %v", err)
+		}
+		userIds = append(userIds, userId)
+	}
+	rows.Close()
+
+	query = `INSERT INTO music_folders (name, path, enabled, scan_interval, file_types) VALUES (?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, folder.Name, folder.Path, folder.Enabled, folder.ScanInterval, strings.Join(folder.FileTypes, ","))
+	if err != nil {
+		return 0, fmt.Errorf("inserting music folder: %v", err)
+	}
+	folderId, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("getting music folder id: %v", err)
+	}
+
+	for _, userId := range userIds {
+		query = `INSERT INTO user_music_folders (user_id, folder_id) VALUES (?, ?) ON CONFLICT(user_id, folder_id) DO NOTHING`
+		if _, err := tx.ExecContext(ctx, query, userId, folderId); err != nil {
+			return 0, fmt.Errorf("inserting user music folder %d for user %d: %v", folderId, userId, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	logger.Printf("Inserted music folder: %s", folder.Path)
+	return int(folderId), nil
+}
+
+// UpdateMusicFolder updates the name and scan settings of a music folder, its path cannot be changed
+func UpdateMusicFolder(ctx context.Context, folder types.MusicFolderSettings) error {
+	query := `UPDATE music_folders SET name = ?, enabled = ?, scan_interval = ?, file_types = ? WHERE id = ?`
+	result, err := DB.ExecContext(ctx, query, folder.Name, folder.Enabled, folder.ScanInterval, strings.Join(folder.FileTypes, ","), folder.Id)
+	if err != nil {
+		return fmt.Errorf("updating music folder %d: %v", folder.Id, err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("music folder with id %d not found", folder.Id)
 	}
+	return nil
+}
+
+// DeleteMusicFolder deletes a music folder, which cascades to its metadata rows and user access
+func DeleteMusicFolder(ctx context.Context, id int) error {
+	result, err := DB.ExecContext(ctx, `DELETE FROM music_folders WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("deleting music folder %d: %v", id, err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("music folder with id %d not found", id)
+	}
+	return nil
+}
 
-	query = `INSERT INTO music_folders (name) VALUES (?)`
-	_, err = DB.ExecContext(ctx, query, name)
+func UpdateMusicFolderLastScanned(ctx context.Context, id int) error {
+	_, err := DB.ExecContext(ctx, `UPDATE music_folders SET last_scanned = ? WHERE id = ?`, logic.GetCurrentTimeFormatted(), id)
 	if err != nil {
-		return fmt.Errorf("inserting music folder: %v", err)
+		return fmt.Errorf("updating last scanned time for music folder %d: %v", id, err)
 	}
-	logger.Printf("Inserted music folder: %s", name)
 	return nil
 }
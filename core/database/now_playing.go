@@ -57,6 +57,7 @@ func GetNowPlaying(ctx context.Context) ([]types.SubsonicNowPlayingEntry, error)
 		REPLACE(PRINTF('%4s', substr(m.release_date,1,4)), ' ', '0') as year, substr(m.genre,1,(instr(m.genre,';')-1)) as genre, m.musicbrainz_track_id as cover_art,
 		m.size, m.duration, m.bitrate, m.file_path as path, m.date_added as created, m.disc_number, m.musicbrainz_artist_id as artist_id,
 		m.genre, m.album_artist, m.bit_depth, m.sample_rate, m.channels,
+		m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak,
 		COALESCE(ur.rating, 0) AS user_rating,
 		COALESCE(AVG(gr.rating), 0.0) AS average_rating,
 		COALESCE(SUM(pc.play_count), 0) AS play_count,
@@ -89,6 +90,7 @@ func GetNowPlaying(ctx context.Context) ([]types.SubsonicNowPlayingEntry, error)
 		var albumArtist string
 		var genreString string
 		var durationFloat float64
+		var replayGain replayGainColumns
 		var played sql.NullString
 		var starred sql.NullString
 		var playedAt int64
@@ -97,6 +99,7 @@ func GetNowPlaying(ctx context.Context) ([]types.SubsonicNowPlayingEntry, error)
 			&nowPlayingEntry.Year, &nowPlayingEntry.Genre, &nowPlayingEntry.CoverArt,
 			&nowPlayingEntry.Size, &durationFloat, &nowPlayingEntry.BitRate, &nowPlayingEntry.Path, &nowPlayingEntry.Created, &nowPlayingEntry.DiscNumber, &nowPlayingEntry.ArtistId,
 			&genreString, &albumArtist, &nowPlayingEntry.BitDepth, &nowPlayingEntry.SamplingRate, &nowPlayingEntry.ChannelCount,
+			&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
 			&nowPlayingEntry.UserRating, &nowPlayingEntry.AverageRating, &nowPlayingEntry.PlayCount, &played, &starred,
 			&nowPlayingEntry.Username, &playedAt, &nowPlayingEntry.PlayerId, &nowPlayingEntry.PlayerName); err != nil {
 			return nil, err
@@ -114,6 +117,7 @@ func GetNowPlaying(ctx context.Context) ([]types.SubsonicNowPlayingEntry, error)
 		}
 
 		nowPlayingEntry.Duration = int(durationFloat)
+		nowPlayingEntry.ReplayGain = replayGain.toChild()
 		nowPlayingEntry.Title = nowPlayingEntry.Album
 		nowPlayingEntry.IsDir = true
 
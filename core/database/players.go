@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+func migratePlayers(ctx context.Context) {
+	schema := `CREATE TABLE players (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		client TEXT NOT NULL,
+		format TEXT NOT NULL DEFAULT '',
+		max_bit_rate INTEGER NOT NULL DEFAULT 0,
+		max_sample_rate INTEGER NOT NULL DEFAULT 0,
+		allow_raw BOOLEAN NOT NULL DEFAULT 1,
+		last_seen TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		UNIQUE (user_id, client)
+	);`
+	createTable(ctx, schema)
+	addColumn(ctx, "players", "last_format", "TEXT NOT NULL DEFAULT ''")
+	addColumn(ctx, "players", "last_max_bit_rate", "INTEGER NOT NULL DEFAULT 0")
+}
+
+const playerColumns = `p.id, p.user_id, u.username, p.client, p.format, p.max_bit_rate, p.max_sample_rate, p.allow_raw, p.last_seen,
+	p.last_format, p.last_max_bit_rate`
+
+func scanPlayer(scanner interface{ Scan(...any) error }) (types.Player, error) {
+	var player types.Player
+	err := scanner.Scan(&player.Id, &player.UserId, &player.Username, &player.Client, &player.Format,
+		&player.MaxBitRate, &player.MaxSampleRate, &player.AllowRaw, &player.LastSeen, &player.LastFormat, &player.LastMaxBitRate)
+	return player, err
+}
+
+// UpsertPlayer records that a user's client has been used, creating the player with the default profile the first time,
+// and returns the player with its transcoding profile
+func UpsertPlayer(ctx context.Context, userId int, client string) (types.Player, error) {
+	query := `INSERT INTO players (user_id, client, last_seen) VALUES (?, ?, ?)
+		ON CONFLICT(user_id, client) DO UPDATE SET last_seen = excluded.last_seen`
+	if _, err := DB.ExecContext(ctx, query, userId, client, logic.GetCurrentTimeFormatted()); err != nil {
+		return types.Player{}, fmt.Errorf("upserting player %s for user %d: %v", client, userId, err)
+	}
+
+	query = `SELECT ` + playerColumns + ` FROM players p JOIN users u ON u.id = p.user_id WHERE p.user_id = ? AND p.client = ?`
+	player, err := scanPlayer(DB.QueryRowContext(ctx, query, userId, client))
+	if err != nil {
+		return types.Player{}, fmt.Errorf("querying player %s for user %d: %v", client, userId, err)
+	}
+	return player, nil
+}
+
+// GetPlayers returns every player of every user, most recently seen first
+func GetPlayers(ctx context.Context) ([]types.Player, error) {
+	query := `SELECT ` + playerColumns + ` FROM players p JOIN users u ON u.id = p.user_id ORDER BY p.last_seen DESC`
+	rows, err := DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying players: %v", err)
+	}
+	defer rows.Close()
+
+	players := []types.Player{}
+	for rows.Next() {
+		player, err := scanPlayer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning player: %v", err)
+		}
+		players = append(players, player)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over players: %v", err)
+	}
+
+	return players, nil
+}
+
+func GetPlayerById(ctx context.Context, id int) (types.Player, error) {
+	query := `SELECT ` + playerColumns + ` FROM players p JOIN users u ON u.id = p.user_id WHERE p.id = ?`
+	player, err := scanPlayer(DB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return types.Player{}, fmt.Errorf("player with id %d not found", id)
+	} else if err != nil {
+		return types.Player{}, fmt.Errorf("querying player: %v", err)
+	}
+	return player, nil
+}
+
+// UpdatePlayer saves a player's transcoding profile
+func UpdatePlayer(ctx context.Context, player types.Player) error {
+	query := `UPDATE players SET format = ?, max_bit_rate = ?, max_sample_rate = ?, allow_raw = ? WHERE id = ?`
+	result, err := DB.ExecContext(ctx, query, player.Format, player.MaxBitRate, player.MaxSampleRate, player.AllowRaw, player.Id)
+	if err != nil {
+		return fmt.Errorf("updating player %d: %v", player.Id, err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("player with id %d not found", player.Id)
+	}
+	return nil
+}
+
+// UpdatePlayerLastStream records the format and bit rate a player last streamed with, which the cache warm-up transcodes with
+func UpdatePlayerLastStream(ctx context.Context, id int, format string, maxBitRate int) error {
+	query := `UPDATE players SET last_format = ?, last_max_bit_rate = ? WHERE id = ?`
+	if _, err := DB.ExecContext(ctx, query, format, maxBitRate, id); err != nil {
+		return fmt.Errorf("updating last stream of player %d: %v", id, err)
+	}
+	return nil
+}
+
+// DeletePlayer forgets a player, it is created again with the default profile the next time the client streams
+func DeletePlayer(ctx context.Context, id int) error {
+	result, err := DB.ExecContext(ctx, `DELETE FROM players WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("deleting player %d: %v", id, err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("player with id %d not found", id)
+	}
+	return nil
+}
@@ -278,9 +278,12 @@ func updateAllowedUsersForPlaylist(ctx context.Context, playlistId int, allowedU
 	return nil
 }
 
+// RemoveOrphanedPlaylistEntries removes playlist entries for tracks that are not in the library,
+// unless the track was deleted recently enough that its file may still come back
 func RemoveOrphanedPlaylistEntries(ctx context.Context) error {
 	query := `DELETE FROM playlist_entries
-	WHERE musicbrainz_track_id NOT IN (SELECT musicbrainz_track_id FROM metadata);`
+	WHERE musicbrainz_track_id NOT IN (SELECT musicbrainz_track_id FROM metadata)
+	AND musicbrainz_track_id NOT IN (SELECT musicbrainz_track_id FROM deleted_tracks);`
 	_, err := DB.ExecContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("removing orphaned playlist entries: %v", err)
@@ -397,6 +400,7 @@ func GetPlaylistEntries(ctx context.Context, playlistId int) ([]types.SubsonicCh
 		REPLACE(PRINTF('%4s', substr(m.release_date,1,4)), ' ', '0') as year, substr(m.genre,1,(instr(m.genre,';')-1)) as genre, m.musicbrainz_track_id as cover_art,
 		m.size, m.duration, m.bitrate, m.file_path as path, m.date_added as created, m.disc_number, m.musicbrainz_artist_id as artist_id,
 		m.genre, m.album_artist, m.bit_depth, m.sample_rate, m.channels,
+		m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak,
 		COALESCE(ur.rating, 0) AS user_rating,
 		COALESCE(AVG(gr.rating), 0.0) AS average_rating,
 		COALESCE(SUM(pc.play_count), 0) AS play_count,
@@ -433,6 +437,7 @@ func GetPlaylistEntries(ctx context.Context, playlistId int) ([]types.SubsonicCh
 		var albumArtistId sql.NullString
 		var genreString string
 		var durationFloat float64
+		var replayGain replayGainColumns
 		var played sql.NullString
 		var starred sql.NullString
 
@@ -440,6 +445,7 @@ func GetPlaylistEntries(ctx context.Context, playlistId int) ([]types.SubsonicCh
 			&result.Year, &result.Genre, &result.CoverArt,
 			&result.Size, &durationFloat, &result.BitRate, &result.Path, &result.Created, &result.DiscNumber, &result.ArtistId,
 			&genreString, &albumArtistName, &result.BitDepth, &result.SamplingRate, &result.ChannelCount,
+			&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
 			&result.UserRating, &result.AverageRating, &result.PlayCount, &played, &starred, &albumArtistId); err != nil {
 			return nil, err
 		}
@@ -456,6 +462,7 @@ func GetPlaylistEntries(ctx context.Context, playlistId int) ([]types.SubsonicCh
 		}
 
 		result.Duration = int(durationFloat)
+		result.ReplayGain = replayGain.toChild()
 		result.IsDir = false
 		result.MusicBrainzId = result.Id
 		result.AlbumId = result.Parent
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"zene/core/logger"
@@ -473,6 +474,25 @@ func GetPodcastEpisodeByGuid(ctx context.Context, episodeGuid string) (types.Pod
 	return episode, nil
 }
 
+// GetPodcastEpisodeVersion returns a downloaded podcast episode as a song version, so it can be streamed like a song.
+// Only the suffix and bit rate of an episode are known, and ErrVersionNotFound is returned when it has not been downloaded.
+func GetPodcastEpisodeVersion(ctx context.Context, episodeGuid string) (types.SongVersion, error) {
+	var version types.SongVersion
+	query := `select file_path, coalesce(suffix, ''), cast(coalesce(nullif(bit_rate, ''), 0) as integer)
+		from podcast_episodes where guid = ? and file_path is not '' limit 1`
+	err := DB.QueryRowContext(ctx, query, episodeGuid).Scan(&version.Path, &version.Suffix, &version.BitRate)
+	if err == sql.ErrNoRows {
+		return version, ErrVersionNotFound
+	} else if err != nil {
+		return version, fmt.Errorf("querying podcast episode %s: %v", episodeGuid, err)
+	}
+	if version.Suffix == "" {
+		version.Suffix = strings.TrimPrefix(strings.ToLower(filepath.Ext(version.Path)), ".")
+	}
+	version.MediaFile.FilePath = version.Path
+	return version, nil
+}
+
 func DeletePodcastEpisodeById(ctx context.Context, episodeId string) error {
 	query := `DELETE FROM podcast_episodes WHERE id = ?;`
 	_, err := DB.ExecContext(ctx, query, episodeId)
@@ -52,6 +52,7 @@ func GetRandomSongs(ctx context.Context, count int, genre string, fromYear strin
 		REPLACE(PRINTF('%4s', substr(m.release_date,1,4)), ' ', '0') as year, substr(m.genre,1,(instr(m.genre,';')-1)) as genre, m.musicbrainz_track_id as cover_art,
 		m.size, m.duration, m.bitrate, m.file_path as path, m.date_added as created, m.disc_number, m.musicbrainz_artist_id as artist_id,
 		m.genre, m.album_artist, m.bit_depth, m.sample_rate, m.channels,
+		m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak,
 		COALESCE(ur.rating, 0) AS user_rating,
 		COALESCE(gr.avg_rating, 0.0) AS average_rating,
 		COALESCE(pc.play_count, 0) AS play_count,
@@ -112,6 +113,7 @@ func GetRandomSongs(ctx context.Context, count int, genre string, fromYear strin
 
 		var genreString string
 		var durationFloat float64
+		var replayGain replayGainColumns
 		var albumArtistName sql.NullString
 		var albumArtistId sql.NullString
 		var starred sql.NullString
@@ -131,7 +133,9 @@ func GetRandomSongs(ctx context.Context, count int, genre string, fromYear strin
 			&result.Track, &result.Year, &result.Genre, &result.CoverArt, &result.Size,
 			&durationFloat, &result.BitRate, &result.Path, &result.Created, &result.DiscNumber,
 			&result.ArtistId, &genreString, &albumArtistName, &result.BitDepth, &result.SamplingRate,
-			&result.ChannelCount, &result.UserRating, &result.AverageRating, &result.PlayCount,
+			&result.ChannelCount,
+			&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
+			&result.UserRating, &result.AverageRating, &result.PlayCount,
 			&played, &starred, &albumArtistId, &labels); err != nil {
 			logger.Printf("Failed to scan row in GetSongsByGenre: %v", err)
 			return []types.SubsonicChild{}, err
@@ -147,6 +151,7 @@ func GetRandomSongs(ctx context.Context, count int, genre string, fromYear strin
 		result.ContentType = logic.InferMimeTypeFromFileExtension(result.Path)
 		result.Suffix = strings.Replace(filepath.Ext(result.Path), ".", "", 1)
 		result.Duration = int(durationFloat)
+		result.ReplayGain = replayGain.toChild()
 		result.SortName = strings.ToLower(result.Title)
 		result.MusicBrainzId = result.Id
 		result.AlbumId = result.Parent
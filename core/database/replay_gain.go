@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"zene/core/logger"
+	"zene/core/types"
+)
+
+// replayGainColumns holds the replaygain columns of a metadata row, scanned alongside the rest of a song
+type replayGainColumns struct {
+	TrackGain sql.NullFloat64
+	TrackPeak sql.NullFloat64
+	AlbumGain sql.NullFloat64
+	AlbumPeak sql.NullFloat64
+}
+
+func nullFloatPointer(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
+
+// toChild returns the OpenSubsonic replayGain object for a song, or nil if nothing is known
+func (c replayGainColumns) toChild() *types.ChildReplayGain {
+	if !c.TrackGain.Valid && !c.AlbumGain.Valid {
+		return nil
+	}
+	return &types.ChildReplayGain{
+		TrackGain: nullFloatPointer(c.TrackGain),
+		TrackPeak: nullFloatPointer(c.TrackPeak),
+		AlbumGain: nullFloatPointer(c.AlbumGain),
+		AlbumPeak: nullFloatPointer(c.AlbumPeak),
+	}
+}
+
+// SelectAlbumsMissingReplayGain returns the IDs of albums with tracks that have neither ReplayGain tags nor analysed values
+func SelectAlbumsMissingReplayGain(ctx context.Context) ([]string, error) {
+	query := `select distinct musicbrainz_album_id from metadata where coalesce(replaygain_source, '') = '';`
+
+	rows, err := DB.QueryContext(ctx, query)
+	if err != nil {
+		logger.Printf("Query failed: %v", err)
+		return []string{}, err
+	}
+	defer rows.Close()
+
+	albumIds := []string{}
+	for rows.Next() {
+		var albumId string
+		if err := rows.Scan(&albumId); err != nil {
+			logger.Printf("Failed to scan row in SelectAlbumsMissingReplayGain: %v", err)
+			return []string{}, err
+		}
+		albumIds = append(albumIds, albumId)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Printf("Rows iteration error: %v", err)
+		return albumIds, err
+	}
+
+	return albumIds, nil
+}
+
+func SelectReplayGainTracksForAlbum(ctx context.Context, musicBrainzAlbumId string) ([]types.ReplayGainTrack, error) {
+	query := `select file_path, coalesce(source_file_path, file_path), source_file_path is not null, coalesce(cue_start, 0),
+		coalesce(cue_end, 0), coalesce(cast(duration as real), 0), replaygain_track_gain, replaygain_track_peak,
+		replaygain_album_gain, replaygain_album_peak, coalesce(replaygain_source, '')
+	from metadata
+	where musicbrainz_album_id = ?
+	order by disc_number, track_number;`
+
+	rows, err := DB.QueryContext(ctx, query, musicBrainzAlbumId)
+	if err != nil {
+		logger.Printf("Query failed: %v", err)
+		return []types.ReplayGainTrack{}, err
+	}
+	defer rows.Close()
+
+	tracks := []types.ReplayGainTrack{}
+	for rows.Next() {
+		var track types.ReplayGainTrack
+		var columns replayGainColumns
+		if err := rows.Scan(&track.FilePath, &track.MediaFile.FilePath, &track.MediaFile.IsCueTrack, &track.MediaFile.Start,
+			&track.MediaFile.End, &track.Duration, &columns.TrackGain, &columns.TrackPeak,
+			&columns.AlbumGain, &columns.AlbumPeak, &track.ReplayGain.Source); err != nil {
+			logger.Printf("Failed to scan row in SelectReplayGainTracksForAlbum: %v", err)
+			return []types.ReplayGainTrack{}, err
+		}
+		track.ReplayGain.TrackGain = nullFloatPointer(columns.TrackGain)
+		track.ReplayGain.TrackPeak = nullFloatPointer(columns.TrackPeak)
+		track.ReplayGain.AlbumGain = nullFloatPointer(columns.AlbumGain)
+		track.ReplayGain.AlbumPeak = nullFloatPointer(columns.AlbumPeak)
+		tracks = append(tracks, track)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Printf("Rows iteration error: %v", err)
+		return tracks, err
+	}
+
+	return tracks, nil
+}
+
+func UpdateReplayGain(ctx context.Context, filePath string, replayGain types.ReplayGain) error {
+	query := `update metadata set replaygain_track_gain = ?, replaygain_track_peak = ?, replaygain_album_gain = ?,
+		replaygain_album_peak = ?, replaygain_source = ?
+	where file_path = ?;`
+
+	_, err := DB.ExecContext(ctx, query, replayGain.TrackGain, replayGain.TrackPeak, replayGain.AlbumGain,
+		replayGain.AlbumPeak, replayGain.Source, filePath)
+	if err != nil {
+		return fmt.Errorf("updating replaygain for %s: %v", filePath, err)
+	}
+	return nil
+}
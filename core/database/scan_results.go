@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+type ScanResultRow struct {
+	ScanId   int
+	FilePath string
+	Status   string
+	Reason   string
+	Detail   string
+}
+
+// number of most recent scans to keep per-file results for
+const scanResultsRetainedScans = 20
+
+func migrateScanResults(ctx context.Context) {
+	schema := `CREATE TABLE scan_results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		scan_id INTEGER NOT NULL,
+		file_path TEXT NOT NULL,
+		status TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		detail TEXT,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE CASCADE
+	);`
+	createTable(ctx, schema)
+	createIndex(ctx, "idx_scan_results_scan_id", "scan_results", []string{"scan_id"}, false)
+	createIndex(ctx, "idx_scan_results_scan_status_reason", "scan_results", []string{"scan_id", "status", "reason"}, false)
+}
+
+func InsertScanResults(ctx context.Context, scanResults []ScanResultRow) error {
+	if len(scanResults) == 0 {
+		return nil
+	}
+
+	const batchSize = 100
+	numberOfColumns := 6
+	createdAt := logic.GetCurrentTimeFormatted()
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	for start := 0; start < len(scanResults); start += batchSize {
+		end := min(start+batchSize, len(scanResults))
+		batch := scanResults[start:end]
+
+		placeholders := make([]string, len(batch))
+		args := make([]interface{}, 0, len(batch)*numberOfColumns)
+
+		for i, scanResult := range batch {
+			placeholders[i] = "(" + strings.Repeat("?, ", numberOfColumns-1) + "?" + ")"
+			args = append(args, scanResult.ScanId, scanResult.FilePath, scanResult.Status, scanResult.Reason, scanResult.Detail, createdAt)
+		}
+
+		query := fmt.Sprintf(`INSERT INTO scan_results (scan_id, file_path, status, reason, detail, created_at) VALUES %s`, strings.Join(placeholders, ", "))
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return fmt.Errorf("insert batch %d-%d: %w; rollback error: %v", start, end, err, rbErr)
+			}
+			return fmt.Errorf("insert batch %d-%d: %w", start, end, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func SelectScanResults(ctx context.Context, scanId int, status string, reason string, limit int, offset int) ([]types.ScanResult, int, error) {
+	where := "where scan_id = ?"
+	args := []interface{}{scanId}
+	if status != "" {
+		where += " and status = ?"
+		args = append(args, status)
+	}
+	if reason != "" {
+		where += " and reason = ?"
+		args = append(args, reason)
+	}
+
+	var totalCount int
+	err := DB.QueryRowContext(ctx, "select count(*) from scan_results "+where, args...).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("counting scan results: %v", err)
+	}
+
+	query := "select file_path, status, reason, COALESCE(detail, ''), created_at from scan_results " + where + " order by id limit ? offset ?"
+	args = append(args, limit, offset)
+
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("querying scan results: %v", err)
+	}
+	defer rows.Close()
+
+	results := []types.ScanResult{}
+	for rows.Next() {
+		var result types.ScanResult
+		if err := rows.Scan(&result.FilePath, &result.Status, &result.Reason, &result.Detail, &result.Created); err != nil {
+			return nil, 0, fmt.Errorf("scanning scan result row: %v", err)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterating scan result rows: %v", err)
+	}
+
+	return results, totalCount, nil
+}
+
+// DeleteOldScanResults removes the per-file results for all but the most recent scans
+func DeleteOldScanResults(ctx context.Context) error {
+	query := `delete from scan_results where scan_id not in (select id from scans order by id desc limit ?)`
+	_, err := DB.ExecContext(ctx, query, scanResultsRetainedScans)
+	if err != nil {
+		return fmt.Errorf("deleting old scan results: %v", err)
+	}
+	return nil
+}
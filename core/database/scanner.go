@@ -2,7 +2,10 @@ package database
 
 import (
 	"context"
+	"path/filepath"
+	"strings"
 	"zene/core/logger"
+	"zene/core/types"
 )
 
 type getArtistsLine struct {
@@ -16,7 +19,7 @@ func SelectArtistsForMusicDir(ctx context.Context, musicDir string) ([]getArtist
 		m.musicbrainz_artist_id,
 		CASE WHEN m.artist = m.album_artist THEN true ELSE false end as is_album_artist
 	FROM metadata m
-	join music_folders mf on m.music_folder_id = mf.id and mf.name = ?`
+	join music_folders mf on m.music_folder_id = mf.id and mf.path = ?`
 
 	var results []getArtistsLine
 
@@ -43,3 +46,37 @@ func SelectArtistsForMusicDir(ctx context.Context, musicDir string) ([]getArtist
 
 	return results, nil
 }
+
+// SelectAlbumsAndArtistsUnderPath returns one metadata row per album for the files at or below a path
+func SelectAlbumsAndArtistsUnderPath(ctx context.Context, path string) ([]types.Metadata, error) {
+	directoryPrefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+	query := `select musicbrainz_album_id, album, musicbrainz_artist_id, artist, album_artist
+	from metadata
+	where file_path = ? or source_file_path = ? or substr(file_path, 1, length(?)) = ?
+	group by musicbrainz_album_id, musicbrainz_artist_id`
+
+	var results []types.Metadata
+
+	rows, err := DB.QueryContext(ctx, query, path, path, directoryPrefix, directoryPrefix)
+	if err != nil {
+		logger.Printf("Query failed: %v", err)
+		return []types.Metadata{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result types.Metadata
+		if err := rows.Scan(&result.MusicBrainzAlbumID, &result.Album, &result.MusicBrainzArtistID, &result.Artist, &result.AlbumArtist); err != nil {
+			logger.Printf("Failed to scan row in SelectAlbumsAndArtistsUnderPath: %v", err)
+			return []types.Metadata{}, err
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Printf("Rows iteration error: %v", err)
+		return results, err
+	}
+
+	return results, nil
+}
@@ -13,6 +13,20 @@ type ScanRow struct {
 	StartedDate   string `xml:"startedDate,attr" json:"startedDate"`
 	Type          string `xml:"type,attr" json:"type"`
 	CompletedDate string `xml:"completedDate,attr" json:"completedDate"`
+	// Processed and Total count the items in the current phase, which are files until the art phase
+	Phase            string `xml:"phase,attr" json:"phase"`
+	Processed        int    `xml:"processed,attr" json:"processed"`
+	Total            int    `xml:"total,attr" json:"total"`
+	PhaseStartedDate string `xml:"phaseStartedDate,attr" json:"phaseStartedDate"`
+}
+
+const scanColumns = `id, count, folder_count, started_date, type, coalesce(completed_date, ''), phase, processed, total, phase_started_date`
+
+func scanScanRow(row *sql.Row) (ScanRow, error) {
+	var scan ScanRow
+	err := row.Scan(&scan.Id, &scan.Count, &scan.FolderCount, &scan.StartedDate, &scan.Type, &scan.CompletedDate,
+		&scan.Phase, &scan.Processed, &scan.Total, &scan.PhaseStartedDate)
+	return scan, err
 }
 
 func migrateScans(ctx context.Context) {
@@ -22,17 +36,23 @@ func migrateScans(ctx context.Context) {
 		folder_count INTEGER NOT NULL,
 		started_date TEXT NOT NULL,
 		type TEXT NOT NULL,
-		completed_date TEXT
+		completed_date TEXT,
+		phase TEXT NOT NULL DEFAULT '',
+		processed INTEGER NOT NULL DEFAULT 0,
+		total INTEGER NOT NULL DEFAULT 0,
+		phase_started_date TEXT NOT NULL DEFAULT ''
 	);`
 	createTable(ctx, schema)
+	addColumn(ctx, "scans", "phase", "TEXT NOT NULL DEFAULT ''")
+	addColumn(ctx, "scans", "processed", "INTEGER NOT NULL DEFAULT 0")
+	addColumn(ctx, "scans", "total", "INTEGER NOT NULL DEFAULT 0")
+	addColumn(ctx, "scans", "phase_started_date", "TEXT NOT NULL DEFAULT ''")
 }
 
 func GetLatestScan(ctx context.Context) (ScanRow, error) {
-	query := `SELECT id, count, folder_count, started_date, type, completed_date FROM scans ORDER BY id DESC LIMIT 1`
-	row := DB.QueryRowContext(ctx, query)
-
-	var scan ScanRow
-	if err := row.Scan(&scan.Id, &scan.Count, &scan.FolderCount, &scan.StartedDate, &scan.Type, &scan.CompletedDate); err != nil {
+	query := `SELECT ` + scanColumns + ` FROM scans ORDER BY id DESC LIMIT 1`
+	scan, err := scanScanRow(DB.QueryRowContext(ctx, query))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return ScanRow{}, err
 		}
@@ -42,11 +62,9 @@ func GetLatestScan(ctx context.Context) (ScanRow, error) {
 }
 
 func GetLatestCompletedScan(ctx context.Context) (ScanRow, error) {
-	query := `SELECT id, count, folder_count, started_date, type, completed_date FROM scans WHERE completed_date IS NOT '' ORDER BY id DESC LIMIT 1`
-	row := DB.QueryRowContext(ctx, query)
-
-	var scan ScanRow
-	if err := row.Scan(&scan.Id, &scan.Count, &scan.FolderCount, &scan.StartedDate, &scan.Type, &scan.CompletedDate); err != nil {
+	query := `SELECT ` + scanColumns + ` FROM scans WHERE completed_date IS NOT '' ORDER BY id DESC LIMIT 1`
+	scan, err := scanScanRow(DB.QueryRowContext(ctx, query))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return ScanRow{}, err
 		}
@@ -72,3 +90,13 @@ func UpdateScanProgress(ctx context.Context, scanId int, scanRow ScanRow) error
 	}
 	return nil
 }
+
+// UpdateScanPhase saves the current phase of a running scan and its progress through that phase
+func UpdateScanPhase(ctx context.Context, scanId int, scanRow ScanRow) error {
+	query := `UPDATE scans SET phase = ?, processed = ?, total = ?, phase_started_date = ? WHERE id = ?`
+	_, err := DB.ExecContext(ctx, query, scanRow.Phase, scanRow.Processed, scanRow.Total, scanRow.PhaseStartedDate, scanId)
+	if err != nil {
+		return fmt.Errorf("updating scan phase: %v", err)
+	}
+	return nil
+}
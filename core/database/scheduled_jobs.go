@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"zene/core/types"
+)
+
+func migrateScheduledJobs(ctx context.Context) {
+	schema := `CREATE TABLE scheduled_jobs (
+		name TEXT PRIMARY KEY,
+		paused BOOLEAN NOT NULL DEFAULT 0,
+		last_run TEXT NOT NULL DEFAULT '',
+		last_duration INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT ''
+	);`
+	createTable(ctx, schema)
+}
+
+// GetScheduledJobStates returns the paused flag and last run of every job that has been paused or has run, keyed by job name
+func GetScheduledJobStates(ctx context.Context) (map[string]types.ScheduledJob, error) {
+	query := `SELECT name, paused, last_run, last_duration, last_error FROM scheduled_jobs`
+	rows, err := DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying scheduled jobs: %v", err)
+	}
+	defer rows.Close()
+
+	jobs := map[string]types.ScheduledJob{}
+	for rows.Next() {
+		var job types.ScheduledJob
+		if err := rows.Scan(&job.Name, &job.Paused, &job.LastRun, &job.LastDuration, &job.LastError); err != nil {
+			return nil, fmt.Errorf("scanning scheduled job: %v", err)
+		}
+		jobs[job.Name] = job
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over scheduled jobs: %v", err)
+	}
+
+	return jobs, nil
+}
+
+func UpdateScheduledJobRun(ctx context.Context, name string, lastRun string, lastDuration int64, lastError string) error {
+	query := `INSERT INTO scheduled_jobs (name, last_run, last_duration, last_error) VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET last_run = excluded.last_run, last_duration = excluded.last_duration, last_error = excluded.last_error`
+	_, err := DB.ExecContext(ctx, query, name, lastRun, lastDuration, lastError)
+	if err != nil {
+		return fmt.Errorf("updating scheduled job %s: %v", name, err)
+	}
+	return nil
+}
+
+func UpdateScheduledJobPaused(ctx context.Context, name string, paused bool) error {
+	query := `INSERT INTO scheduled_jobs (name, paused) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET paused = excluded.paused`
+	_, err := DB.ExecContext(ctx, query, name, paused)
+	if err != nil {
+		return fmt.Errorf("updating paused state of scheduled job %s: %v", name, err)
+	}
+	return nil
+}
@@ -234,6 +234,10 @@ func SearchAlbums(ctx context.Context, searchQuery string, limit int, offset int
 		return albums, err
 	}
 
+	if err := populateAlbumCredits(ctx, albums); err != nil {
+		logger.Printf("Error populating credits for searched albums: %v", err)
+	}
+
 	return albums, nil
 }
 
@@ -261,6 +265,7 @@ func SearchSongs(ctx context.Context, searchQuery string, limit int, offset int,
 		m.disc_number,
 		m.musicbrainz_artist_id as artist_id,
 		m.genre, m.album_artist, m.bit_depth, m.sample_rate, m.channels,
+		m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak,
 		COALESCE(ur.rating, 0) AS user_rating,
 		COALESCE(AVG(gr.rating), 0.0) AS average_rating,
 		COALESCE(SUM(pc.play_count), 0) AS play_count,
@@ -310,6 +315,7 @@ func SearchSongs(ctx context.Context, searchQuery string, limit int, offset int,
 		var albumArtistId sql.NullString
 		var genreString string
 		var durationFloat float64
+		var replayGain replayGainColumns
 		var played sql.NullString
 		var starred sql.NullString
 
@@ -317,6 +323,7 @@ func SearchSongs(ctx context.Context, searchQuery string, limit int, offset int,
 			&result.Year, &result.Genre, &result.CoverArt,
 			&result.Size, &durationFloat, &result.BitRate, &result.Path, &result.Created, &result.DiscNumber, &result.ArtistId,
 			&genreString, &albumArtistName, &result.BitDepth, &result.SamplingRate, &result.ChannelCount,
+			&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
 			&result.UserRating, &result.AverageRating, &result.PlayCount, &played, &starred, &albumArtistId); err != nil {
 			return nil, err
 		}
@@ -333,6 +340,7 @@ func SearchSongs(ctx context.Context, searchQuery string, limit int, offset int,
 		}
 
 		result.Duration = int(durationFloat)
+		result.ReplayGain = replayGain.toChild()
 		result.IsDir = false
 		result.MusicBrainzId = result.Id
 		result.AlbumId = result.Parent
@@ -357,5 +365,9 @@ func SearchSongs(ctx context.Context, searchQuery string, limit int, offset int,
 		return results, err
 	}
 
+	if err := populateSongCredits(ctx, results); err != nil {
+		logger.Printf("Error populating credits for searched songs: %v", err)
+	}
+
 	return results, nil
 }
@@ -18,6 +18,7 @@ func GetSong(ctx context.Context, musicbrainzTrackId string) (types.SubsonicChil
 		REPLACE(PRINTF('%4s', substr(m.release_date,1,4)), ' ', '0') as year, substr(m.genre,1,(instr(m.genre,';')-1)) as genre, m.musicbrainz_track_id as cover_art,
 		m.size, m.duration, m.bitrate, m.file_path as path, m.date_added as created, m.disc_number, m.musicbrainz_artist_id as artist_id,
 		m.genre, m.album_artist, m.bit_depth, m.sample_rate, m.channels,
+		m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak,
 		COALESCE(ur.rating, 0) AS user_rating,
 		COALESCE(AVG(gr.rating), 0.0) AS average_rating,
 		COALESCE(SUM(pc.play_count), 0) AS play_count,
@@ -42,6 +43,7 @@ func GetSong(ctx context.Context, musicbrainzTrackId string) (types.SubsonicChil
 	var albumArtistId sql.NullString
 	var genreString string
 	var durationFloat float64
+	var replayGain replayGainColumns
 	var played sql.NullString
 	var starred sql.NullString
 
@@ -50,6 +52,7 @@ func GetSong(ctx context.Context, musicbrainzTrackId string) (types.SubsonicChil
 		&result.Year, &result.Genre, &result.CoverArt,
 		&result.Size, &durationFloat, &result.BitRate, &result.Path, &result.Created, &result.DiscNumber, &result.ArtistId,
 		&genreString, &albumArtistName, &result.BitDepth, &result.SamplingRate, &result.ChannelCount,
+		&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
 		&result.UserRating, &result.AverageRating, &result.PlayCount, &played, &starred, &albumArtistId,
 	)
 	if err == sql.ErrNoRows {
@@ -72,6 +75,7 @@ func GetSong(ctx context.Context, musicbrainzTrackId string) (types.SubsonicChil
 	}
 
 	result.Duration = int(durationFloat)
+	result.ReplayGain = replayGain.toChild()
 	result.IsDir = false
 	result.MusicBrainzId = result.Id
 	result.AlbumId = result.Parent
@@ -88,7 +92,27 @@ func GetSong(ctx context.Context, musicbrainzTrackId string) (types.SubsonicChil
 
 	result.DisplayAlbumArtist = albumArtistName.String
 
-	return result, nil
+	// a song in more than one music folder describes the version downloaded by default, and lists every version
+	versions, err := GetSongVersions(ctx, musicbrainzTrackId, requestUser.Id)
+	if err != nil {
+		logger.Printf("Error getting versions of song %s: %v", musicbrainzTrackId, err)
+	} else if len(versions) > 1 {
+		selected, _ := SelectSongVersion(versions, types.VersionPreference{Format: "raw"})
+		result.Path = selected.Path
+		result.Size = selected.Size
+		result.BitRate = selected.BitRate
+		result.BitDepth = selected.BitDepth
+		result.SamplingRate = selected.SamplingRate
+		result.ChannelCount = selected.ChannelCount
+		result.Versions = versions
+	}
+
+	songs := []types.SubsonicChild{result}
+	if err := populateSongCredits(ctx, songs); err != nil {
+		logger.Printf("Error populating credits for song %s: %v", musicbrainzTrackId, err)
+	}
+
+	return songs[0], nil
 }
 
 func GetSongsForAlbum(ctx context.Context, musicbrainzAlbumId string) ([]types.SubsonicChild, error) {
@@ -112,6 +136,7 @@ func GetSongsForAlbum(ctx context.Context, musicbrainzAlbumId string) ([]types.S
 		REPLACE(PRINTF('%4s', substr(m.release_date,1,4)), ' ', '0') as year, substr(m.genre,1,(instr(m.genre,';')-1)) as genre, m.musicbrainz_track_id as cover_art,
 		m.size, m.duration, m.bitrate, m.file_path as path, m.date_added as created, m.disc_number, m.musicbrainz_artist_id as artist_id,
 		m.genre, m.album_artist, maa.musicbrainz_artist_id as album_artist_id, m.bit_depth, m.sample_rate, m.channels,
+		m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak,
 		COALESCE(ur.rating, 0) AS user_rating,
 		COALESCE(AVG(gr.rating), 0.0) AS average_rating,
 		COALESCE(pc.play_count, 0) AS play_count,
@@ -147,6 +172,7 @@ func GetSongsForAlbum(ctx context.Context, musicbrainzAlbumId string) ([]types.S
 		var albumArtistId sql.NullString
 		var genreString string
 		var durationFloat float64
+		var replayGain replayGainColumns
 		var played sql.NullString
 		var starred sql.NullString
 
@@ -154,6 +180,7 @@ func GetSongsForAlbum(ctx context.Context, musicbrainzAlbumId string) ([]types.S
 			&result.Year, &result.Genre, &result.CoverArt,
 			&result.Size, &durationFloat, &result.BitRate, &result.Path, &result.Created, &result.DiscNumber, &result.ArtistId,
 			&genreString, &albumArtistName, &albumArtistId, &result.BitDepth, &result.SamplingRate, &result.ChannelCount,
+			&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
 			&result.UserRating, &result.AverageRating, &result.PlayCount, &played, &starred); err != nil {
 			return nil, err
 		}
@@ -170,6 +197,7 @@ func GetSongsForAlbum(ctx context.Context, musicbrainzAlbumId string) ([]types.S
 		}
 
 		result.Duration = int(durationFloat)
+		result.ReplayGain = replayGain.toChild()
 		result.IsDir = false
 		result.MusicBrainzId = result.Id
 		result.AlbumId = result.Parent
@@ -194,5 +222,9 @@ func GetSongsForAlbum(ctx context.Context, musicbrainzAlbumId string) ([]types.S
 		return results, err
 	}
 
+	if err := populateSongCredits(ctx, results); err != nil {
+		logger.Printf("Error populating credits for album %s: %v", musicbrainzAlbumId, err)
+	}
+
 	return results, nil
 }
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"zene/core/types"
+)
+
+var ErrVersionNotFound = errors.New("song version not found")
+
+var losslessCodecs = []string{"flac", "alac", "ape", "wavpack", "tta", "mlp", "truehd"}
+
+// getVersionId returns a stable id for a song version, which is one file in one music folder
+func getVersionId(filePath string) string {
+	hash := sha256.Sum256([]byte(filePath))
+	return hex.EncodeToString(hash[:8])
+}
+
+// GetSongVersions returns every file of a song in the music folders a user can access, in music folder order
+func GetSongVersions(ctx context.Context, musicbrainzTrackId string, userId int) ([]types.SongVersion, error) {
+	query := `select m.file_path, coalesce(m.source_file_path, m.file_path), m.source_file_path is not null,
+			coalesce(m.cue_start, 0), m.cue_end, m.music_folder_id, mf.name, coalesce(m.codec, ''),
+			cast(coalesce(nullif(m.size, ''), 0) as integer), cast(coalesce(nullif(m.bitrate, ''), 0) as integer),
+			coalesce(m.bit_depth, 0), coalesce(m.sample_rate, 0), coalesce(m.channels, 0),
+			cast(coalesce(nullif(m.duration, ''), 0) as real),
+			m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak
+		from metadata m
+		join music_folders mf on mf.id = m.music_folder_id
+		join user_music_folders u on u.folder_id = m.music_folder_id
+		where m.musicbrainz_track_id = ?
+		and u.user_id = ?
+		order by m.music_folder_id, m.file_path`
+	rows, err := DB.QueryContext(ctx, query, musicbrainzTrackId, userId)
+	if err != nil {
+		return nil, fmt.Errorf("querying song versions: %v", err)
+	}
+	defer rows.Close()
+
+	versions := []types.SongVersion{}
+	for rows.Next() {
+		var version types.SongVersion
+		var cueEnd sql.NullFloat64
+		if err := rows.Scan(&version.Path, &version.MediaFile.FilePath, &version.MediaFile.IsCueTrack,
+			&version.MediaFile.Start, &cueEnd, &version.MusicFolderId, &version.MusicFolderName, &version.Codec,
+			&version.Size, &version.BitRate, &version.BitDepth, &version.SamplingRate, &version.ChannelCount,
+			&version.MediaFile.Duration, &version.MediaFile.ReplayGain.TrackGain, &version.MediaFile.ReplayGain.TrackPeak,
+			&version.MediaFile.ReplayGain.AlbumGain, &version.MediaFile.ReplayGain.AlbumPeak); err != nil {
+			return nil, fmt.Errorf("scanning song version: %v", err)
+		}
+		version.Id = getVersionId(version.Path)
+		version.Suffix = strings.TrimPrefix(strings.ToLower(filepath.Ext(version.MediaFile.FilePath)), ".")
+		version.Lossless = slices.Contains(losslessCodecs, version.Codec) || strings.HasPrefix(version.Codec, "pcm_")
+		version.MediaFile.End = cueEnd.Float64
+		version.MediaFile.VersionId = version.Id
+		version.MediaFile.SampleRate = version.SamplingRate
+		version.MediaFile.BitDepth = version.BitDepth
+		version.MediaFile.Channels = version.ChannelCount
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over song versions: %v", err)
+	}
+
+	return versions, nil
+}
+
+// SelectSongVersion chooses the version of a song to stream, and marks it as selected.
+// A requested version id must match one of the versions. Otherwise a version that is already in the requested format
+// and within the maximum bit rate is chosen, as it needs the least transcoding, and failing that the best quality version.
+func SelectSongVersion(versions []types.SongVersion, preference types.VersionPreference) (types.SongVersion, error) {
+	if len(versions) == 0 {
+		return types.SongVersion{}, ErrVersionNotFound
+	}
+
+	selected := -1
+	if preference.VersionId != "" {
+		selected = slices.IndexFunc(versions, func(version types.SongVersion) bool {
+			return version.Id == preference.VersionId
+		})
+		if selected < 0 {
+			return types.SongVersion{}, fmt.Errorf("%w: %s", ErrVersionNotFound, preference.VersionId)
+		}
+	}
+
+	if selected < 0 && preference.Format != "" && preference.Format != "raw" {
+		for i, version := range versions {
+			// bit rates are stored in bits per second, and requested in kilobits per second
+			if version.Suffix != preference.Format || (preference.MaxBitRate > 0 && version.BitRate/1000 > preference.MaxBitRate) {
+				continue
+			}
+			if selected < 0 || version.BitRate > versions[selected].BitRate {
+				selected = i
+			}
+		}
+	}
+
+	if selected < 0 {
+		selected = 0
+		for i, version := range versions {
+			if isBetterQuality(version, versions[selected]) {
+				selected = i
+			}
+		}
+	}
+
+	for i := range versions {
+		versions[i].Selected = i == selected
+	}
+	return versions[selected], nil
+}
+
+// isBetterQuality prefers lossless versions, then higher bit depths and sample rates, then higher bit rates
+func isBetterQuality(a types.SongVersion, b types.SongVersion) bool {
+	if a.Lossless != b.Lossless {
+		return a.Lossless
+	}
+	if a.BitDepth != b.BitDepth {
+		return a.BitDepth > b.BitDepth
+	}
+	if a.SamplingRate != b.SamplingRate {
+		return a.SamplingRate > b.SamplingRate
+	}
+	return a.BitRate > b.BitRate
+}
@@ -3,28 +3,43 @@ package database
 import (
 	"context"
 	"fmt"
+	"zene/core/types"
 )
 
 func getUnendedMetadataWithPlaycountsSql(userId int) string {
-	return fmt.Sprintf("SELECT m.*, IFNULL(up.play_count, 0) AS user_play_count, IFNULL(gp.global_play_count, 0) AS global_play_count FROM metadata m LEFT JOIN ( "+
+	return fmt.Sprintf("SELECT m.file_path, m.date_added, m.date_modified, m.file_name, m.format, m.duration, m.size, m.bitrate, m.title, m.artist, m.album, "+
+		"m.album_artist, m.genre, m.track_number, m.total_tracks, m.disc_number, m.total_discs, m.release_date, m.musicbrainz_artist_id, "+
+		"m.musicbrainz_album_id, m.musicbrainz_track_id, m.label, m.music_folder_id, m.codec, m.bit_depth, m.sample_rate, m.channels, "+
+		"IFNULL(up.play_count, 0) AS user_play_count, IFNULL(gp.global_play_count, 0) AS global_play_count FROM metadata m LEFT JOIN ( "+
 		"SELECT musicbrainz_track_id, play_count FROM play_counts WHERE user_id = %d ) AS up ON m.musicbrainz_track_id = up.musicbrainz_track_id LEFT JOIN ( "+
 		"SELECT musicbrainz_track_id, SUM(play_count) AS global_play_count FROM play_counts GROUP BY musicbrainz_track_id ) AS gp ON m.musicbrainz_track_id = gp.musicbrainz_track_id", userId)
 }
 
-func GetMediaFilePath(ctx context.Context, mediaId string) (string, error) {
-	var filePath string
-	query := `select file_path from (
-		select file_path
-		from metadata
-		where musicbrainz_track_id = ?
-		union ALL
-		select file_path
-		from podcast_episodes
-		where guid = ? and file_path is not ''
-		) limit 1;`
-	err := DB.QueryRowContext(ctx, query, mediaId, mediaId).Scan(&filePath)
+// GetMediaFile returns the file to stream for a song or podcast episode, and the time slice of its source file for a CUE sheet track.
+// A song is streamed from the version chosen by SelectSongVersion, from the music folders the requesting user can access.
+func GetMediaFile(ctx context.Context, mediaId string, preference types.VersionPreference) (types.MediaFile, error) {
+	requestUser, err := GetUserByContext(ctx)
 	if err != nil {
-		return "", err
+		return types.MediaFile{}, err
 	}
-	return filePath, nil
+
+	versions, err := GetSongVersions(ctx, mediaId, requestUser.Id)
+	if err != nil {
+		return types.MediaFile{}, err
+	}
+	if len(versions) > 0 {
+		version, err := SelectSongVersion(versions, preference)
+		if err != nil {
+			return types.MediaFile{}, err
+		}
+		return version.MediaFile, nil
+	}
+
+	var mediaFile types.MediaFile
+	query := `select file_path, cast(coalesce(duration, 0) as real) from podcast_episodes where guid = ? and file_path is not '' limit 1;`
+	err = DB.QueryRowContext(ctx, query, mediaId).Scan(&mediaFile.FilePath, &mediaFile.Duration)
+	if err != nil {
+		return types.MediaFile{}, err
+	}
+	return mediaFile, nil
 }
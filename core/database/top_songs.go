@@ -74,6 +74,10 @@ func SelectTopSongsForArtistName(ctx context.Context, artistName string, limit i
 			m.bit_depth,
 			m.sample_rate,
 			m.channels,
+			m.replaygain_track_gain,
+			m.replaygain_track_peak,
+			m.replaygain_album_gain,
+			m.replaygain_album_peak,
 			COALESCE(ur.rating, 0),
 			COALESCE(AVG(gr.rating), 0.0),
 			COALESCE(tp.play_count, 0) as play_count,
@@ -109,6 +113,7 @@ func SelectTopSongsForArtistName(ctx context.Context, artistName string, limit i
 		var albumArtistId sql.NullString
 		var genreString string
 		var durationFloat float64
+		var replayGain replayGainColumns
 		var played sql.NullString
 		var starred sql.NullString
 
@@ -116,6 +121,7 @@ func SelectTopSongsForArtistName(ctx context.Context, artistName string, limit i
 			&result.Year, &result.Genre, &result.CoverArt, &result.Size, &durationFloat, &result.BitRate, &result.Path,
 			&result.Created, &result.DiscNumber, &result.ArtistId,
 			&genreString, &albumArtistName, &albumArtistId, &result.BitDepth, &result.SamplingRate, &result.ChannelCount,
+			&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
 			&result.UserRating, &result.AverageRating, &result.PlayCount, &played, &starred); err != nil {
 			return nil, err
 		}
@@ -132,6 +138,7 @@ func SelectTopSongsForArtistName(ctx context.Context, artistName string, limit i
 		}
 
 		result.Duration = int(durationFloat)
+		result.ReplayGain = replayGain.toChild()
 		result.IsDir = false
 		result.MusicBrainzId = result.Id
 		result.AlbumId = result.Parent
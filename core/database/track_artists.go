@@ -0,0 +1,338 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"zene/core/logger"
+	"zene/core/types"
+)
+
+// number of ids per query when selecting credits for many tracks or albums
+const creditsQueryBatchSize = 500
+
+func migrateTrackArtists(ctx context.Context) {
+	schema := `CREATE TABLE track_artists (
+		file_path TEXT NOT NULL,
+		role TEXT NOT NULL,
+		sub_role TEXT NOT NULL DEFAULT '',
+		position INTEGER NOT NULL,
+		artist_name TEXT NOT NULL,
+		musicbrainz_artist_id TEXT NOT NULL,
+		FOREIGN KEY(file_path) REFERENCES metadata(file_path) ON DELETE CASCADE
+	);`
+
+	createTable(ctx, schema)
+	createIndex(ctx, "idx_track_artists_file_path", "track_artists", []string{"file_path"}, false)
+	createIndex(ctx, "idx_track_artists_artist_id", "track_artists", []string{"musicbrainz_artist_id"}, false)
+	createIndex(ctx, "idx_track_artists_role_artist_id", "track_artists", []string{"role", "musicbrainz_artist_id"}, false)
+
+	var count int
+	err = DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM track_artists").Scan(&count)
+	if err != nil {
+		log.Fatalf("error checking count of track_artists table: %v", err)
+	}
+
+	if count == 0 {
+		err = populateTrackArtistsFromMetadata(ctx)
+		if err != nil {
+			log.Fatalf("error populating track_artists table from metadata: %v", err)
+		}
+	}
+}
+
+// populateTrackArtistsFromMetadata credits the single artist and album artist of existing metadata rows,
+// so that credits are available before the next scan reads the full multi-valued tags
+func populateTrackArtistsFromMetadata(ctx context.Context) error {
+	stmt := `INSERT INTO track_artists (file_path, role, position, artist_name, musicbrainz_artist_id)
+		SELECT file_path, ?, 0, artist, musicbrainz_artist_id FROM metadata
+		UNION ALL
+		SELECT m.file_path, ?, 0, m.album_artist, coalesce(maa.musicbrainz_artist_id, m.musicbrainz_artist_id)
+		FROM metadata m
+		LEFT JOIN (
+			SELECT artist, min(musicbrainz_artist_id) AS musicbrainz_artist_id FROM metadata GROUP BY artist
+		) maa ON maa.artist = m.album_artist;`
+
+	_, err := DB.ExecContext(ctx, stmt, types.CreditRoleArtist, types.CreditRoleAlbumArtist)
+	if err != nil {
+		return fmt.Errorf("inserting data into track_artists table: %v", err)
+	}
+	return nil
+}
+
+// replaceTrackArtists replaces the credits for a batch of upserted metadata rows within the upsert transaction
+func replaceTrackArtists(ctx context.Context, tx *sql.Tx, batch []types.Metadata) error {
+	filePathPlaceholders := make([]string, len(batch))
+	filePathArgs := make([]interface{}, len(batch))
+	creditPlaceholders := []string{}
+	creditArgs := []interface{}{}
+
+	for i, m := range batch {
+		filePathPlaceholders[i] = "?"
+		filePathArgs[i] = m.FilePath
+
+		credits := m.Credits
+		if len(credits) == 0 {
+			credits = []types.ArtistCredit{{Role: types.CreditRoleArtist, Name: m.Artist, MusicBrainzArtistID: m.MusicBrainzArtistID}}
+		}
+		for _, credit := range credits {
+			creditPlaceholders = append(creditPlaceholders, "(?, ?, ?, ?, ?, ?)")
+			creditArgs = append(creditArgs, m.FilePath, credit.Role, credit.SubRole, credit.Position, credit.Name, credit.MusicBrainzArtistID)
+		}
+	}
+
+	query := fmt.Sprintf("DELETE FROM track_artists WHERE file_path IN (%s)", strings.Join(filePathPlaceholders, ", "))
+	if _, err := tx.ExecContext(ctx, query, filePathArgs...); err != nil {
+		return fmt.Errorf("deleting track artists: %w", err)
+	}
+
+	query = fmt.Sprintf("INSERT INTO track_artists (file_path, role, sub_role, position, artist_name, musicbrainz_artist_id) VALUES %s", strings.Join(creditPlaceholders, ", "))
+	if _, err := tx.ExecContext(ctx, query, creditArgs...); err != nil {
+		return fmt.Errorf("inserting track artists: %w", err)
+	}
+	return nil
+}
+
+// selectCreditsForIds returns the credits keyed by track or album id, ordered by role position
+func selectCreditsForIds(ctx context.Context, idColumn string, ids []string, roles []string) (map[string][]types.ArtistCredit, error) {
+	results := map[string][]types.ArtistCredit{}
+
+	for start := 0; start < len(ids); start += creditsQueryBatchSize {
+		end := min(start+creditsQueryBatchSize, len(ids))
+		batch := ids[start:end]
+
+		args := make([]interface{}, 0, len(batch)+len(roles))
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		query := fmt.Sprintf(`select distinct m.%s, ta.role, ta.sub_role, ta.position, ta.artist_name, ta.musicbrainz_artist_id
+			from track_artists ta
+			join metadata m on m.file_path = ta.file_path
+			where m.%s in (%s)`, idColumn, idColumn, strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", "))
+		if len(roles) > 0 {
+			query += fmt.Sprintf(" and ta.role in (%s)", strings.TrimSuffix(strings.Repeat("?, ", len(roles)), ", "))
+			for _, role := range roles {
+				args = append(args, role)
+			}
+		}
+		query += " order by ta.role, ta.position, ta.sub_role"
+
+		rows, err := DB.QueryContext(ctx, query, args...)
+		if err != nil {
+			logger.Printf("Query failed: %v", err)
+			return results, err
+		}
+
+		for rows.Next() {
+			var id string
+			var credit types.ArtistCredit
+			if err := rows.Scan(&id, &credit.Role, &credit.SubRole, &credit.Position, &credit.Name, &credit.MusicBrainzArtistID); err != nil {
+				rows.Close()
+				logger.Printf("Failed to scan row in selectCreditsForIds: %v", err)
+				return results, err
+			}
+			results[id] = append(results[id], credit)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			logger.Printf("Rows iteration error: %v", err)
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// populateSongCredits fills in the OpenSubsonic artists, albumArtists, contributors and displayComposer fields of songs.
+// Songs without any credits keep the single artist and album artist they were built with.
+func populateSongCredits(ctx context.Context, songs []types.SubsonicChild) error {
+	ids := make([]string, 0, len(songs))
+	for _, song := range songs {
+		ids = append(ids, song.Id)
+	}
+
+	creditsByTrack, err := selectCreditsForIds(ctx, "musicbrainz_track_id", ids, nil)
+	if err != nil {
+		return fmt.Errorf("selecting song credits: %v", err)
+	}
+
+	for i := range songs {
+		credits, found := creditsByTrack[songs[i].Id]
+		if !found {
+			continue
+		}
+
+		artists := []types.ChildArtist{}
+		albumArtists := []types.ChildArtist{}
+		contributors := []types.ChildContributors{}
+		composers := []string{}
+		seenAlbumArtists := map[string]bool{}
+
+		for _, credit := range credits {
+			artist := types.ChildArtist{Id: credit.MusicBrainzArtistID, Name: credit.Name}
+			switch credit.Role {
+			case types.CreditRoleArtist:
+				artists = append(artists, artist)
+			case types.CreditRoleAlbumArtist:
+				if !seenAlbumArtists[artist.Id] {
+					seenAlbumArtists[artist.Id] = true
+					albumArtists = append(albumArtists, artist)
+				}
+			default:
+				contributors = append(contributors, types.ChildContributors{Role: credit.Role, SubRole: credit.SubRole, Artist: &artist})
+				if credit.Role == types.CreditRoleComposer {
+					composers = append(composers, credit.Name)
+				}
+			}
+		}
+
+		if len(artists) > 0 {
+			songs[i].Artists = artists
+		}
+		if len(albumArtists) > 0 {
+			songs[i].AlbumArtists = albumArtists
+		}
+		songs[i].Contributors = contributors
+		songs[i].DisplayComposer = strings.Join(composers, ", ")
+	}
+
+	return nil
+}
+
+// populateAlbumCredits fills in the artists and albumArtists of albums from the album artist credits of their tracks
+func populateAlbumCredits(ctx context.Context, albums []types.AlbumId3) error {
+	ids := make([]string, 0, len(albums))
+	for _, album := range albums {
+		ids = append(ids, album.Id)
+	}
+
+	creditsByAlbum, err := selectCreditsForIds(ctx, "musicbrainz_album_id", ids, []string{types.CreditRoleAlbumArtist})
+	if err != nil {
+		return fmt.Errorf("selecting album credits: %v", err)
+	}
+
+	for i := range albums {
+		credits, found := creditsByAlbum[albums[i].Id]
+		if !found {
+			continue
+		}
+		albumArtists := []types.Artist{}
+		seenArtists := map[string]bool{}
+		for _, credit := range credits {
+			if !seenArtists[credit.MusicBrainzArtistID] {
+				seenArtists[credit.MusicBrainzArtistID] = true
+				albumArtists = append(albumArtists, types.Artist{Id: credit.MusicBrainzArtistID, Name: credit.Name})
+			}
+		}
+		albums[i].Artists = albumArtists
+		albums[i].AlbumArtists = albumArtists
+	}
+
+	return nil
+}
+
+// GetSongsCreditedToArtist returns the songs an artist is credited on (as a featured artist, composer, performer and so on)
+// where they are not the primary artist of the track
+func GetSongsCreditedToArtist(ctx context.Context, musicBrainzArtistId string) ([]types.SubsonicChild, error) {
+	requestUser, err := GetUserByContext(ctx)
+	if err != nil {
+		return []types.SubsonicChild{}, err
+	}
+
+	query := `with plays AS (
+		SELECT	m.musicbrainz_track_id,
+		SUM(pc.play_count) AS play_count,
+		MAX(pc.last_played) AS last_played,
+		pc.user_id
+		FROM play_counts pc
+		join metadata m ON m.musicbrainz_track_id = pc.musicbrainz_track_id
+		where pc.user_id = ?
+		GROUP BY m.musicbrainz_track_id
+	)
+	select m.musicbrainz_track_id as id, m.musicbrainz_album_id as parent, m.title, m.album, m.artist, COALESCE(m.track_number, 0) as track,
+		REPLACE(PRINTF('%4s', substr(m.release_date,1,4)), ' ', '0') as year, substr(m.genre,1,(instr(m.genre,';')-1)) as genre, m.musicbrainz_track_id as cover_art,
+		m.size, m.duration, m.bitrate, m.file_path as path, m.date_added as created, m.disc_number, m.musicbrainz_artist_id as artist_id,
+		m.genre, m.album_artist, m.bit_depth, m.sample_rate, m.channels,
+		m.replaygain_track_gain, m.replaygain_track_peak, m.replaygain_album_gain, m.replaygain_album_peak,
+		COALESCE(ur.rating, 0) AS user_rating,
+		COALESCE(AVG(gr.rating), 0.0) AS average_rating,
+		COALESCE(pc.play_count, 0) AS play_count,
+		pc.last_played as played,
+		us.created_at AS starred
+	from user_music_folders u
+	join metadata m on m.music_folder_id = u.folder_id
+	join track_artists ta on ta.file_path = m.file_path
+	LEFT JOIN user_stars us ON m.musicbrainz_track_id = us.metadata_id AND us.user_id = u.user_id
+	LEFT JOIN user_ratings ur ON m.musicbrainz_track_id = ur.metadata_id AND ur.user_id = u.user_id
+	LEFT JOIN user_ratings gr ON m.musicbrainz_track_id = gr.metadata_id
+	LEFT JOIN plays pc ON pc.musicbrainz_track_id = m.musicbrainz_track_id AND pc.user_id = u.user_id
+	where ta.musicbrainz_artist_id = ?
+	and m.musicbrainz_artist_id != ?
+	and u.user_id = ?
+	group by m.musicbrainz_track_id
+	order by m.release_date desc, m.disc_number asc, m.track_number asc;`
+
+	rows, err := DB.QueryContext(ctx, query, requestUser.Id, musicBrainzArtistId, musicBrainzArtistId, requestUser.Id)
+	if err != nil {
+		logger.Printf("Query failed: %v", err)
+		return []types.SubsonicChild{}, err
+	}
+	defer rows.Close()
+
+	results := []types.SubsonicChild{}
+
+	for rows.Next() {
+		var result types.SubsonicChild
+
+		var albumArtistName sql.NullString
+		var genreString string
+		var durationFloat float64
+		var replayGain replayGainColumns
+		var played sql.NullString
+		var starred sql.NullString
+
+		if err := rows.Scan(&result.Id, &result.Parent, &result.Title, &result.Album, &result.Artist, &result.Track,
+			&result.Year, &result.Genre, &result.CoverArt,
+			&result.Size, &durationFloat, &result.BitRate, &result.Path, &result.Created, &result.DiscNumber, &result.ArtistId,
+			&genreString, &albumArtistName, &result.BitDepth, &result.SamplingRate, &result.ChannelCount,
+			&replayGain.TrackGain, &replayGain.TrackPeak, &replayGain.AlbumGain, &replayGain.AlbumPeak,
+			&result.UserRating, &result.AverageRating, &result.PlayCount, &played, &starred); err != nil {
+			return nil, err
+		}
+		result.Genres = []types.ChildGenre{}
+		for _, genre := range strings.Split(genreString, ";") {
+			result.Genres = append(result.Genres, types.ChildGenre{Name: genre})
+		}
+
+		if played.Valid {
+			result.Played = played.String
+		}
+		if starred.Valid {
+			result.Starred = starred.String
+		}
+
+		result.Duration = int(durationFloat)
+		result.ReplayGain = replayGain.toChild()
+		result.IsDir = false
+		result.MusicBrainzId = result.Id
+		result.AlbumId = result.Parent
+		result.DisplayArtist = result.Artist
+		result.DisplayAlbumArtist = albumArtistName.String
+
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Printf("Rows iteration error: %v", err)
+		return results, err
+	}
+
+	if err := populateSongCredits(ctx, results); err != nil {
+		return results, err
+	}
+
+	return results, nil
+}
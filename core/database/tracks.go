@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"zene/core/logger"
 	"zene/core/logic"
 	"zene/core/types"
@@ -29,25 +31,54 @@ func SelectTrack(ctx context.Context, musicBrainzTrackId string) (types.Metadata
 	return result, nil
 }
 
-func SelectTrackFilesForScanner(ctx context.Context, musicDir string) ([]types.File, error) {
-	query := "SELECT m.file_path, m.file_name, m.date_modified FROM metadata m join music_folders f on m.music_folder_id = f.id where f.name = ?;"
+// SelectTrackModifiedDatesForScanner returns the date_modified of every file in a music dir, keyed by file path.
+// CUE sheet tracks are keyed by their source file path.
+func SelectTrackModifiedDatesForScanner(ctx context.Context, musicDir string) (map[string]string, error) {
+	query := `SELECT coalesce(m.source_file_path, m.file_path), min(m.date_modified)
+		FROM metadata m join music_folders f on m.music_folder_id = f.id
+		where f.path = ?
+		group by coalesce(m.source_file_path, m.file_path);`
+	return selectTrackModifiedDates(ctx, query, musicDir)
+}
+
+// SelectTrackModifiedDatesUnderPath returns the date_modified of the metadata row for a single file path,
+// or for every file below a directory path, keyed by file path. CUE sheet tracks are keyed by their source file path.
+func SelectTrackModifiedDatesUnderPath(ctx context.Context, path string) (map[string]string, error) {
+	directoryPrefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+	query := `SELECT coalesce(source_file_path, file_path), min(date_modified) FROM metadata
+		where file_path = ? or source_file_path = ? or substr(file_path, 1, length(?)) = ?
+		group by coalesce(source_file_path, file_path);`
+	return selectTrackModifiedDates(ctx, query, path, path, directoryPrefix, directoryPrefix)
+}
+
+// CountMusicFolderFiles returns the number of files in a music folder, counting a file split by a CUE sheet once
+func CountMusicFolderFiles(ctx context.Context, musicFolderId int) (int, error) {
+	query := `SELECT count(distinct coalesce(source_file_path, file_path)) FROM metadata WHERE music_folder_id = ?`
+	var count int
+	if err := DB.QueryRowContext(ctx, query, musicFolderId).Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting files in music folder %d: %v", musicFolderId, err)
+	}
+	return count, nil
+}
 
-	rows, err := DB.QueryContext(ctx, query, musicDir)
+func selectTrackModifiedDates(ctx context.Context, query string, args ...interface{}) (map[string]string, error) {
+	rows, err := DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		logger.Printf("Query failed: %v", err)
-		return []types.File{}, err
+		return map[string]string{}, err
 	}
 	defer rows.Close()
 
-	var results []types.File
+	results := map[string]string{}
 
 	for rows.Next() {
-		var result types.File
-		if err := rows.Scan(&result.FilePathAbs, &result.FileName, &result.DateModified); err != nil {
-			logger.Printf("Failed to scan row in SelectTrackFilesForScanner: %v", err)
-			return []types.File{}, err
+		var filePath string
+		var dateModified string
+		if err := rows.Scan(&filePath, &dateModified); err != nil {
+			logger.Printf("Failed to scan row in selectTrackModifiedDates: %v", err)
+			return map[string]string{}, err
 		}
-		results = append(results, result)
+		results[filePath] = dateModified
 	}
 
 	if err := rows.Err(); err != nil {
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+func migrateTranscodeSettings(ctx context.Context) {
+	schema := `CREATE TABLE transcode_settings (
+		user_id INTEGER PRIMARY KEY,
+		normalization TEXT NOT NULL DEFAULT '',
+		channels INTEGER NOT NULL DEFAULT 0,
+		max_sample_rate INTEGER NOT NULL DEFAULT 0,
+		max_bit_depth INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+	createTable(ctx, schema)
+}
+
+// GetTranscodeSettings returns a user's default DSP filters, which are all off until the user sets them
+func GetTranscodeSettings(ctx context.Context, userId int) (types.TranscodeSettings, error) {
+	settings := types.TranscodeSettings{UserId: userId}
+	query := `SELECT normalization, channels, max_sample_rate, max_bit_depth FROM transcode_settings WHERE user_id = ?`
+	err := DB.QueryRowContext(ctx, query, userId).Scan(&settings.Normalization, &settings.Channels, &settings.MaxSampleRate, &settings.MaxBitDepth)
+	if err != nil && err != sql.ErrNoRows {
+		return types.TranscodeSettings{}, fmt.Errorf("querying transcode settings for user %d: %v", userId, err)
+	}
+	return settings, nil
+}
+
+// GetPlayerTranscodeSettings returns a user's default DSP filters for streaming to one of their players,
+// with the lower of the user's and the player's sample rate caps. It is what stream uses when a request doesn't pass them.
+func GetPlayerTranscodeSettings(ctx context.Context, userId int, player types.Player) (types.TranscodeSettings, error) {
+	settings, err := GetTranscodeSettings(ctx, userId)
+	if err != nil {
+		settings = types.TranscodeSettings{UserId: userId}
+	}
+	settings.MaxSampleRate = logic.MinPositive(settings.MaxSampleRate, player.MaxSampleRate)
+	return settings, err
+}
+
+func UpsertTranscodeSettings(ctx context.Context, settings types.TranscodeSettings) error {
+	query := `INSERT INTO transcode_settings (user_id, normalization, channels, max_sample_rate, max_bit_depth) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET normalization = excluded.normalization, channels = excluded.channels,
+			max_sample_rate = excluded.max_sample_rate, max_bit_depth = excluded.max_bit_depth`
+	_, err := DB.ExecContext(ctx, query, settings.UserId, settings.Normalization, settings.Channels, settings.MaxSampleRate, settings.MaxBitDepth)
+	if err != nil {
+		return fmt.Errorf("upserting transcode settings for user %d: %v", settings.UserId, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"zene/core/types"
+)
+
+// waveformTrackColumns are the columns of a waveform track, one for each version of the track.
+// CUE sheet tracks are computed from the time slice of their source file.
+const waveformTrackColumns = `m.musicbrainz_track_id, m.file_path, coalesce(m.source_file_path, m.file_path), m.source_file_path is not null,
+	coalesce(m.cue_start, 0), coalesce(m.cue_end, 0), coalesce(cast(m.duration as real), 0)`
+
+// SelectWaveformTracksForScans returns every version of the tracks that were inserted or updated by the scans after one scan,
+// up to and including another, for precomputing waveforms. CUE sheet tracks are matched by their source file path.
+func SelectWaveformTracksForScans(ctx context.Context, afterScanId int, toScanId int) ([]types.WaveformTrack, error) {
+	query := `with upserted as (
+		select distinct file_path from scan_results
+		where scan_id > ? and scan_id <= ? and status in (?, ?)
+	)
+	select ` + waveformTrackColumns + `
+	from metadata m
+	where m.file_path in upserted or m.source_file_path in upserted;`
+	return selectWaveformTracks(ctx, query, afterScanId, toScanId, types.ScanResultInserted, types.ScanResultUpdated)
+}
+
+// SelectWaveformTracks returns every version of every track in the library
+func SelectWaveformTracks(ctx context.Context) ([]types.WaveformTrack, error) {
+	return selectWaveformTracks(ctx, `select `+waveformTrackColumns+` from metadata m`)
+}
+
+func selectWaveformTracks(ctx context.Context, query string, args ...any) ([]types.WaveformTrack, error) {
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying waveform tracks: %v", err)
+	}
+	defer rows.Close()
+
+	tracks := []types.WaveformTrack{}
+	for rows.Next() {
+		var track types.WaveformTrack
+		var versionPath string
+		if err := rows.Scan(&track.TrackId, &versionPath, &track.MediaFile.FilePath, &track.MediaFile.IsCueTrack,
+			&track.MediaFile.Start, &track.MediaFile.End, &track.MediaFile.Duration); err != nil {
+			return nil, fmt.Errorf("scanning waveform track: %v", err)
+		}
+		track.MediaFile.VersionId = getVersionId(versionPath)
+		tracks = append(tracks, track)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %v", err)
+	}
+
+	return tracks, nil
+}
@@ -0,0 +1,125 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"zene/core/types"
+)
+
+// loudnessTarget is the EBU R128 integrated loudness, true peak and loudness range that loudnorm normalises to
+const loudnessTarget = "I=-16:TP=-1.5:LRA=11"
+
+// dsp is the ffmpeg audio filter chain and output options of a transcode's DSP options,
+// and the suffix they add to its cache key so that transcodes with different filters are cached apart
+type dsp struct {
+	filters  []string
+	args     []string
+	cacheKey string
+}
+
+// getDsp returns the filters of a transcode of a media file to a stream format.
+// Filters that would do nothing to the file, such as a downmix of a stereo file to stereo or a bit depth cap
+// on a lossy format, are left out so that they don't make another copy of the same transcode in the cache.
+func getDsp(mediaFile types.MediaFile, options types.TranscodeOptions, format string) dsp {
+	var d dsp
+	var keyParts []string
+	output := outputFormats[format]
+	sampleRate := getOutputSampleRate(mediaFile, options.MaxSampleRate, format)
+
+	switch options.Normalization {
+	case types.NormalizationLoudnorm:
+		d.filters = append(d.filters, "loudnorm="+loudnessTarget)
+		keyParts = append(keyParts, "loudnorm")
+		// loudnorm outputs 192kHz, so the file's sample rate is kept unless it is capped
+		if sampleRate == 0 && format != "opus" {
+			sampleRate = mediaFile.SampleRate
+			if sampleRate <= 0 {
+				sampleRate = 48000
+			}
+		}
+	case types.NormalizationTrack, types.NormalizationAlbum:
+		if gain, found := getReplayGain(mediaFile.ReplayGain, options.Normalization); found {
+			gainString := strconv.FormatFloat(gain, 'f', 2, 64)
+			d.filters = append(d.filters, "volume="+gainString+"dB")
+			keyParts = append(keyParts, "rg"+gainString+"db")
+		}
+	}
+
+	downmix := options.Channels > 0 && (mediaFile.Channels <= 0 || mediaFile.Channels > options.Channels)
+	if downmix {
+		d.args = append(d.args, "-ac", strconv.Itoa(options.Channels))
+		keyParts = append(keyParts, fmt.Sprintf("%dch", options.Channels))
+	}
+
+	if output.lossless && options.MaxBitDepth > 0 && (mediaFile.BitDepth <= 0 || mediaFile.BitDepth > options.MaxBitDepth) {
+		if filters, args := getBitDepthFilters(format, options.MaxBitDepth); len(args) > 0 {
+			// downmix and resample before dithering, so that the dither is the last thing done to the samples
+			if downmix && len(filters) > 0 {
+				d.filters = append(d.filters, "aformat=channel_layouts="+map[int]string{1: "mono", 2: "stereo"}[options.Channels])
+			}
+			if sampleRate > 0 && len(filters) > 0 {
+				d.filters = append(d.filters, "aresample="+strconv.Itoa(sampleRate))
+			}
+			d.filters = append(d.filters, filters...)
+			d.args = append(d.args, args...)
+			keyParts = append(keyParts, fmt.Sprintf("%dbit", options.MaxBitDepth))
+		}
+	}
+
+	if sampleRate > 0 {
+		d.args = append(d.args, "-ar", strconv.Itoa(sampleRate))
+		// the sample rate comes first, so that the keys of transcodes without other filters are unchanged
+		keyParts = append([]string{fmt.Sprintf("%dhz", sampleRate)}, keyParts...)
+	}
+
+	if len(keyParts) > 0 {
+		d.cacheKey = "-" + strings.Join(keyParts, "-")
+	}
+	return d
+}
+
+// getArgs returns the ffmpeg output arguments of the filters
+func (d dsp) getArgs() []string {
+	args := []string{}
+	if len(d.filters) > 0 {
+		args = append(args, "-af", strings.Join(d.filters, ","))
+	}
+	return append(args, d.args...)
+}
+
+// getReplayGain returns the track or album gain to apply to a file, lowered when needed so that its peak doesn't clip.
+// The album gain falls back to the track gain for files without one.
+func getReplayGain(replayGain types.ReplayGain, mode string) (float64, bool) {
+	gain, peak := replayGain.TrackGain, replayGain.TrackPeak
+	if mode == types.NormalizationAlbum && replayGain.AlbumGain != nil {
+		gain, peak = replayGain.AlbumGain, replayGain.AlbumPeak
+	}
+	if gain == nil {
+		return 0, false
+	}
+	if peak != nil && *peak > 0 {
+		return min(*gain, -20*math.Log10(*peak)), true
+	}
+	return *gain, true
+}
+
+// getBitDepthFilters returns the filters and sample format to reduce a lossless transcode to a bit depth,
+// dithering when reducing to 16 bits. Formats with a fixed bit depth, such as wav, return nothing.
+func getBitDepthFilters(format string, bitDepth int) ([]string, []string) {
+	var sampleFormat string
+	switch {
+	case format == "flac" && bitDepth == 16:
+		sampleFormat = "s16"
+	case format == "flac" && bitDepth == 24:
+		return nil, []string{"-sample_fmt", "s32", "-bits_per_raw_sample", "24"}
+	case format == "alac" && bitDepth == 16:
+		sampleFormat = "s16p"
+	case format == "alac" && bitDepth == 24:
+		return nil, []string{"-sample_fmt", "s32p"}
+	default:
+		return nil, nil
+	}
+	return []string{"aresample=osf=" + sampleFormat + ":dither_method=triangular"}, []string{"-sample_fmt", sampleFormat}
+}
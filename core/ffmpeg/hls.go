@@ -0,0 +1,328 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"zene/core/config"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/types"
+)
+
+// HlsSegmentSeconds is the length of an HLS segment, the last segment of a file can be shorter
+const HlsSegmentSeconds = 6
+
+// hlsIdleTimeout is how long the transcode of a variant carries on after its segments stop being requested,
+// such as when every client playing it has gone, before it is stopped to free its transcode slot
+const hlsIdleTimeout = time.Minute
+
+// errHlsVariantIdle is why the transcode of a variant ended when it was stopped by hlsIdleTimeout
+var errHlsVariantIdle = errors.New("the transcode was stopped as its segments were no longer requested")
+
+// HlsTempDirectoryPrefix starts the name of the directories in the audio cache that segments are transcoded into
+const HlsTempDirectoryPrefix = ".tmp-hls-"
+
+// GetHlsSegmentCount returns the number of HLS segments of a media file, or 0 when its duration is not known
+func GetHlsSegmentCount(mediaFile types.MediaFile) int {
+	return int(math.Ceil(getDuration(mediaFile) / HlsSegmentSeconds))
+}
+
+// GetHlsSegmentDuration returns the duration in seconds of an HLS segment of a media file
+func GetHlsSegmentDuration(mediaFile types.MediaFile, index int) float64 {
+	return min(getDuration(mediaFile)-float64(index*HlsSegmentSeconds), HlsSegmentSeconds)
+}
+
+// hlsVariant is the transcode of a media file at one bit rate into all of its HLS segments, which are moved into
+// the audio cache as ffmpeg finishes them. Requests for segments that are not cached yet wait for it.
+type hlsVariant struct {
+	mutex    sync.Mutex
+	segments map[int]bool
+	done     bool
+	err      error
+	// updated is closed and replaced each time a segment is cached, and when the transcode ends
+	updated chan struct{}
+	// lastRequested and waiting tell whether anyone still wants the segments, and cancel stops the transcode when not
+	lastRequested time.Time
+	waiting       int
+	cancel        context.CancelFunc
+}
+
+// hlsVariants are the variants being transcoded, by the cache key of their segments without the segment number
+var hlsVariants = struct {
+	sync.Mutex
+	variants map[string]*hlsVariant
+}{variants: map[string]*hlsVariant{}}
+
+// TranscodeHlsSegment serves one HLS segment of a media file, transcoded to AAC in MPEG-TS, from the audio cache.
+// The segments of a variant are cut from one continuous transcode of the file rather than transcoded one at a time,
+// so that they play back without the gap the AAC encoder's priming would add at the start of each one.
+// A segment that is not cached yet starts the transcode of its variant, or waits for the running one to reach it.
+func TranscodeHlsSegment(ctx context.Context, w http.ResponseWriter, r *http.Request, mediaFile types.MediaFile, trackId string, options types.TranscodeOptions, index int) error {
+	maxBitRate := options.MaxBitRate
+	dsp := getDsp(mediaFile, options, "aac")
+	variantKey := fmt.Sprintf("%s-%d%s-hls", getCacheId(mediaFile, trackId), maxBitRate, dsp.cacheKey)
+	cacheKey := fmt.Sprintf("%s%d.ts", variantKey, index)
+	cachePath := filepath.Join(config.AudioCacheFolder, cacheKey)
+
+	sourceVersion := getSourceVersion(mediaFile.FilePath)
+	if isCached(ctx, cachePath, cacheKey, sourceVersion) {
+		// a client playing segments the transcode has already cached still wants the ones it hasn't reached
+		touchHlsVariant(variantKey)
+		return serveCachedFile(ctx, w, r, cachePath, cacheKey, "video/mp2t")
+	}
+
+	for {
+		variant, err := getHlsVariant(ctx, r, mediaFile, trackId, variantKey, cachePath, maxBitRate, dsp, sourceVersion)
+		if err != nil {
+			return err
+		}
+		if variant == nil {
+			break
+		}
+		err = variant.wait(ctx, index)
+		// a transcode that was stopped just as the segment was requested is started again
+		if errors.Is(err, errHlsVariantIdle) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("transcoding HLS segment %d of %s: %w", index, mediaFile.FilePath, err)
+		}
+		break
+	}
+	return serveCachedFile(ctx, w, r, cachePath, cacheKey, "video/mp2t")
+}
+
+// touchHlsVariant records that a segment of a variant has been requested, if it is being transcoded
+func touchHlsVariant(variantKey string) {
+	hlsVariants.Lock()
+	variant, found := hlsVariants.variants[variantKey]
+	hlsVariants.Unlock()
+	if found {
+		variant.mutex.Lock()
+		variant.lastRequested = time.Now()
+		variant.mutex.Unlock()
+	}
+}
+
+// getHlsVariant returns the running transcode of a variant, or starts one once there is a free transcode slot.
+// It returns nil when the segment was cached by a transcode that finished since it was looked for.
+func getHlsVariant(ctx context.Context, r *http.Request, mediaFile types.MediaFile, trackId string, variantKey string, cachePath string,
+	maxBitRate int, dsp dsp, sourceVersion string) (*hlsVariant, error) {
+	hlsVariants.Lock()
+	if variant, found := hlsVariants.variants[variantKey]; found {
+		hlsVariants.Unlock()
+		return variant, nil
+	}
+	if _, err := os.Stat(cachePath); err == nil {
+		hlsVariants.Unlock()
+		return nil, nil
+	}
+	// the transcode carries on when the request that started it ends, as the requests for the next segments wait for it,
+	// until its segments are no longer requested
+	encodeCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	variant := &hlsVariant{segments: map[int]bool{}, updated: make(chan struct{}), lastRequested: time.Now(), cancel: cancel}
+	hlsVariants.variants[variantKey] = variant
+	hlsVariants.Unlock()
+
+	transcodeCtx, transcode, err := startTranscode(encodeCtx, r, trackId, "hls", maxBitRate)
+	if err != nil {
+		cancel()
+		variant.finish(variantKey, err)
+		return nil, err
+	}
+
+	tempDirectory, err := os.MkdirTemp(config.AudioCacheFolder, HlsTempDirectoryPrefix)
+	if err != nil {
+		cancel()
+		transcode.finish()
+		variant.finish(variantKey, err)
+		return nil, fmt.Errorf("creating HLS segment directory: %w", err)
+	}
+
+	logger.Printf("Transcoding HLS segments of %s at %dk", mediaFile.FilePath, maxBitRate)
+	args := []string{"-loglevel", "error", "-y"}
+	if mediaFile.Start > 0 {
+		args = append(args, "-ss", formatSeconds(mediaFile.Start))
+	}
+	args = append(args, "-i", mediaFile.FilePath, "-vn")
+	if mediaFile.End > 0 {
+		args = append(args, "-t", formatSeconds(mediaFile.End-mediaFile.Start))
+	}
+	args = append(args, "-c:a", "aac")
+	if maxBitRate > 0 {
+		args = append(args, "-b:a", fmt.Sprintf("%dk", maxBitRate))
+	}
+	args = append(args, dsp.getArgs()...)
+	args = append(args, "-f", "segment", "-segment_time", strconv.Itoa(HlsSegmentSeconds), "-segment_format", "mpegts",
+		"-segment_list", filepath.Join(tempDirectory, "segments.csv"), "-segment_list_type", "csv", filepath.Join(tempDirectory, "%d.ts"))
+
+	go func() {
+		defer os.RemoveAll(tempDirectory)
+		defer transcode.finish()
+		defer cancel()
+		err := variant.transcode(transcodeCtx, exec.CommandContext(transcodeCtx, config.FfmpegPath, args...), tempDirectory, variantKey, mediaFile.FilePath, sourceVersion)
+		if errors.Is(err, errHlsVariantIdle) {
+			logger.Printf("Stopped transcoding HLS segments of %s at %dk, as they were no longer requested", mediaFile.FilePath, maxBitRate)
+		} else if err != nil {
+			logger.Printf("Error transcoding HLS segments of %s: %v", mediaFile.FilePath, err)
+		}
+		variant.finish(variantKey, err)
+	}()
+	return variant, nil
+}
+
+// transcode runs ffmpeg, and moves each segment into the audio cache once ffmpeg has listed it as finished
+func (v *hlsVariant) transcode(ctx context.Context, cmd *exec.Cmd, tempDirectory string, variantKey string, filePath string, sourceVersion string) error {
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("getting ffmpeg stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting ffmpeg: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		slurp, _ := io.ReadAll(stderr)
+		if len(slurp) > 0 {
+			logger.Printf("ffmpeg stderr: %s", slurp)
+		}
+		exited <- cmd.Wait()
+	}()
+
+	// the cache rows are written with a context that outlives a killed transcode, so that finished segments are kept
+	cacheCtx := context.WithoutCancel(ctx)
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			if cacheErr := v.cacheSegments(cacheCtx, tempDirectory, variantKey, filePath, sourceVersion); cacheErr != nil {
+				return cacheErr
+			}
+			if err != nil {
+				return fmt.Errorf("ffmpeg exited with error: %w", err)
+			}
+			return nil
+		case <-ticker.C:
+			if err := v.cacheSegments(cacheCtx, tempDirectory, variantKey, filePath, sourceVersion); err != nil {
+				return err
+			}
+			if v.isIdle() {
+				v.cancel()
+				<-exited
+				if err := v.cacheSegments(cacheCtx, tempDirectory, variantKey, filePath, sourceVersion); err != nil {
+					return err
+				}
+				return errHlsVariantIdle
+			}
+		}
+	}
+}
+
+// cacheSegments moves the segments in ffmpeg's segment list that are not cached yet into the audio cache
+func (v *hlsVariant) cacheSegments(ctx context.Context, tempDirectory string, variantKey string, filePath string, sourceVersion string) error {
+	segmentList, err := os.ReadFile(filepath.Join(tempDirectory, "segments.csv"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading HLS segment list: %w", err)
+	}
+
+	for _, line := range strings.Split(string(segmentList), "\n") {
+		fileName, _, _ := strings.Cut(strings.TrimSpace(line), ",")
+		index, err := strconv.Atoi(strings.TrimSuffix(fileName, ".ts"))
+		if err != nil {
+			continue
+		}
+		v.mutex.Lock()
+		cached := v.segments[index]
+		v.mutex.Unlock()
+		if cached {
+			continue
+		}
+
+		cacheKey := fmt.Sprintf("%s%d.ts", variantKey, index)
+		if err := database.UpsertAudioCacheEntry(ctx, cacheKey, filePath, sourceVersion); err != nil {
+			return fmt.Errorf("upserting audio cache entry for %s: %w", cacheKey, err)
+		}
+		if err := os.Rename(filepath.Join(tempDirectory, fileName), filepath.Join(config.AudioCacheFolder, cacheKey)); err != nil {
+			return fmt.Errorf("moving HLS segment to the audio cache: %w", err)
+		}
+
+		v.mutex.Lock()
+		v.segments[index] = true
+		close(v.updated)
+		v.updated = make(chan struct{})
+		v.mutex.Unlock()
+	}
+	return nil
+}
+
+// isIdle reports whether no request is waiting for a segment, and none has been requested for hlsIdleTimeout
+func (v *hlsVariant) isIdle() bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.waiting == 0 && time.Since(v.lastRequested) >= hlsIdleTimeout
+}
+
+// wait waits until a segment is in the audio cache, the transcode has ended without it, or the request is cancelled
+func (v *hlsVariant) wait(ctx context.Context, index int) error {
+	v.mutex.Lock()
+	v.waiting++
+	v.mutex.Unlock()
+	defer func() {
+		v.mutex.Lock()
+		v.waiting--
+		v.lastRequested = time.Now()
+		v.mutex.Unlock()
+	}()
+
+	for {
+		v.mutex.Lock()
+		cached, done, err, updated := v.segments[index], v.done, v.err, v.updated
+		v.mutex.Unlock()
+		if cached {
+			return nil
+		}
+		if done && err != nil {
+			return err
+		}
+		if done {
+			return fmt.Errorf("segment %d was not transcoded", index)
+		}
+		select {
+		case <-updated:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// finish marks the transcode of a variant as ended, so that a later request for a segment that is not cached starts another
+func (v *hlsVariant) finish(variantKey string, err error) {
+	hlsVariants.Lock()
+	if hlsVariants.variants[variantKey] == v {
+		delete(hlsVariants.variants, variantKey)
+	}
+	hlsVariants.Unlock()
+
+	v.mutex.Lock()
+	v.done = true
+	v.err = err
+	close(v.updated)
+	v.updated = make(chan struct{})
+	v.mutex.Unlock()
+}
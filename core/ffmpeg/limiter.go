@@ -0,0 +1,198 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+	"zene/core/config"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+var ErrTranscodeQueueTimeout = errors.New("timed out waiting for a transcode slot")
+var ErrTranscodeNotFound = errors.New("transcode not found")
+
+// transcode is an ffmpeg process that is running, or waiting in the queue for a slot
+type transcode struct {
+	id        int
+	userId    int
+	username  string
+	client    string
+	trackId   string
+	format    string
+	bitRate   int
+	queuedAt  time.Time
+	startedAt time.Time
+	bytesSent atomic.Int64
+	cancel    context.CancelFunc
+	ready     chan struct{}
+}
+
+// the limiter runs at most TRANSCODE_MAX_CONCURRENT transcodes, and TRANSCODE_MAX_PER_USER for each user,
+// and starts queued transcodes in the order they were queued as slots free up
+var limiter struct {
+	sync.Mutex
+	nextId int
+	active []*transcode
+	queue  []*transcode
+}
+
+// startTranscode waits for a transcode slot, and returns a context that is cancelled when the transcode is killed.
+// Transcodes without a user in the context, such as background transcodes, only count towards the global limit.
+func startTranscode(ctx context.Context, r *http.Request, trackId string, format string, bitRate int) (context.Context, *transcode, error) {
+	transcodeCtx, cancel := context.WithCancel(ctx)
+	t := &transcode{
+		trackId:  trackId,
+		format:   format,
+		bitRate:  bitRate,
+		queuedAt: time.Now(),
+		cancel:   cancel,
+		ready:    make(chan struct{}),
+	}
+	t.userId, _ = ctx.Value(types.ContextKey("userId")).(int)
+	t.username, _ = ctx.Value(types.ContextKey("username")).(string)
+	if r != nil {
+		t.client = r.FormValue("c")
+	}
+
+	limiter.Lock()
+	limiter.nextId++
+	t.id = limiter.nextId
+	limiter.queue = append(limiter.queue, t)
+	admitQueuedTranscodes()
+	limiter.Unlock()
+
+	timeout := time.NewTimer(time.Duration(config.TranscodeQueueTimeoutSeconds) * time.Second)
+	defer timeout.Stop()
+
+	var err error
+	select {
+	case <-t.ready:
+		return transcodeCtx, t, nil
+	case <-transcodeCtx.Done():
+		err = transcodeCtx.Err()
+	case <-timeout.C:
+		err = ErrTranscodeQueueTimeout
+	}
+
+	limiter.Lock()
+	defer limiter.Unlock()
+	select {
+	case <-t.ready:
+		// the transcode was started as it gave up waiting
+		if transcodeCtx.Err() == nil {
+			return transcodeCtx, t, nil
+		}
+		limiter.active = slices.DeleteFunc(limiter.active, func(active *transcode) bool { return active == t })
+		admitQueuedTranscodes()
+	default:
+		limiter.queue = slices.DeleteFunc(limiter.queue, func(queued *transcode) bool { return queued == t })
+	}
+	cancel()
+	if errors.Is(err, ErrTranscodeQueueTimeout) {
+		logger.Printf("Timed out waiting for a transcode slot for %s (user %s)", trackId, t.username)
+	}
+	return nil, nil, err
+}
+
+// finish frees the transcode's slot for the next queued transcode, it can be called more than once
+func (t *transcode) finish() {
+	limiter.Lock()
+	defer limiter.Unlock()
+	limiter.active = slices.DeleteFunc(limiter.active, func(active *transcode) bool { return active == t })
+	t.cancel()
+	admitQueuedTranscodes()
+}
+
+// countWrites counts the bytes of a transcode written to a writer
+func (t *transcode) countWrites(w io.Writer) io.Writer {
+	return &countingWriter{w: w, transcode: t}
+}
+
+type countingWriter struct {
+	w         io.Writer
+	transcode *transcode
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.transcode.bytesSent.Add(int64(n))
+	return n, err
+}
+
+// admitQueuedTranscodes starts queued transcodes while there are free slots, skipping users at their limit.
+// It must be called with the limiter locked.
+func admitQueuedTranscodes() {
+	for i := 0; i < len(limiter.queue) && len(limiter.active) < config.TranscodeMaxConcurrent; {
+		t := limiter.queue[i]
+		if t.userId > 0 && config.TranscodeMaxPerUser > 0 && countUserTranscodes(t.userId) >= config.TranscodeMaxPerUser {
+			i++
+			continue
+		}
+		limiter.queue = slices.Delete(limiter.queue, i, i+1)
+		t.startedAt = time.Now()
+		limiter.active = append(limiter.active, t)
+		close(t.ready)
+	}
+}
+
+func countUserTranscodes(userId int) int {
+	count := 0
+	for _, t := range limiter.active {
+		if t.userId == userId {
+			count++
+		}
+	}
+	return count
+}
+
+// GetTranscodes returns the running transcodes, then the queued transcodes in the order they will start
+func GetTranscodes() []types.Transcode {
+	limiter.Lock()
+	defer limiter.Unlock()
+
+	transcodes := []types.Transcode{}
+	for _, t := range limiter.active {
+		transcodes = append(transcodes, t.toType("active", t.startedAt))
+	}
+	for _, t := range limiter.queue {
+		transcodes = append(transcodes, t.toType("queued", t.queuedAt))
+	}
+	return transcodes
+}
+
+func (t *transcode) toType(status string, since time.Time) types.Transcode {
+	return types.Transcode{
+		Id:        t.id,
+		UserId:    t.userId,
+		Username:  t.username,
+		Client:    t.client,
+		TrackId:   t.trackId,
+		Format:    t.format,
+		BitRate:   t.bitRate,
+		Status:    status,
+		BytesSent: t.bytesSent.Load(),
+		Duration:  int(time.Since(since).Seconds()),
+		StartedAt: logic.FormatTimeAsString(since),
+	}
+}
+
+// KillTranscode stops a running transcode, or removes a queued one from the queue
+func KillTranscode(id int) error {
+	limiter.Lock()
+	defer limiter.Unlock()
+	for _, t := range slices.Concat(limiter.active, limiter.queue) {
+		if t.id == id {
+			logger.Printf("Killing transcode %d of %s for user %s", t.id, t.trackId, t.username)
+			t.cancel()
+			return nil
+		}
+	}
+	return ErrTranscodeNotFound
+}
@@ -0,0 +1,66 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+
+	"zene/core/config"
+	"zene/core/types"
+)
+
+var (
+	integratedLoudnessRegex = regexp.MustCompile(`(?s)Integrated loudness:\s*I:\s*(-?[\d.]+|-inf) LUFS`)
+	truePeakRegex           = regexp.MustCompile(`(?s)True peak:\s*Peak:\s*(-?[\d.]+|-inf) dBFS`)
+)
+
+// GetLoudness measures the integrated loudness in LUFS and the true peak in dBFS of a file, or the time slice of a CUE sheet track,
+// with the ebur128 filter
+func GetLoudness(ctx context.Context, mediaFile types.MediaFile) (float64, float64, error) {
+	var stderr bytes.Buffer
+	audiofilePath := mediaFile.FilePath
+
+	args := []string{"-hide_banner", "-nostats"}
+	if mediaFile.Start > 0 {
+		args = append(args, "-ss", formatSeconds(mediaFile.Start))
+	}
+	args = append(args, "-i", audiofilePath)
+	if mediaFile.End > 0 {
+		args = append(args, "-t", formatSeconds(mediaFile.End-mediaFile.Start))
+	}
+	args = append(args, "-vn", "-af", "ebur128=peak=true", "-f", "null", "-")
+
+	cmd := exec.CommandContext(ctx, config.FfmpegPath, args...)
+
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return 0, 0, fmt.Errorf("ffmpeg failed for %s: %v\nstderr: %s", audiofilePath, err, stderr.String())
+	}
+
+	// the summary is printed last, after the per-frame log lines
+	output := stderr.Bytes()
+	if index := bytes.LastIndex(output, []byte("Summary:")); index >= 0 {
+		output = output[index:]
+	}
+
+	loudnessMatch := integratedLoudnessRegex.FindSubmatch(output)
+	peakMatch := truePeakRegex.FindSubmatch(output)
+	if loudnessMatch == nil || peakMatch == nil {
+		return 0, 0, fmt.Errorf("ebur128 summary not found in ffmpeg output for %s", audiofilePath)
+	}
+
+	loudness, err := strconv.ParseFloat(string(loudnessMatch[1]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing integrated loudness for %s: %v", audiofilePath, err)
+	}
+	peak, err := strconv.ParseFloat(string(peakMatch[1]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing true peak for %s: %v", audiofilePath, err)
+	}
+
+	return loudness, peak, nil
+}
@@ -10,11 +10,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 	"zene/core/config"
 	"zene/core/database"
 	"zene/core/logger"
+	"zene/core/types"
 )
 
 var activeTranscodes sync.Map
@@ -33,35 +35,79 @@ func cleanupIncompleteCache(cachePath string, cacheKey string) {
 	}
 }
 
-func TranscodeAndStream(ctx context.Context, w http.ResponseWriter, r *http.Request, filePathAbs string, trackId string, maxBitRate int, timeOffset int, format string) error {
-	cacheKey := fmt.Sprintf("%s-%d.%s", trackId, maxBitRate, format)
-	cachePath := filepath.Join(config.AudioCacheFolder, cacheKey)
+// outputFormat is the ffmpeg codec and muxer for a stream format, the container it is reported as, the content type it is served as
+// and the extension it is downloaded with
+type outputFormat struct {
+	codec       string
+	muxer       string
+	container   string
+	contentType string
+	extension   string
+	lossless    bool
+}
 
-	useCache := timeOffset <= 0
+var outputFormats = map[string]outputFormat{
+	"aac":      {codec: "aac", muxer: "adts", container: "aac", contentType: "audio/aac", extension: ".aac"},
+	"mp3":      {codec: "libmp3lame", muxer: "mp3", container: "mp3", contentType: "audio/mpeg", extension: ".mp3"},
+	"opus":     {codec: "libopus", muxer: "opus", container: "ogg", contentType: "audio/opus", extension: ".opus"},
+	"flac":     {codec: "flac", muxer: "flac", container: "flac", contentType: "audio/flac", extension: ".flac", lossless: true},
+	"vorbis":   {codec: "libvorbis", muxer: "ogg", container: "ogg", contentType: "audio/vorbis", extension: ".ogg"},
+	"wav":      {codec: "pcm_s16le", muxer: "wav", container: "wav", contentType: "audio/wav", extension: ".wav", lossless: true},
+	"alac":     {codec: "alac", muxer: "mp4", container: "mp4", contentType: "audio/alac", extension: ".m4a", lossless: true},
+	"wma":      {codec: "wmav2", muxer: "asf", container: "asf", contentType: "audio/x-ms-wma", extension: ".wma"},
+	"aac_latm": {codec: "aac", muxer: "latm", container: "latm", contentType: "audio/aac", extension: ".latm"},
+}
 
-	if useCache {
-		// serve from cache if it exists
-		if _, err := os.Stat(cachePath); err == nil {
+// IsSupportedFormat reports whether audio can be transcoded to a stream format
+func IsSupportedFormat(format string) bool {
+	_, found := outputFormats[format]
+	return found
+}
+
+// GetFileExtension returns the file extension of a stream format, including the dot
+func GetFileExtension(format string) string {
+	return outputFormats[format].extension
+}
+
+// TranscodeAndStream transcodes a media file and streams it, caching the output unless it starts from a time offset.
+// CUE sheet tracks are transcoded from their time slice of the source file.
+// A MaxSampleRate above 0 resamples files with a higher sample rate, and the other DSP options add ffmpeg filters.
+// Cached transcodes are only served while the file's size and modification time are unchanged.
+// They are served with Range support, and a range request on a transcode that is not cached
+// restarts it from the time of the byte offset, estimated from the bit rate.
+func TranscodeAndStream(ctx context.Context, w http.ResponseWriter, r *http.Request, mediaFile types.MediaFile, trackId string, options types.TranscodeOptions) error {
+	filePathAbs := mediaFile.FilePath
+	format, maxBitRate, timeOffset := options.Format, options.MaxBitRate, options.TimeOffset
+
+	output, found := outputFormats[format]
+	if !found {
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+
+	dsp := getDsp(mediaFile, options, format)
+	cacheKey := getCacheKey(mediaFile, trackId, options, dsp)
+	cachePath := filepath.Join(config.AudioCacheFolder, cacheKey)
+
+	// serve from cache if it exists and is of the current version of the file
+	sourceVersion := getSourceVersion(filePathAbs)
+	if timeOffset <= 0 {
+		if isCached(ctx, cachePath, cacheKey, sourceVersion) {
 			logger.Printf("Serving transcoded file from cache for %s: %s", filePathAbs, cachePath)
-			if err := database.UpsertAudioCacheEntry(ctx, cacheKey); err != nil {
-				logger.Printf("Failed to update last_accessed for %s: %v", cacheKey, err)
-			}
-			f, err := os.Open(cachePath)
-			if err != nil {
-				return fmt.Errorf("opening cached file: %w", err)
-			}
-			defer f.Close()
-			fileInfo, err := f.Stat()
-			if err != nil {
-				return fmt.Errorf("getting file info: %w", err)
-			}
-			w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-			w.Header().Set("Cache-Control", "public, max-age=31536000")
-			w.Header().Set("Content-Type", fmt.Sprintf("audio/%s", format))
-			_, err = io.Copy(w, f)
-			return err
+			return serveCachedFile(ctx, w, r, cachePath, cacheKey, output.contentType)
 		}
+	}
+
+	contentLength := estimateContentLength(mediaFile, maxBitRate, timeOffset, output)
+	rangeStart, isRange, err := getRangeStart(r, contentLength)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", contentLength))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return nil
+	}
+
+	useCache := timeOffset <= 0 && rangeStart == 0
 
+	if useCache {
 		// check if another request is already transcoding this file
 		mutex, loaded := activeTranscodes.LoadOrStore(cacheKey, &sync.Mutex{})
 		transcodeInProgress := loaded
@@ -80,25 +126,9 @@ func TranscodeAndStream(ctx context.Context, w http.ResponseWriter, r *http.Requ
 			select {
 			case <-done:
 				// transcode finished, check if cache file now exists
-				if _, err := os.Stat(cachePath); err == nil {
+				if isCached(ctx, cachePath, cacheKey, sourceVersion) {
 					logger.Printf("Serving transcoded file from cache for %s after waiting: %s", filePathAbs, cachePath)
-					if err := database.UpsertAudioCacheEntry(ctx, cacheKey); err != nil {
-						logger.Printf("Failed to update last_accessed for %s: %v", cacheKey, err)
-					}
-					f, err := os.Open(cachePath)
-					if err != nil {
-						return fmt.Errorf("opening cached file: %w", err)
-					}
-					defer f.Close()
-					fileInfo, err := f.Stat()
-					if err != nil {
-						return fmt.Errorf("getting file info: %w", err)
-					}
-					w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-					w.Header().Set("Cache-Control", "public, max-age=31536000")
-					w.Header().Set("Content-Type", fmt.Sprintf("audio/%s", format))
-					_, err = io.Copy(w, f)
-					return err
+					return serveCachedFile(ctx, w, r, cachePath, cacheKey, output.contentType)
 				}
 				// Cache file doesn't exist, fallback to not using cache
 				logger.Printf("Cache file not found after waiting, will transcode: %s", cacheKey)
@@ -118,65 +148,45 @@ func TranscodeAndStream(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		}()
 	}
 
-	if timeOffset > 0 {
-		logger.Printf("Transcoding %s to stream at %s %dk starting from %ds (no cache)", filePathAbs, format, maxBitRate, timeOffset)
+	// seek to the time of the byte offset, as the transcode has a fixed bit rate
+	seekSeconds := 0.0
+	if rangeStart > 0 {
+		seekSeconds = float64(rangeStart) / (float64(maxBitRate) * 1000 / 8)
+	}
+	if timeOffset > 0 || rangeStart > 0 {
+		logger.Printf("Transcoding %s to stream at %s %dk starting from %ss (no cache)", filePathAbs, format, maxBitRate, formatSeconds(float64(timeOffset)+seekSeconds))
 	} else {
 		logger.Printf("Transcoding %s to stream at %s %dk", filePathAbs, format, maxBitRate)
 	}
 
 	// Build ffmpeg arguments based on format
 	args := []string{"-loglevel", "error"}
-	if timeOffset > 0 {
+	start := mediaFile.Start + float64(timeOffset) + seekSeconds
+	if start > 0 {
 		// Place -ss before -i for faster seek
-		args = append(args, "-ss", fmt.Sprintf("%d", timeOffset))
+		args = append(args, "-ss", formatSeconds(start))
 	}
 	args = append(args, "-i", filePathAbs, "-vn")
+	if mediaFile.End > 0 {
+		args = append(args, "-t", formatSeconds(max(mediaFile.End-start, 0)))
+	}
 
-	var codec, muxer, contentType string
-	switch format {
-	case "aac":
-		codec = "aac"
-		muxer = "adts"
-		contentType = "audio/aac"
-	case "mp3":
-		codec = "libmp3lame"
-		muxer = "mp3"
-		contentType = "audio/mpeg"
-	case "opus":
-		codec = "libopus"
-		muxer = "opus"
-		contentType = "audio/opus"
-	case "flac":
-		codec = "flac"
-		muxer = "flac"
-		contentType = "audio/flac"
-	case "vorbis":
-		codec = "libvorbis"
-		muxer = "ogg"
-		contentType = "audio/vorbis"
-	case "wav":
-		codec = "pcm_s16le"
-		muxer = "wav"
-		contentType = "audio/wav"
-	case "alac":
-		codec = "alac"
-		muxer = "mp4"
-		contentType = "audio/alac"
-	case "wma":
-		codec = "wmav2"
-		muxer = "asf"
-		contentType = "audio/x-ms-wma"
-	case "aac_latm":
-		codec = "aac"
-		muxer = "latm"
-		contentType = "audio/aac"
-	default:
-		return fmt.Errorf("unsupported format: %s", format)
+	codec, muxer, contentType := output.codec, output.muxer, output.contentType
+
+	args = append(args, "-c:a", codec)
+	if maxBitRate > 0 {
+		args = append(args, "-b:a", fmt.Sprintf("%dk", maxBitRate))
 	}
+	args = append(args, dsp.getArgs()...)
+	args = append(args, "-f", muxer, "pipe:1")
 
-	args = append(args, "-c:a", codec, "-b:a", fmt.Sprintf("%dk", maxBitRate), "-f", muxer, "pipe:1")
+	transcodeCtx, transcode, err := startTranscode(ctx, r, trackId, format, maxBitRate)
+	if err != nil {
+		return err
+	}
+	defer transcode.finish()
 
-	cmd := exec.CommandContext(ctx, config.FfmpegPath, args...)
+	cmd := exec.CommandContext(transcodeCtx, config.FfmpegPath, args...)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -200,13 +210,31 @@ func TranscodeAndStream(ctx context.Context, w http.ResponseWriter, r *http.Requ
 
 	w.Header().Set("Content-Type", contentType)
 
-	var mw io.Writer = w
+	// the bytes sent are counted for the running transcodes, and the response is cut at its estimated length,
+	// as the transcode can come out slightly longer
+	responseWriter := transcode.countWrites(w)
+	if contentLength > 0 {
+		w.Header().Set("Accept-Ranges", "bytes")
+	}
+	if isRange {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", rangeStart, contentLength-1, contentLength))
+		w.Header().Set("Content-Length", strconv.FormatInt(contentLength-rangeStart, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		responseWriter = &cappedWriter{w: responseWriter, remaining: contentLength - rangeStart}
+	} else if options.EstimateContentLength && contentLength > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(contentLength, 10))
+		responseWriter = &cappedWriter{w: responseWriter, remaining: contentLength}
+	}
+
+	var mw io.Writer = responseWriter
 	var cacheFile *os.File
 	var tempCachePath string
 	cacheFileCreated := false
 	defer func() {
-		// clean up temp file if it exists and we didn't finish successfully
+		// clean up temp file if it exists and we didn't finish successfully, closing it first
+		// for the returns that don't, such as a transcode stopped by a client disconnect or an admin
 		if useCache && cacheFileCreated {
+			cacheFile.Close()
 			if _, err := os.Stat(tempCachePath); err == nil {
 				os.Remove(tempCachePath)
 			}
@@ -223,7 +251,7 @@ func TranscodeAndStream(ctx context.Context, w http.ResponseWriter, r *http.Requ
 			return fmt.Errorf("creating temp cache file: %w", err)
 		}
 		cacheFileCreated = true
-		mw = io.MultiWriter(w, cacheFile)
+		mw = io.MultiWriter(responseWriter, cacheFile)
 	}
 
 	_, err = io.Copy(mw, stdout)
@@ -243,6 +271,10 @@ func TranscodeAndStream(ctx context.Context, w http.ResponseWriter, r *http.Requ
 			logger.Printf("ffmpeg killed due to client disconnect: %s (trackId=%s, client=%s, UA=%s)", filePathAbs, trackId, r.RemoteAddr, r.UserAgent())
 			return nil
 		}
+		if transcodeCtx.Err() != nil {
+			logger.Printf("ffmpeg killed by an admin: %s (trackId=%s, client=%s, UA=%s)", filePathAbs, trackId, r.RemoteAddr, r.UserAgent())
+			return nil
+		}
 		logger.Printf("ffmpeg exited with error while streaming %s (trackId=%s, client=%s, UA=%s): %v", filePathAbs, trackId, r.RemoteAddr, r.UserAgent(), waitErr)
 		if useCache && cacheFileCreated {
 			cacheFile.Close()
@@ -253,6 +285,11 @@ func TranscodeAndStream(ctx context.Context, w http.ResponseWriter, r *http.Requ
 
 	if useCache && cacheFileCreated {
 		cacheFile.Close()
+		// the cache row is written first, so that a cached file always has the version of the file it was transcoded from
+		if err = database.UpsertAudioCacheEntry(ctx, cacheKey, filePathAbs, sourceVersion); err != nil {
+			logger.Printf("Error upserting audiocache entry for: %s", cacheKey)
+			return err
+		}
 		// move temp file to final cache path
 		if err := os.Rename(tempCachePath, cachePath); err != nil {
 			cleanupIncompleteCache(tempCachePath, cacheKey)
@@ -261,14 +298,46 @@ func TranscodeAndStream(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		// tempCachePath is now gone, so don't try to clean it up in defer
 		cacheFileCreated = false
 		logger.Printf("Transcoding %s complete, cached at %s", filePathAbs, cachePath)
-		if err = database.UpsertAudioCacheEntry(ctx, cacheKey); err != nil {
-			cleanupIncompleteCache(cachePath, cacheKey)
-			logger.Printf("Error upserting audiocache entry for: %s", cacheKey)
-			return err
-		}
 	} else {
 		logger.Printf("Transcoding %s complete (offset stream, not cached)", filePathAbs)
 	}
 
 	return nil
 }
+
+// getCacheId returns the start of the cache keys for a media file, as each version of a song is transcoded from a different file
+func getCacheId(mediaFile types.MediaFile, trackId string) string {
+	if mediaFile.VersionId != "" {
+		return trackId + "-" + mediaFile.VersionId
+	}
+	return trackId
+}
+
+// getCacheKey returns the name of the cached transcode of a media file
+func getCacheKey(mediaFile types.MediaFile, trackId string, options types.TranscodeOptions, dsp dsp) string {
+	return fmt.Sprintf("%s-%d%s.%s", getCacheId(mediaFile, trackId), options.MaxBitRate, dsp.cacheKey, options.Format)
+}
+
+// getDuration returns the duration of a media file in seconds, or 0 when it is not known
+func getDuration(mediaFile types.MediaFile) float64 {
+	if mediaFile.Duration <= 0 && mediaFile.End > 0 {
+		return mediaFile.End - mediaFile.Start
+	}
+	return mediaFile.Duration
+}
+
+// getOutputSampleRate returns the sample rate to resample to, or 0 to keep the file's sample rate.
+// Files are never upsampled, and opus is left alone as it is always encoded at 48kHz.
+func getOutputSampleRate(mediaFile types.MediaFile, maxSampleRate int, format string) int {
+	if maxSampleRate <= 0 || format == "opus" {
+		return 0
+	}
+	if mediaFile.SampleRate > 0 && mediaFile.SampleRate <= maxSampleRate {
+		return 0
+	}
+	return maxSampleRate
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
@@ -0,0 +1,341 @@
+package ffmpeg
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"zene/core/config"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+// transcode reasons, as returned in a transcode decision
+const (
+	reasonContainerNotSupported       = "ContainerNotSupported"
+	reasonAudioCodecNotSupported      = "AudioCodecNotSupported"
+	reasonProtocolNotSupported        = "ProtocolNotSupported"
+	reasonAudioChannelsNotSupported   = "AudioChannelsNotSupported"
+	reasonAudioBitrateNotSupported    = "AudioBitrateNotSupported"
+	reasonAudioProfileNotSupported    = "AudioProfileNotSupported"
+	reasonAudioSampleRateNotSupported = "AudioSampleRateNotSupported"
+	reasonAudioBitDepthNotSupported   = "AudioBitDepthNotSupported"
+	reasonDirectPlayNotAllowed        = "DirectPlayNotAllowed"
+	reasonCueSheetTrack               = "CueSheetTrack"
+)
+
+// transcodeFormatOrder is the order formats are matched against a transcoding profile,
+// so that a profile asking for aac without a container gets the adts stream that stream returns
+var transcodeFormatOrder = []string{"aac", "mp3", "opus", "flac", "vorbis", "wav", "alac", "wma", "aac_latm"}
+
+var containerAliases = map[string]string{
+	"m4a":  "mp4",
+	"m4b":  "mp4",
+	"oga":  "ogg",
+	"opus": "ogg",
+	"adts": "aac",
+	"wma":  "asf",
+	"aif":  "aiff",
+}
+
+// codecAliases maps ffmpeg encoders, and the suffixes used when a file's codec is not known, to codec names
+var codecAliases = map[string]string{
+	"libmp3lame": "mp3",
+	"libopus":    "opus",
+	"libvorbis":  "vorbis",
+	"wmav2":      "wma",
+	"m4a":        "aac",
+	"ogg":        "vorbis",
+	"oga":        "vorbis",
+}
+
+func normaliseContainer(container string) string {
+	container = strings.ToLower(strings.TrimSpace(container))
+	return cmp.Or(containerAliases[container], container)
+}
+
+func normaliseCodec(codec string) string {
+	codec = strings.ToLower(strings.TrimSpace(codec))
+	if strings.HasPrefix(codec, "pcm") || codec == "wav" {
+		return "pcm"
+	}
+	return cmp.Or(codecAliases[codec], codec)
+}
+
+// supportsHttp reports whether a client protocol can be served, as files are only streamed over plain HTTP
+func supportsHttp(protocol string) bool {
+	protocol = strings.ToLower(strings.TrimSpace(protocol))
+	return protocol == "" || protocol == "http" || protocol == "https"
+}
+
+// GetSourceStreamDetails describes a song version as it is stored
+func GetSourceStreamDetails(version types.SongVersion) types.StreamDetails {
+	return types.StreamDetails{
+		Protocol:        "http",
+		Container:       normaliseContainer(version.Suffix),
+		Codec:           normaliseCodec(cmp.Or(version.Codec, version.Suffix)),
+		AudioChannels:   version.ChannelCount,
+		AudioBitrate:    version.BitRate,
+		AudioSamplerate: version.SamplingRate,
+		AudioBitdepth:   version.BitDepth,
+	}
+}
+
+// MakeTranscodeDecision decides whether a client can play a song version as it is, and otherwise what to transcode it to,
+// using the first transcoding profile of the client that can be streamed.
+// The player's profile is used for what the client does not limit, and the user's maximum bit rate (in kbps) always applies.
+func MakeTranscodeDecision(version types.SongVersion, clientInfo types.ClientInfo, player types.Player, userMaxBitRate int) types.TranscodeDecision {
+	source := GetSourceStreamDetails(version)
+	decision := types.TranscodeDecision{
+		SourceStream: &source,
+	}
+
+	decision.TranscodeReason = getDirectPlayFailures(source, version.MediaFile.IsCueTrack, clientInfo, player, userMaxBitRate)
+	if len(decision.TranscodeReason) == 0 {
+		decision.CanDirectPlay = true
+		decision.TranscodeParams = EncodeTranscodeParams(types.TranscodeParams{
+			VersionId: version.Id,
+			Format:    "raw",
+		})
+		return decision
+	}
+
+	for _, profile := range clientInfo.TranscodingProfiles {
+		format, found := getTranscodingProfileFormat(profile)
+		if !found {
+			continue
+		}
+
+		output := outputFormats[format]
+		params := types.TranscodeParams{
+			VersionId:     version.Id,
+			Format:        format,
+			MaxSampleRate: getMaxSampleRate(clientInfo.CodecProfiles, normaliseCodec(output.codec), player.MaxSampleRate),
+		}
+		// audio with more channels than the profile takes is downmixed, to stereo or to mono for a mono profile
+		if profile.MaxAudioChannels > 0 && source.AudioChannels > profile.MaxAudioChannels {
+			params.Channels = min(profile.MaxAudioChannels, 2)
+		}
+		if !output.lossless {
+			params.MaxBitRate = cmp.Or(
+				logic.MinPositive(clientInfo.MaxTranscodingAudioBitrate/1000, clientInfo.MaxAudioBitrate/1000),
+				player.MaxBitRate,
+				config.DefaultBitRate,
+			)
+			if userMaxBitRate > 0 {
+				params.MaxBitRate = min(params.MaxBitRate, userMaxBitRate)
+			}
+		}
+
+		transcodeStream := types.StreamDetails{
+			Protocol:        "http",
+			Container:       output.container,
+			Codec:           normaliseCodec(output.codec),
+			AudioChannels:   cmp.Or(params.Channels, source.AudioChannels),
+			AudioBitrate:    params.MaxBitRate * 1000,
+			AudioSamplerate: cmp.Or(getOutputSampleRate(version.MediaFile, params.MaxSampleRate, format), source.AudioSamplerate),
+		}
+		switch format {
+		case "opus":
+			transcodeStream.AudioSamplerate = 48000
+		case "wav":
+			transcodeStream.AudioBitdepth = 16
+		case "flac", "alac":
+			transcodeStream.AudioBitdepth = source.AudioBitdepth
+		}
+
+		decision.CanTranscode = true
+		decision.TranscodeParams = EncodeTranscodeParams(params)
+		decision.TranscodeStream = &transcodeStream
+		return decision
+	}
+
+	decision.ErrorReason = "no transcoding profile of the client can be streamed"
+	return decision
+}
+
+// getDirectPlayFailures returns the reasons a client cannot play a source stream as it is, or none when it can.
+// When no direct play profile matches, the reasons are those of the profile that came closest.
+func getDirectPlayFailures(source types.StreamDetails, isCueTrack bool, clientInfo types.ClientInfo, player types.Player, userMaxBitRate int) []string {
+	if !player.AllowRaw {
+		return []string{reasonDirectPlayNotAllowed}
+	}
+	// a CUE sheet track is only part of its source file
+	if isCueTrack {
+		return []string{reasonCueSheetTrack}
+	}
+
+	var reasons []string
+	if maxBitRate := logic.MinPositive(clientInfo.MaxAudioBitrate, userMaxBitRate*1000); maxBitRate > 0 && source.AudioBitrate > maxBitRate {
+		reasons = append(reasons, reasonAudioBitrateNotSupported)
+	}
+
+	var profileReasons []string
+	for i, profile := range clientInfo.DirectPlayProfiles {
+		failures := getDirectPlayProfileFailures(source, profile)
+		if i == 0 || len(failures) < len(profileReasons) {
+			profileReasons = failures
+		}
+		if len(failures) == 0 {
+			break
+		}
+	}
+	if len(clientInfo.DirectPlayProfiles) == 0 {
+		profileReasons = []string{reasonContainerNotSupported}
+	}
+	reasons = append(reasons, profileReasons...)
+
+	for _, codecProfile := range clientInfo.CodecProfiles {
+		if !strings.EqualFold(codecProfile.Type, "AudioCodec") || normaliseCodec(codecProfile.Name) != source.Codec {
+			continue
+		}
+		for _, limitation := range codecProfile.Limitations {
+			if reason := getLimitationFailure(source, limitation); reason != "" && !slices.Contains(reasons, reason) {
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+
+	return reasons
+}
+
+func getDirectPlayProfileFailures(source types.StreamDetails, profile types.DirectPlayProfile) []string {
+	var failures []string
+	if len(profile.Protocols) > 0 && !slices.ContainsFunc(profile.Protocols, supportsHttp) {
+		failures = append(failures, reasonProtocolNotSupported)
+	}
+	if len(profile.Containers) > 0 && !slices.ContainsFunc(profile.Containers, func(container string) bool {
+		return normaliseContainer(container) == source.Container
+	}) {
+		failures = append(failures, reasonContainerNotSupported)
+	}
+	if len(profile.AudioCodecs) > 0 && !slices.ContainsFunc(profile.AudioCodecs, func(codec string) bool {
+		return normaliseCodec(codec) == source.Codec
+	}) {
+		failures = append(failures, reasonAudioCodecNotSupported)
+	}
+	if profile.MaxAudioChannels > 0 && source.AudioChannels > profile.MaxAudioChannels {
+		failures = append(failures, reasonAudioChannelsNotSupported)
+	}
+	return failures
+}
+
+// getLimitationFailure returns the transcode reason for a codec profile limitation the source stream does not meet, or an empty string
+func getLimitationFailure(source types.StreamDetails, limitation types.Limitation) string {
+	var reason string
+	var value string
+	switch strings.ToLower(limitation.Name) {
+	case "audiochannels":
+		reason, value = reasonAudioChannelsNotSupported, strconv.Itoa(source.AudioChannels)
+	case "audiobitrate":
+		reason, value = reasonAudioBitrateNotSupported, strconv.Itoa(source.AudioBitrate)
+	case "audioprofile":
+		reason, value = reasonAudioProfileNotSupported, source.AudioProfile
+	case "audiosamplerate":
+		reason, value = reasonAudioSampleRateNotSupported, strconv.Itoa(source.AudioSamplerate)
+	case "audiobitdepth":
+		reason, value = reasonAudioBitDepthNotSupported, strconv.Itoa(source.AudioBitdepth)
+	default:
+		return ""
+	}
+
+	if value == "" || value == "0" {
+		if limitation.Required {
+			return reason
+		}
+		return ""
+	}
+	if len(limitation.Values) == 0 {
+		return ""
+	}
+
+	var met bool
+	switch strings.ToLower(limitation.Comparison) {
+	case "equals":
+		met = slices.ContainsFunc(limitation.Values, func(v string) bool { return strings.EqualFold(v, value) })
+	case "notequals":
+		met = !slices.ContainsFunc(limitation.Values, func(v string) bool { return strings.EqualFold(v, value) })
+	case "lessthanequal", "greaterthanequal":
+		number, err := strconv.Atoi(value)
+		limit, limitErr := strconv.Atoi(limitation.Values[0])
+		if err != nil || limitErr != nil {
+			return ""
+		}
+		met = number <= limit
+		if strings.EqualFold(limitation.Comparison, "GreaterThanEqual") {
+			met = number >= limit
+		}
+	default:
+		return ""
+	}
+
+	if !met {
+		return reason
+	}
+	return ""
+}
+
+// getTranscodingProfileFormat returns the stream format for a transcoding profile, matching its codec and container
+func getTranscodingProfileFormat(profile types.TranscodingProfile) (string, bool) {
+	if !supportsHttp(profile.Protocol) || (profile.AudioCodec == "" && profile.Container == "") {
+		return "", false
+	}
+	for _, format := range transcodeFormatOrder {
+		output := outputFormats[format]
+		if profile.AudioCodec != "" && normaliseCodec(profile.AudioCodec) != normaliseCodec(output.codec) {
+			continue
+		}
+		if profile.Container != "" && normaliseContainer(profile.Container) != output.container {
+			continue
+		}
+		return format, true
+	}
+	return "", false
+}
+
+// getMaxSampleRate returns the lowest sample rate the client's codec profiles and the player allow for a codec, or 0 for no limit
+func getMaxSampleRate(codecProfiles []types.CodecProfile, codec string, playerMaxSampleRate int) int {
+	maxSampleRate := playerMaxSampleRate
+	for _, codecProfile := range codecProfiles {
+		if !strings.EqualFold(codecProfile.Type, "AudioCodec") || normaliseCodec(codecProfile.Name) != codec {
+			continue
+		}
+		for _, limitation := range codecProfile.Limitations {
+			if !strings.EqualFold(limitation.Name, "audioSamplerate") || !strings.EqualFold(limitation.Comparison, "LessThanEqual") || len(limitation.Values) == 0 {
+				continue
+			}
+			if limit, err := strconv.Atoi(limitation.Values[0]); err == nil {
+				maxSampleRate = logic.MinPositive(maxSampleRate, limit)
+			}
+		}
+	}
+	return maxSampleRate
+}
+
+// EncodeTranscodeParams encodes how a file is streamed as the opaque transcodeParams of a transcode decision
+func EncodeTranscodeParams(params types.TranscodeParams) string {
+	encoded, _ := json.Marshal(params)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeTranscodeParams decodes the transcodeParams of a transcode decision, checking that its format can be streamed
+func DecodeTranscodeParams(transcodeParams string) (types.TranscodeParams, error) {
+	var params types.TranscodeParams
+	decoded, err := base64.RawURLEncoding.DecodeString(transcodeParams)
+	if err != nil {
+		return params, fmt.Errorf("decoding transcode params: %w", err)
+	}
+	if err := json.Unmarshal(decoded, &params); err != nil {
+		return params, fmt.Errorf("parsing transcode params: %w", err)
+	}
+	if params.Format != "raw" && !IsSupportedFormat(params.Format) {
+		return params, fmt.Errorf("unsupported format in transcode params: %s", params.Format)
+	}
+	if params.MaxBitRate < 0 || params.MaxSampleRate < 0 || params.Channels < 0 || params.Channels > 2 {
+		return params, fmt.Errorf("invalid limits in transcode params")
+	}
+	return params, nil
+}
@@ -0,0 +1,148 @@
+package ffmpeg
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/types"
+)
+
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// serveCachedFile serves a cached transcode with support for Range, If-Range and ETag requests
+func serveCachedFile(ctx context.Context, w http.ResponseWriter, r *http.Request, cachePath string, cacheKey string, contentType string) error {
+	f, err := os.Open(cachePath)
+	if err != nil {
+		return fmt.Errorf("opening cached file: %w", err)
+	}
+	defer f.Close()
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("getting file info: %w", err)
+	}
+
+	if err := database.UpdateAudioCacheLastAccessed(ctx, cacheKey); err != nil {
+		logger.Printf("Failed to update last_accessed for %s: %v", cacheKey, err)
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", getCacheETag(cacheKey, fileInfo))
+	http.ServeContent(w, r, cacheKey, fileInfo.ModTime(), f)
+	return nil
+}
+
+// getSourceVersion identifies the version of a source file by its size and modification time,
+// which change when it is replaced or re-tagged, without reading the whole file
+func getSourceVersion(filePath string) string {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", fileInfo.Size(), fileInfo.ModTime().UnixNano())
+}
+
+// isCached reports whether a transcode is in the cache and was transcoded from the current version of its source file.
+// A transcode of another version, or one without a cache row, is removed so that it is transcoded again.
+func isCached(ctx context.Context, cachePath string, cacheKey string, sourceVersion string) bool {
+	if _, err := os.Stat(cachePath); err != nil {
+		return false
+	}
+
+	entry, err := database.GetAudioCacheEntry(ctx, cacheKey)
+	if err == nil && sourceVersion != "" && entry.SourceVersion == sourceVersion {
+		return true
+	}
+
+	logger.Printf("Cached transcode %s is not of the current version of its source file, removing it", cacheKey)
+	if err := os.Remove(cachePath); err != nil {
+		logger.Printf("Failed to remove cache file %s: %v", cachePath, err)
+	}
+	if err := database.DeleteAudioCacheEntry(cacheKey); err != nil {
+		logger.Printf("Failed to delete audio cache entry for %s: %v", cacheKey, err)
+	}
+	return false
+}
+
+// getCacheETag returns a strong ETag for a cached transcode, which changes when the file is transcoded again
+func getCacheETag(cacheKey string, fileInfo os.FileInfo) string {
+	hash := sha256.Sum256([]byte(cacheKey))
+	return fmt.Sprintf(`"%x-%x-%x"`, hash[:8], fileInfo.Size(), fileInfo.ModTime().UnixNano())
+}
+
+// estimateContentLength estimates the length of a transcode from its duration and bit rate.
+// It returns 0 when the length cannot be estimated, as lossless formats and encoder default bit rates have no fixed bit rate.
+func estimateContentLength(mediaFile types.MediaFile, maxBitRate int, timeOffset int, output outputFormat) int64 {
+	if output.lossless || maxBitRate <= 0 {
+		return 0
+	}
+	remaining := getDuration(mediaFile) - float64(timeOffset)
+	if remaining <= 0 {
+		return 0
+	}
+	return int64(remaining * float64(maxBitRate) * 1000 / 8)
+}
+
+// getRangeStart returns the first byte of a single range request on a transcode of an estimated length,
+// and whether the request is a range request that can be served. Multiple ranges, and ranges with an If-Range
+// condition that cannot be checked without a cached file, are served as the whole transcode.
+func getRangeStart(r *http.Request, contentLength int64) (int64, bool, error) {
+	rangeHeader := r.Header.Get("Range")
+	if rangeHeader == "" || contentLength <= 0 || r.Header.Get("If-Range") != "" {
+		return 0, false, nil
+	}
+
+	spec, found := strings.CutPrefix(rangeHeader, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, false, nil
+	}
+	startString, endString, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, false, nil
+	}
+
+	if startString == "" {
+		// a suffix range asks for the last bytes of the transcode
+		suffixLength, err := strconv.ParseInt(endString, 10, 64)
+		if err != nil || suffixLength <= 0 {
+			return 0, false, errRangeNotSatisfiable
+		}
+		return max(contentLength-suffixLength, 0), true, nil
+	}
+
+	start, err := strconv.ParseInt(startString, 10, 64)
+	if err != nil || start < 0 {
+		return 0, false, nil
+	}
+	if start >= contentLength {
+		return 0, false, errRangeNotSatisfiable
+	}
+	return start, true, nil
+}
+
+// cappedWriter writes up to a number of bytes, and discards the rest of the transcode,
+// so that a response never goes past its estimated Content-Length while the cache still gets the whole file
+type cappedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (c *cappedWriter) Write(p []byte) (int, error) {
+	if c.remaining <= 0 {
+		return len(p), nil
+	}
+	n, err := c.w.Write(p[:min(int64(len(p)), c.remaining)])
+	c.remaining -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,36 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"zene/core/types"
+)
+
+// TranscodeToWriter transcodes a media file to a writer that is not an HTTP response, such as a file in a ZIP archive,
+// through the audio cache like TranscodeAndStream. The transcode is listed with the client it is for.
+func TranscodeToWriter(ctx context.Context, w io.Writer, client string, mediaFile types.MediaFile, trackId string, options types.TranscodeOptions) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, "/?"+url.Values{"c": {client}}.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("creating transcode request: %w", err)
+	}
+	return TranscodeAndStream(ctx, &writerResponse{w: w, header: http.Header{}}, r, mediaFile, trackId, options)
+}
+
+// writerResponse is a response that only writes its body to a writer, dropping its headers and status code
+type writerResponse struct {
+	w      io.Writer
+	header http.Header
+}
+
+func (wr *writerResponse) Header() http.Header {
+	return wr.header
+}
+
+func (wr *writerResponse) Write(p []byte) (int, error) {
+	return wr.w.Write(p)
+}
+
+func (wr *writerResponse) WriteHeader(statusCode int) {}
@@ -0,0 +1,29 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"zene/core/config"
+	"zene/core/types"
+)
+
+// WarmCache transcodes a media file into the audio cache without streaming it, so that its first play starts from the cache.
+// It reports whether the file was transcoded, as files that are already cached are skipped.
+// The transcode is queued for a slot like any other, and is listed with a client of warmup.
+func WarmCache(ctx context.Context, mediaFile types.MediaFile, trackId string, options types.TranscodeOptions) (bool, error) {
+	if !IsSupportedFormat(options.Format) {
+		return false, fmt.Errorf("unsupported format: %s", options.Format)
+	}
+
+	cacheKey := getCacheKey(mediaFile, trackId, options, getDsp(mediaFile, options, options.Format))
+	if isCached(ctx, filepath.Join(config.AudioCacheFolder, cacheKey), cacheKey, getSourceVersion(mediaFile.FilePath)) {
+		return false, nil
+	}
+
+	if err := TranscodeToWriter(ctx, io.Discard, "warmup", mediaFile, trackId, options); err != nil {
+		return false, err
+	}
+	return true, nil
+}
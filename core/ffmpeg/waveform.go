@@ -0,0 +1,105 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"zene/core/config"
+	"zene/core/types"
+)
+
+// waveformSampleRate is the rate audio is decoded at to measure its peaks, which is plenty for a seek bar
+const waveformSampleRate = 8000
+
+// waveformWindow is the number of samples, 10ms at waveformSampleRate, whose peak is kept while decoding,
+// so that a long file is not held in memory before it is known how many samples each slice has
+const waveformWindow = 80
+
+// GetWaveformPeaks decodes a file, or the time slice of a CUE sheet track, to mono and returns the peak amplitude
+// of each of resolution equal slices of it, from 0 for silence to 255 for full scale.
+// The decode takes a transcode slot, and is listed with the running transcodes as a waveform.
+func GetWaveformPeaks(ctx context.Context, trackId string, mediaFile types.MediaFile, resolution int) ([]byte, error) {
+	transcodeCtx, t, err := startTranscode(ctx, nil, trackId, "waveform", 0)
+	if err != nil {
+		return nil, err
+	}
+	defer t.finish()
+
+	var stderr bytes.Buffer
+	audiofilePath := mediaFile.FilePath
+
+	args := []string{"-hide_banner", "-nostats", "-loglevel", "error"}
+	if mediaFile.Start > 0 {
+		args = append(args, "-ss", formatSeconds(mediaFile.Start))
+	}
+	args = append(args, "-i", audiofilePath)
+	if mediaFile.End > 0 {
+		args = append(args, "-t", formatSeconds(mediaFile.End-mediaFile.Start))
+	}
+	args = append(args, "-vn", "-ac", "1", "-ar", fmt.Sprint(waveformSampleRate), "-f", "s16le", "-acodec", "pcm_s16le", "pipe:1")
+
+	cmd := exec.CommandContext(transcodeCtx, config.FfmpegPath, args...)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("getting ffmpeg stdout pipe: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting ffmpeg for %s: %v", audiofilePath, err)
+	}
+
+	windowPeaks, readErr := readWindowPeaks(bufio.NewReader(stdout))
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed for %s: %v\nstderr: %s", audiofilePath, err, stderr.String())
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("reading decoded audio of %s: %v", audiofilePath, readErr)
+	}
+	if len(windowPeaks) == 0 {
+		return nil, fmt.Errorf("no audio decoded from %s", audiofilePath)
+	}
+
+	peaks := make([]byte, resolution)
+	for i := range peaks {
+		start := i * len(windowPeaks) / resolution
+		end := max((i+1)*len(windowPeaks)/resolution, start+1)
+		var peak uint16
+		for _, windowPeak := range windowPeaks[start:end] {
+			peak = max(peak, windowPeak)
+		}
+		peaks[i] = byte(int(peak) * 255 / 32768)
+	}
+	return peaks, nil
+}
+
+// readWindowPeaks reads signed 16-bit little-endian samples and returns the absolute peak of each waveformWindow of them
+func readWindowPeaks(r io.Reader) ([]uint16, error) {
+	windowPeaks := []uint16{}
+	buffer := make([]byte, waveformWindow*2)
+	for {
+		n, err := io.ReadFull(r, buffer)
+		if n >= 2 {
+			var peak uint16
+			for i := 0; i+1 < n; i += 2 {
+				sample := int(int16(binary.LittleEndian.Uint16(buffer[i:])))
+				if sample < 0 {
+					sample = -sample
+				}
+				peak = max(peak, uint16(min(sample, 32767)))
+			}
+			windowPeaks = append(windowPeaks, peak)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return windowPeaks, nil
+		}
+		if err != nil {
+			return windowPeaks, err
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package ffprobe
+
+import (
+	"regexp"
+	"strings"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+// single-valued contributor tags, keyed by credit role
+var contributorTags = []struct {
+	Role string
+	Tags []string
+}{
+	{types.CreditRoleComposer, []string{"composer"}},
+	{types.CreditRoleLyricist, []string{"lyricist", "TEXT"}},
+	{types.CreditRoleConductor, []string{"conductor"}},
+	{types.CreditRoleArranger, []string{"arranger"}},
+	{types.CreditRoleRemixer, []string{"remixer", "MIXARTIST"}},
+	{types.CreditRoleProducer, []string{"producer"}},
+	{types.CreditRoleEngineer, []string{"engineer"}},
+	{types.CreditRoleMixer, []string{"mixer"}},
+	{types.CreditRoleDjMixer, []string{"djmixer"}},
+}
+
+// roles used in ID3v2 involved people lists (TIPL)
+var involvedPeopleRoles = map[string]string{
+	"arranger": types.CreditRoleArranger,
+	"engineer": types.CreditRoleEngineer,
+	"producer": types.CreditRoleProducer,
+	"mix":      types.CreditRoleMixer,
+	"dj-mix":   types.CreditRoleDjMixer,
+}
+
+// matches performer tags like "Name (instrument)"
+var performerRegex = regexp.MustCompile(`^(.+?)\s*\(([^()]+)\)$`)
+
+// splitTagValues splits a multi-valued tag, which ffprobe and the native tag reader join with ";"
+func splitTagValues(value string, separators string) []string {
+	values := []string{}
+	seen := map[string]bool{}
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(separators, r) }) {
+		part = strings.TrimSpace(part)
+		if part != "" && !seen[part] {
+			seen[part] = true
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// getArtistCredits builds the credits for a track from its multi-valued artist and contributor tags.
+// The first artist and album artist credits always use the track's primary artist and album artist IDs,
+// and artists without a MusicBrainz ID are given a synthetic one.
+func getArtistCredits(tags map[string]string, artist string, artistId string, albumArtist string, albumArtistId string) []types.ArtistCredit {
+	credits := []types.ArtistCredit{}
+
+	artistNames := splitTagValues(getTagStringValue(tags, []string{"ARTISTS"}), ";")
+	artistIds := splitTagValues(getTagStringValue(tags, []string{"MUSICBRAINZ_ARTISTID", "MusicBrainz Artist Id"}), ";/")
+	credits = append(credits, getRoleCredits(types.CreditRoleArtist, artistNames, artistIds, artist, artistId)...)
+
+	albumArtistNames := splitTagValues(getTagStringValue(tags, []string{"ALBUMARTISTS"}), ";")
+	albumArtistIds := splitTagValues(getTagStringValue(tags, []string{"MUSICBRAINZ_ALBUMARTISTID", "MusicBrainz Album Artist Id"}), ";/")
+	credits = append(credits, getRoleCredits(types.CreditRoleAlbumArtist, albumArtistNames, albumArtistIds, albumArtist, albumArtistId)...)
+
+	// reuse the IDs of artists credited on the same track, so a composer who is also the artist links to the same page
+	knownIds := map[string]string{}
+	for _, credit := range credits {
+		knownIds[strings.ToLower(credit.Name)] = credit.MusicBrainzArtistID
+	}
+	addContributor := func(role string, subRole string, name string) {
+		position := 0
+		for _, credit := range credits {
+			if credit.Role == role {
+				if strings.EqualFold(credit.Name, name) && credit.SubRole == subRole {
+					return
+				}
+				position++
+			}
+		}
+		id, found := knownIds[strings.ToLower(name)]
+		if !found {
+			id = logic.GenerateSyntheticId("artist", name)
+		}
+		credits = append(credits, types.ArtistCredit{Role: role, SubRole: subRole, Position: position, Name: name, MusicBrainzArtistID: id})
+	}
+
+	for _, contributorTag := range contributorTags {
+		for _, name := range splitTagValues(getTagStringValue(tags, contributorTag.Tags), ";") {
+			addContributor(contributorTag.Role, "", name)
+		}
+	}
+
+	for _, performer := range splitTagValues(getTagStringValue(tags, []string{"performer"}), ";") {
+		if matches := performerRegex.FindStringSubmatch(performer); len(matches) == 3 {
+			addContributor(types.CreditRolePerformer, matches[2], matches[1])
+		} else {
+			addContributor(types.CreditRolePerformer, "", performer)
+		}
+	}
+
+	// ID3v2 involved people (TIPL) and musician credits (TMCL) lists are pairs of role and name
+	involvedPeople := strings.Split(getTagStringValue(tags, []string{"TIPL", "IPLS"}), ";")
+	for i := 0; i+1 < len(involvedPeople); i += 2 {
+		role, found := involvedPeopleRoles[strings.ToLower(strings.TrimSpace(involvedPeople[i]))]
+		name := strings.TrimSpace(involvedPeople[i+1])
+		if found && name != "" {
+			addContributor(role, "", name)
+		}
+	}
+	musicianCredits := strings.Split(getTagStringValue(tags, []string{"TMCL"}), ";")
+	for i := 0; i+1 < len(musicianCredits); i += 2 {
+		instrument := strings.TrimSpace(musicianCredits[i])
+		name := strings.TrimSpace(musicianCredits[i+1])
+		if name != "" {
+			addContributor(types.CreditRolePerformer, instrument, name)
+		}
+	}
+
+	return credits
+}
+
+// getRoleCredits pairs artist names with MusicBrainz IDs by position, falling back to the display name and primary ID
+func getRoleCredits(role string, names []string, ids []string, displayName string, primaryId string) []types.ArtistCredit {
+	if len(names) == 0 {
+		names = []string{displayName}
+	}
+
+	credits := make([]types.ArtistCredit, 0, len(names))
+	for position, name := range names {
+		var id string
+		switch {
+		case position == 0 && primaryId != "":
+			id = primaryId
+		case len(ids) == len(names):
+			id = ids[position]
+		default:
+			id = logic.GenerateSyntheticId("artist", name)
+		}
+		credits = append(credits, types.ArtistCredit{Role: role, Position: position, Name: name, MusicBrainzArtistID: id})
+	}
+	return credits
+}
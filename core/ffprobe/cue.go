@@ -0,0 +1,150 @@
+package ffprobe
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"zene/core/cue"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+// tags that describe the whole release, so they are kept for every track of a CUE sheet.
+// Track tags like the title, artist, MusicBrainz track ID, lyrics and track ReplayGain only describe the whole file.
+var cueAlbumTagKeys = map[string]bool{
+	"album":                       true,
+	"album_artist":                true,
+	"album-artist":                true,
+	"albumartist":                 true,
+	"albumartists":                true,
+	"date":                        true,
+	"originaldate":                true,
+	"original_date":               true,
+	"release_date":                true,
+	"genre":                       true,
+	"label":                       true,
+	"publisher":                   true,
+	"disc":                        true,
+	"totaldiscs":                  true,
+	"musicbrainz_albumid":         true,
+	"musicbrainz album id":        true,
+	"musicbrainz_albumartistid":   true,
+	"musicbrainz album artist id": true,
+	"replaygain_album_gain":       true,
+	"replaygain_album_peak":       true,
+	"r128_album_gain":             true,
+}
+
+// GetMetadataWithCueTracks returns the metadata for an audio file, or for each of its tracks if it is split by a CUE sheet,
+// either in the same directory or embedded in a CUESHEET tag
+func GetMetadataWithCueTracks(ctx context.Context, audiofilePath string) ([]types.FileMetadata, error) {
+	fileTags, err := getFileTags(ctx, audiofilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet, cueFile, found := cue.FindSheetForAudioFile(audiofilePath)
+	if !found {
+		if embeddedSheet := getTagStringValue(fileTags.Tags, []string{"cuesheet"}); embeddedSheet != "" {
+			sheet, err = cue.Parse([]byte(embeddedSheet))
+			if err != nil {
+				logger.Printf("Ignoring embedded CUE sheet in %s: %v", audiofilePath, err)
+			} else if len(sheet.Files) == 1 && len(sheet.Files[0].Tracks) > 1 {
+				cueFile, found = sheet.Files[0], true
+			}
+		}
+	}
+
+	if !found {
+		fileMetadata, err := ParseMetadata(ctx, fileTags)
+		if err != nil {
+			return nil, err
+		}
+		fileMetadata.FilePath = audiofilePath
+		return []types.FileMetadata{fileMetadata}, nil
+	}
+
+	return getCueTrackMetadata(ctx, audiofilePath, fileTags, sheet, cueFile)
+}
+
+func getCueTrackMetadata(ctx context.Context, audiofilePath string, fileTags types.FfprobeStandard, sheet types.CueSheet, cueFile types.CueFile) ([]types.FileMetadata, error) {
+	totalDuration, _ := strconv.ParseFloat(fileTags.Duration, 64)
+	totalSize, _ := strconv.ParseFloat(fileTags.Size, 64)
+
+	album := cmp.Or(getTagStringValue(fileTags.Tags, []string{"album"}), sheet.Title)
+	albumArtist := cmp.Or(getTagStringValue(fileTags.Tags, []string{"album_artist", "album-artist", "albumartist"}), sheet.Performer,
+		getTagStringValue(fileTags.Tags, []string{"artist"}))
+	albumArtistId := getTagStringValue(fileTags.Tags, []string{"MUSICBRAINZ_ALBUMARTISTID", "MusicBrainz Album Artist Id"})
+	discNumber := strings.Split(cmp.Or(getTagStringValue(fileTags.Tags, []string{"disc"}), "1"), "/")[0]
+
+	// CUE sheet tracks never have their own MusicBrainz IDs, so they always get synthetic IDs,
+	// using the same inputs as files without MusicBrainz tags
+	albumId := getTagStringValue(fileTags.Tags, []string{"MUSICBRAINZ_ALBUMID", "MusicBrainz Album Id", "musicbrainz Album Id"})
+	if albumId == "" {
+		albumId = logic.GenerateSyntheticId("album", albumArtist, album)
+	}
+
+	results := make([]types.FileMetadata, 0, len(cueFile.Tracks))
+	for _, track := range cueFile.Tracks {
+		end := track.End
+		if end == 0 {
+			end = totalDuration
+		}
+		duration := max(end-track.Start, 0)
+
+		trackTags := map[string]string{}
+		for key, value := range fileTags.Tags {
+			if cueAlbumTagKeys[strings.ToLower(key)] {
+				trackTags[key] = value
+			}
+		}
+
+		artist := cmp.Or(track.Performer, albumArtist)
+		artistId := logic.GenerateSyntheticId("artist", artist)
+		if albumArtistId != "" && strings.EqualFold(artist, albumArtist) {
+			artistId = albumArtistId
+		}
+
+		trackTags["album"] = album
+		trackTags["album_artist"] = albumArtist
+		trackTags["title"] = track.Title
+		trackTags["artist"] = artist
+		trackTags["track"] = fmt.Sprintf("%d/%d", track.Number, len(cueFile.Tracks))
+		trackTags["MUSICBRAINZ_ALBUMID"] = albumId
+		trackTags["MUSICBRAINZ_ARTISTID"] = artistId
+		trackTags["MUSICBRAINZ_TRACKID"] = logic.GenerateSyntheticId("track", albumId, discNumber, strconv.Itoa(track.Number), track.Title)
+		if composer := cmp.Or(track.Songwriter, sheet.Songwriter); composer != "" {
+			trackTags["composer"] = composer
+		}
+		if getTagStringValue(trackTags, []string{"date"}) == "" && sheet.Date != "" {
+			trackTags["date"] = sheet.Date
+		}
+		if getTagStringValue(trackTags, []string{"genre"}) == "" && sheet.Genre != "" {
+			trackTags["genre"] = sheet.Genre
+		}
+
+		trackFileTags := fileTags
+		trackFileTags.Filename = cue.GetTrackFilePath(audiofilePath, track.Number)
+		trackFileTags.Tags = trackTags
+		trackFileTags.Duration = strconv.FormatFloat(duration, 'f', 6, 64)
+		if totalDuration > 0 && totalSize > 0 {
+			trackFileTags.Size = strconv.FormatInt(int64(totalSize*duration/totalDuration), 10)
+		}
+
+		trackMetadata, err := ParseMetadata(ctx, trackFileTags)
+		if err != nil {
+			return nil, fmt.Errorf("track %d of CUE sheet for %s: %w", track.Number, audiofilePath, err)
+		}
+		trackMetadata.FilePath = trackFileTags.Filename
+		trackMetadata.SourceFilePath = audiofilePath
+		trackMetadata.CueStart = track.Start
+		trackMetadata.CueEnd = track.End
+		results = append(results, trackMetadata)
+	}
+
+	return results, nil
+}
@@ -1,8 +1,10 @@
 package ffprobe
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os/exec"
@@ -13,10 +15,20 @@ import (
 	"zene/core/config"
 	"zene/core/io"
 	"zene/core/logger"
+	"zene/core/logic"
 	"zene/core/musicbrainz"
+	"zene/core/tags"
 	"zene/core/types"
 )
 
+var (
+	ErrFfprobe                    = errors.New("ffprobe error")
+	ErrMissingMusicBrainzArtistId = errors.New("missing MusicBrainz artist id")
+	ErrMissingMusicBrainzAlbumId  = errors.New("missing MusicBrainz album id")
+	ErrMissingMusicBrainzTrackId  = errors.New("missing MusicBrainz track id")
+	ErrMusicBrainzLookup          = errors.New("musicbrainz lookup error")
+)
+
 func InitializeFfprobe(ctx context.Context) {
 	logger.Printf("FFPROBE_PATH: %s", config.FfprobePath)
 
@@ -43,7 +55,7 @@ func GetFfprobeVersion(ctx context.Context) (string, error) {
 }
 
 func GetMetadata(ctx context.Context, audiofilePath string) (types.FileMetadata, error) {
-	fileTags, err := GetMetadataFromFile(ctx, audiofilePath)
+	fileTags, err := getFileTags(ctx, audiofilePath)
 	if err != nil {
 		return types.FileMetadata{}, err
 	}
@@ -54,6 +66,39 @@ func GetMetadata(ctx context.Context, audiofilePath string) (types.FileMetadata,
 	return parsedTags, nil
 }
 
+// getFileTags reads tags with the native tag reader, and only runs ffprobe for formats it cannot parse
+// or to fill in stream properties it cannot determine
+func getFileTags(ctx context.Context, audiofilePath string) (types.FfprobeStandard, error) {
+	if !config.NativeTagReader {
+		return GetMetadataFromFile(ctx, audiofilePath)
+	}
+
+	fileTags, err := tags.Read(audiofilePath)
+	if err != nil {
+		if !errors.Is(err, tags.ErrUnsupportedFormat) {
+			logger.Printf("Native tag reader failed for %s, falling back to ffprobe: %v", audiofilePath, err)
+		}
+		return GetMetadataFromFile(ctx, audiofilePath)
+	}
+
+	if tags.HasStreamProperties(fileTags) {
+		return fileTags, nil
+	}
+
+	ffprobeTags, err := GetMetadataFromFile(ctx, audiofilePath)
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+	fileTags.FormatName = cmp.Or(fileTags.FormatName, ffprobeTags.FormatName)
+	fileTags.Duration = cmp.Or(fileTags.Duration, ffprobeTags.Duration)
+	fileTags.Bitrate = cmp.Or(fileTags.Bitrate, ffprobeTags.Bitrate)
+	fileTags.Codec = cmp.Or(fileTags.Codec, ffprobeTags.Codec)
+	fileTags.BitDepth = cmp.Or(fileTags.BitDepth, ffprobeTags.BitDepth)
+	fileTags.SampleRate = cmp.Or(fileTags.SampleRate, ffprobeTags.SampleRate)
+	fileTags.Channels = cmp.Or(fileTags.Channels, ffprobeTags.Channels)
+	return fileTags, nil
+}
+
 func GetDurationAndBitrate(ctx context.Context, audiofilePath string) (string, string, error) {
 	cmd := exec.CommandContext(ctx, config.FfprobePath,
 		"-v", "quiet",
@@ -90,7 +135,7 @@ func GetMetadataFromFile(ctx context.Context, audiofilePath string) (types.Ffpro
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		logger.Printf("Error running ffprobe: %s", output)
-		return types.FfprobeStandard{}, err
+		return types.FfprobeStandard{}, fmt.Errorf("%w: %v", ErrFfprobe, err)
 	}
 
 	if filepath.Ext(audiofilePath) == ".opus" {
@@ -99,7 +144,10 @@ func GetMetadataFromFile(ctx context.Context, audiofilePath string) (types.Ffpro
 		err = json.Unmarshal(output, &ffprobeOpusOutput)
 		if err != nil {
 			logger.Printf("Error parsing ffprobe output: %v", err)
-			return types.FfprobeStandard{}, err
+			return types.FfprobeStandard{}, fmt.Errorf("%w: parsing output: %v", ErrFfprobe, err)
+		}
+		if len(ffprobeOpusOutput.Streams) == 0 {
+			return types.FfprobeStandard{}, fmt.Errorf("%w: no streams found", ErrFfprobe)
 		}
 
 		sampleRateInt, err := strconv.Atoi(ffprobeOpusOutput.Streams[0].SampleRate)
@@ -108,7 +156,7 @@ func GetMetadataFromFile(ctx context.Context, audiofilePath string) (types.Ffpro
 		}
 
 		return types.FfprobeStandard{
-			Filename:   ffprobeOpusOutput.Format.Filename,
+			Filename:   audiofilePath,
 			FormatName: ffprobeOpusOutput.Format.FormatName,
 			Tags:       ffprobeOpusOutput.Streams[0].Tags,
 			Duration:   ffprobeOpusOutput.Format.Duration,
@@ -125,7 +173,10 @@ func GetMetadataFromFile(ctx context.Context, audiofilePath string) (types.Ffpro
 		err = json.Unmarshal(output, &ffprobeStandardOutput)
 		if err != nil {
 			logger.Printf("Error parsing ffprobe output: %v", err)
-			return types.FfprobeStandard{}, err
+			return types.FfprobeStandard{}, fmt.Errorf("%w: parsing output: %v", ErrFfprobe, err)
+		}
+		if len(ffprobeStandardOutput.Streams) == 0 {
+			return types.FfprobeStandard{}, fmt.Errorf("%w: no streams found", ErrFfprobe)
 		}
 
 		sampleRateInt, err := strconv.Atoi(ffprobeStandardOutput.Streams[0].SampleRate)
@@ -134,7 +185,7 @@ func GetMetadataFromFile(ctx context.Context, audiofilePath string) (types.Ffpro
 		}
 
 		return types.FfprobeStandard{
-			Filename:   ffprobeStandardOutput.Format.Filename,
+			Filename:   audiofilePath,
 			FormatName: ffprobeStandardOutput.Format.FormatName,
 			Tags:       ffprobeStandardOutput.Format.Tags,
 			Duration:   ffprobeStandardOutput.Format.Duration,
@@ -174,7 +225,15 @@ func ParseMetadata(ctx context.Context, ffprobeOutput types.FfprobeStandard) (ty
 	parsedGenre := getTagStringValue(ffprobeOutput.Tags, []string{"genre"})
 	parsedReleaseDate := getTagStringValue(ffprobeOutput.Tags, []string{"ORIGINAL_DATE", "ORIGINALDATE", "date", "release_date"})
 	musicBrainzAlbumId := getTagStringValue(ffprobeOutput.Tags, []string{"MUSICBRAINZ_ALBUMID", "MusicBrainz Album Id", "musicbrainz Album Id"})
-	musicBrainzArtistId := getTagStringValue(ffprobeOutput.Tags, []string{"MUSICBRAINZ_ARTISTID", "MusicBrainz Artist Id", "musicbrainz Artist Id"})
+	// multi-valued artist ids are split into credits below, the first one is the track's primary artist
+	musicBrainzArtistId := ""
+	if artistIds := splitTagValues(getTagStringValue(ffprobeOutput.Tags, []string{"MUSICBRAINZ_ARTISTID", "MusicBrainz Artist Id", "musicbrainz Artist Id"}), ";/"); len(artistIds) > 0 {
+		musicBrainzArtistId = artistIds[0]
+	}
+	musicBrainzAlbumArtistId := ""
+	if albumArtistIds := splitTagValues(getTagStringValue(ffprobeOutput.Tags, []string{"MUSICBRAINZ_ALBUMARTISTID", "MusicBrainz Album Artist Id"}), ";/"); len(albumArtistIds) > 0 {
+		musicBrainzAlbumArtistId = albumArtistIds[0]
+	}
 	musicBrainzTrackId := getTagStringValue(ffprobeOutput.Tags, []string{"MUSICBRAINZ_TRACKID", "MusicBrainz Release Track Id", "musicbrainz Release Track Id"})
 	totalTracks := getTagStringValue(ffprobeOutput.Tags, []string{"TOTALTRACKS"})
 	trackNumber := getTagStringValue(ffprobeOutput.Tags, []string{"track"})
@@ -183,10 +242,6 @@ func ParseMetadata(ctx context.Context, ffprobeOutput types.FfprobeStandard) (ty
 	discNumber := getTagStringValue(ffprobeOutput.Tags, []string{"disc"})
 	label := getTagStringValue(ffprobeOutput.Tags, []string{"label", "publisher"})
 
-	if musicBrainzArtistId == "" || musicBrainzAlbumId == "" || musicBrainzTrackId == "" {
-		return types.FileMetadata{}, fmt.Errorf("Unable to parse musicbrainz metadata from file")
-	}
-
 	if strings.Contains(trackNumber, "/") {
 		splitValue := strings.Split(trackNumber, "/")
 		trackNumber = splitValue[0]
@@ -207,17 +262,49 @@ func ParseMetadata(ctx context.Context, ffprobeOutput types.FfprobeStandard) (ty
 		discNumber = "1"
 	}
 
+	if musicBrainzArtistId == "" || musicBrainzAlbumId == "" || musicBrainzTrackId == "" {
+		if !config.SyntheticIds {
+			switch {
+			case musicBrainzTrackId == "":
+				return types.FileMetadata{}, ErrMissingMusicBrainzTrackId
+			case musicBrainzAlbumId == "":
+				return types.FileMetadata{}, ErrMissingMusicBrainzAlbumId
+			default:
+				return types.FileMetadata{}, ErrMissingMusicBrainzArtistId
+			}
+		}
+		syntheticTags := getSyntheticTags(ffprobeOutput.Filename, syntheticTags{
+			Artist:              parsedArtist,
+			AlbumArtist:         parsedAlbumArtist,
+			Album:               parsedAlbum,
+			Title:               parsedTitle,
+			TrackNumber:         trackNumber,
+			DiscNumber:          discNumber,
+			MusicBrainzArtistID: musicBrainzArtistId,
+			MusicBrainzAlbumID:  musicBrainzAlbumId,
+			MusicBrainzTrackID:  musicBrainzTrackId,
+		})
+		parsedArtist = syntheticTags.Artist
+		parsedAlbumArtist = syntheticTags.AlbumArtist
+		parsedAlbum = syntheticTags.Album
+		parsedTitle = syntheticTags.Title
+		trackNumber = syntheticTags.TrackNumber
+		musicBrainzArtistId = syntheticTags.MusicBrainzArtistID
+		musicBrainzAlbumId = syntheticTags.MusicBrainzAlbumID
+		musicBrainzTrackId = syntheticTags.MusicBrainzTrackID
+	}
+
 	// if parsedReleaseDate == "" && originalYear != "" {
 	// 	parsedReleaseDate = fmt.Sprintf("%s-01-01", originalYear)
 	// }
 
 	var musicBrainzData types.MbRelease
 
-	if parsedReleaseDate == "" || trackNumber == "" {
+	if (parsedReleaseDate == "" || trackNumber == "") && !logic.IsSyntheticId(musicBrainzAlbumId) {
 		musicBrainzData, err := musicbrainz.GetMetadataForMusicBrainzAlbumId(musicBrainzAlbumId)
 		if err != nil {
 			logger.Printf("Error fetching parsedReleaseDate from musicbrainz: %v", err)
-			return types.FileMetadata{}, err
+			return types.FileMetadata{}, fmt.Errorf("%w: %v", ErrMusicBrainzLookup, err)
 		}
 		parsedReleaseDate = musicBrainzData.Date
 	}
@@ -265,6 +352,11 @@ func ParseMetadata(ctx context.Context, ffprobeOutput types.FfprobeStandard) (ty
 		totalDiscsInt = 0
 	}
 
+	if musicBrainzAlbumArtistId == "" && parsedAlbumArtist == parsedArtist {
+		musicBrainzAlbumArtistId = musicBrainzArtistId
+	}
+	credits := getArtistCredits(ffprobeOutput.Tags, parsedArtist, musicBrainzArtistId, parsedAlbumArtist, musicBrainzAlbumArtistId)
+
 	parsedMetadata := types.FileMetadata{
 		Format:              ffprobeOutput.FormatName,
 		Duration:            ffprobeOutput.Duration,
@@ -288,6 +380,9 @@ func ParseMetadata(ctx context.Context, ffprobeOutput types.FfprobeStandard) (ty
 		BitDepth:            ffprobeOutput.BitDepth,
 		SampleRate:          ffprobeOutput.SampleRate,
 		Channels:            ffprobeOutput.Channels,
+		Credits:             credits,
+		ReplayGain:          getReplayGain(ffprobeOutput.Tags),
+		Lyrics:              getEmbeddedLyrics(ffprobeOutput.Tags),
 	}
 
 	return parsedMetadata, nil
@@ -0,0 +1,43 @@
+package ffprobe
+
+import (
+	"slices"
+	"strings"
+	"zene/core/lyrics"
+	"zene/core/types"
+)
+
+// getEmbeddedLyrics reads lyrics from LYRICS, UNSYNCEDLYRICS and USLT tags, which ffprobe keys as "lyrics-<language>",
+// and from SYLT frames, which the native tag reader converts to LRC
+func getEmbeddedLyrics(tags map[string]string) []types.LocalLyrics {
+	results := []types.LocalLyrics{}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		lowerKey := strings.ToLower(key)
+		var lang string
+		switch {
+		case lowerKey == "sylt", lowerKey == "lyrics", lowerKey == "unsyncedlyrics", lowerKey == "syncedlyrics":
+		case strings.HasPrefix(lowerKey, "lyrics-"):
+			lang = strings.TrimPrefix(lowerKey, "lyrics-")
+		default:
+			continue
+		}
+
+		localLyrics, ok := lyrics.NewLocalLyrics(types.LyricsSourceEmbedded, lang, tags[key])
+		if !ok {
+			continue
+		}
+		if localLyrics.SyncedLyrics != "" {
+			localLyrics.Source = types.LyricsSourceEmbeddedSynced
+			localLyrics.Priority = types.LyricsSourcePriorities[types.LyricsSourceEmbeddedSynced]
+		}
+		results = append(results, localLyrics)
+	}
+	return results
+}
@@ -0,0 +1,54 @@
+package ffprobe
+
+import (
+	"strconv"
+	"strings"
+	"zene/core/types"
+)
+
+// R128 gains are relative to -23 LUFS, ReplayGain 2.0 gains are relative to -18 LUFS
+const r128ToReplayGainOffset = 5.0
+
+// getReplayGain reads ReplayGain values from REPLAYGAIN_* tags, falling back to the R128_* tags written to Opus files
+func getReplayGain(tags map[string]string) types.ReplayGain {
+	replayGain := types.ReplayGain{
+		TrackGain: parseGainTag(getTagStringValue(tags, []string{"REPLAYGAIN_TRACK_GAIN"})),
+		TrackPeak: parseGainTag(getTagStringValue(tags, []string{"REPLAYGAIN_TRACK_PEAK"})),
+		AlbumGain: parseGainTag(getTagStringValue(tags, []string{"REPLAYGAIN_ALBUM_GAIN"})),
+		AlbumPeak: parseGainTag(getTagStringValue(tags, []string{"REPLAYGAIN_ALBUM_PEAK"})),
+	}
+	if replayGain.TrackGain == nil {
+		replayGain.TrackGain = parseR128GainTag(getTagStringValue(tags, []string{"R128_TRACK_GAIN"}))
+	}
+	if replayGain.AlbumGain == nil {
+		replayGain.AlbumGain = parseR128GainTag(getTagStringValue(tags, []string{"R128_ALBUM_GAIN"}))
+	}
+
+	if replayGain.TrackGain != nil || replayGain.AlbumGain != nil {
+		replayGain.Source = types.ReplayGainSourceTags
+	}
+	return replayGain
+}
+
+// parseGainTag parses values like "-6.54 dB" or "0.988547"
+func parseGainTag(value string) *float64 {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), "db"))
+	if value == "" {
+		return nil
+	}
+	gain, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+	return &gain
+}
+
+// parseR128GainTag parses a Q7.8 fixed point gain and converts it to the ReplayGain reference level
+func parseR128GainTag(value string) *float64 {
+	fixedPoint, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return nil
+	}
+	gain := float64(fixedPoint)/256 + r128ToReplayGainOffset
+	return &gain
+}
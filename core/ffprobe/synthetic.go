@@ -0,0 +1,70 @@
+package ffprobe
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"zene/core/logic"
+)
+
+type syntheticTags struct {
+	Artist              string
+	AlbumArtist         string
+	Album               string
+	Title               string
+	TrackNumber         string
+	DiscNumber          string
+	MusicBrainzArtistID string
+	MusicBrainzAlbumID  string
+	MusicBrainzTrackID  string
+}
+
+// matches file names like "01 - Title", "01. Title" or "1_Title"
+var trackNumberFileNameRegex = regexp.MustCompile(`^(\d{1,3})[\s._-]+(.+)$`)
+
+// getSyntheticTags fills in any missing names from the folder layout (Artist/Album/NN - Title.ext),
+// then replaces any missing MusicBrainz IDs with deterministic synthetic IDs built from the normalised tags.
+// Real MusicBrainz IDs are always kept when they are present.
+func getSyntheticTags(audiofilePath string, tags syntheticTags) syntheticTags {
+	albumDirectory := filepath.Dir(audiofilePath)
+	artistDirectory := filepath.Dir(albumDirectory)
+	fileName := strings.TrimSuffix(filepath.Base(audiofilePath), filepath.Ext(audiofilePath))
+
+	fileNameTitle := fileName
+	fileNameTrackNumber := ""
+	if matches := trackNumberFileNameRegex.FindStringSubmatch(fileName); len(matches) == 3 {
+		fileNameTrackNumber = strings.TrimLeft(matches[1], "0")
+		fileNameTitle = strings.TrimSpace(matches[2])
+	}
+
+	if tags.Artist == "" {
+		tags.Artist = tags.AlbumArtist
+	}
+	if tags.Artist == "" {
+		tags.Artist = filepath.Base(artistDirectory)
+	}
+	if tags.AlbumArtist == "" {
+		tags.AlbumArtist = tags.Artist
+	}
+	if tags.Album == "" {
+		tags.Album = filepath.Base(albumDirectory)
+	}
+	if tags.Title == "" {
+		tags.Title = fileNameTitle
+	}
+	if tags.TrackNumber == "" {
+		tags.TrackNumber = fileNameTrackNumber
+	}
+
+	if tags.MusicBrainzArtistID == "" {
+		tags.MusicBrainzArtistID = logic.GenerateSyntheticId("artist", tags.Artist)
+	}
+	if tags.MusicBrainzAlbumID == "" {
+		tags.MusicBrainzAlbumID = logic.GenerateSyntheticId("album", tags.AlbumArtist, tags.Album)
+	}
+	if tags.MusicBrainzTrackID == "" {
+		tags.MusicBrainzTrackID = logic.GenerateSyntheticId("track", tags.MusicBrainzAlbumID, tags.DiscNumber, tags.TrackNumber, tags.Title)
+	}
+
+	return tags
+}
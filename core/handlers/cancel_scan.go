@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/scanner"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleCancelScan(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to cancel scans", "")
+		return
+	}
+
+	scanId, err := scanner.CancelScan()
+	if err != nil {
+		if errors.Is(err, scanner.ErrNoScanInProgress) {
+			net.WriteSubsonicError(w, r, types.ErrorGeneric, "No scan is in progress", "")
+			return
+		}
+		logger.Printf("Error cancelling scan: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to cancel scan", "")
+		return
+	}
+
+	logger.Printf("Scan %d cancelled by %s", scanId, requestUser.Username)
+
+	// the scan stops at its next checkpoint, so the status may still show it as scanning
+	scanStatus, err := scanner.GetScanStatus(ctx)
+	if err != nil {
+		logger.Printf("Error getting scan status: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to get scan status", "")
+		return
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.ScanStatus = &scanStatus
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
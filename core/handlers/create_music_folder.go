@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/scanner"
+	"zene/core/subsonic"
+	"zene/core/types"
+	"zene/core/watcher"
+)
+
+func HandleCreateMusicFolder(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	path := form["path"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage music folders", "")
+		return
+	}
+
+	if path == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "path parameter is mandatory", "")
+		return
+	}
+
+	musicFolder := types.MusicFolderSettings{
+		Path:         path,
+		Enabled:      true,
+		ScanInterval: database.DefaultScanInterval,
+	}
+	musicFolder, err = parseMusicFolderSettingsForm(form, musicFolder)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, err.Error(), "")
+		return
+	}
+
+	musicFolder, err = scanner.CreateMusicFolder(ctx, musicFolder)
+	if err != nil {
+		logger.Printf("Error creating music folder %s: %v", path, err)
+		if errors.Is(err, scanner.ErrInvalidMusicFolder) {
+			net.WriteSubsonicError(w, r, types.ErrorGeneric, err.Error(), "")
+			return
+		}
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to create music folder", "")
+		return
+	}
+
+	logger.Printf("Music folder %s created with ID %d by %s", musicFolder.Path, musicFolder.Id, requestUser.Username)
+	watcher.Refresh()
+
+	if musicFolder.Enabled {
+		_, err := scanner.RunScan(context.Background(), types.ScanOptions{
+			IncludeArt:    true,
+			MusicFolderId: musicFolder.Id,
+		})
+		if err != nil {
+			// the scheduler scans the folder once the running scan has finished, as it has never been scanned
+			logger.Printf("Not scanning new music folder %s now: %v", musicFolder.Path, err)
+		}
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.MusicFolderSettings = &types.MusicFolderSettingsList{
+		MusicFolder: []types.MusicFolderSettings{musicFolder},
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
+
+// parseMusicFolderSettingsForm applies the optional name, enabled, scanInterval and fileTypes parameters to a music folder
+func parseMusicFolderSettingsForm(form map[string]string, musicFolder types.MusicFolderSettings) (types.MusicFolderSettings, error) {
+	if name, ok := form["name"]; ok {
+		musicFolder.Name = name
+	}
+
+	if enabled := form["enabled"]; enabled != "" {
+		parsedEnabled, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return musicFolder, fmt.Errorf("enabled must be true or false")
+		}
+		musicFolder.Enabled = parsedEnabled
+	}
+
+	if scanInterval := form["scaninterval"]; scanInterval != "" {
+		parsedScanInterval, err := strconv.Atoi(scanInterval)
+		if err != nil || parsedScanInterval < 0 {
+			return musicFolder, fmt.Errorf("scanInterval must be a number of minutes, or 0 to disable scheduled scans")
+		}
+		musicFolder.ScanInterval = parsedScanInterval
+	}
+
+	if fileTypes, ok := form["filetypes"]; ok {
+		musicFolder.FileTypes = []string{}
+		if fileTypes != "" {
+			musicFolder.FileTypes = strings.Split(fileTypes, ",")
+		}
+	}
+
+	return musicFolder, nil
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/scanner"
+	"zene/core/subsonic"
+	"zene/core/types"
+	"zene/core/watcher"
+)
+
+func HandleDeleteMusicFolder(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	id := form["id"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage music folders", "")
+		return
+	}
+
+	if id == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id parameter is mandatory", "")
+		return
+	}
+
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id must be a valid music folder id", "")
+		return
+	}
+
+	musicFolder, err := database.GetMusicFolderSettingsById(ctx, idInt)
+	if err != nil {
+		logger.Printf("Error getting music folder %d: %v", idInt, err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Music folder not found", "")
+		return
+	}
+
+	err = scanner.DeleteMusicFolder(ctx, idInt)
+	if err != nil {
+		logger.Printf("Error deleting music folder %s: %v", musicFolder.Path, err)
+		if errors.Is(err, scanner.ErrScanInProgress) {
+			net.WriteSubsonicError(w, r, types.ErrorGeneric, "A scan is in progress. Please wait for it to complete before deleting a music folder.", "")
+			return
+		}
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to delete music folder", "")
+		return
+	}
+
+	logger.Printf("Music folder %s deleted by %s", musicFolder.Path, requestUser.Username)
+	watcher.Refresh()
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleDeletePlayer(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	id := form["id"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage players", "")
+		return
+	}
+
+	if id == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id parameter is mandatory", "")
+		return
+	}
+
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id must be a valid player id", "")
+		return
+	}
+
+	if err := database.DeletePlayer(ctx, idInt); err != nil {
+		logger.Printf("Error deleting player %d: %v", idInt, err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Player not found", "")
+		return
+	}
+
+	logger.Printf("Player %d deleted by %s", idInt, requestUser.Username)
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
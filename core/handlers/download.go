@@ -1,14 +1,33 @@
 package handlers
 
 import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"zene/core/config"
 	"zene/core/database"
-	"zene/core/io"
+	"zene/core/ffmpeg"
 	"zene/core/logger"
 	"zene/core/net"
 	"zene/core/types"
 )
 
+// downloadEntry is a track of a ZIP archive download, with the path of its file in the archive without an extension
+type downloadEntry struct {
+	trackId string
+	name    string
+}
+
 func HandleDownload(w http.ResponseWriter, r *http.Request) {
 	if net.MethodIsNotGetOrPost(w, r) {
 		return
@@ -16,16 +35,131 @@ func HandleDownload(w http.ResponseWriter, r *http.Request) {
 
 	form := net.NormalisedForm(r, w)
 	mediaId := form["id"]
+	versionId := form["versionid"]
+	downloadFormat := form["format"]
+	maxBitRateString := form["maxbitrate"]
+	client := form["c"]
 
 	ctx := r.Context()
 
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil {
+		logger.Printf("Error getting user by context: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "User not found", "")
+		return
+	}
+
+	if !requestUser.DownloadRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to download", "")
+		return
+	}
+
 	if mediaId == "" {
 		errorString := "invalid id parameter"
 		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, errorString, "")
 		return
 	}
 
-	mediaFilepath, err := database.GetMediaFilePath(ctx, mediaId)
+	// a download is the original file unless a format is requested, in which case it is transcoded like a stream
+	if downloadFormat == "raw" {
+		downloadFormat = ""
+	}
+	if downloadFormat != "" && !ffmpeg.IsSupportedFormat(downloadFormat) {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "format parameter must be raw or a supported transcode format", "")
+		return
+	}
+
+	options := types.TranscodeOptions{Format: downloadFormat}
+	if downloadFormat != "" {
+		options.MaxBitRate = config.DefaultBitRate
+		if maxBitRateString != "" {
+			options.MaxBitRate, err = strconv.Atoi(maxBitRateString)
+			if err != nil {
+				net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "maxBitRate parameter must be an integer", "")
+				return
+			}
+		}
+		if requestUser.MaxBitRate > 0 && requestUser.MaxBitRate < options.MaxBitRate {
+			options.MaxBitRate = requestUser.MaxBitRate
+		}
+
+		settings, err := getTranscodeSettings(ctx, form, requestUser, types.Player{})
+		if err != nil {
+			net.WriteSubsonicError(w, r, types.ErrorMissingParameter, err.Error(), "")
+			return
+		}
+		options.MaxSampleRate = settings.MaxSampleRate
+		options.Normalization = settings.Normalization
+		options.Channels = settings.Channels
+		options.MaxBitDepth = settings.MaxBitDepth
+	}
+
+	// albums, artists and playlists are downloaded as a ZIP archive of their tracks
+	_, metadataType, err := database.IsValidMetadataId(ctx, mediaId)
+	if err == nil && metadataType == database.MetadataAlbum {
+		tracks, err := database.SelectAlbumDownloadTracks(ctx, requestUser.Id, mediaId)
+		if err != nil {
+			logger.Printf("Error querying database for tracks of album %s: %v", mediaId, err)
+			net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error getting album tracks", "")
+			return
+		}
+		if len(tracks) == 0 {
+			net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Album not found", "")
+			return
+		}
+		archiveName := tracks[0].AlbumArtist + " - " + tracks[0].Album
+		writeDownloadZip(ctx, w, archiveName, getAlbumDownloadEntries(tracks, ""), client, options)
+		return
+	}
+
+	if err == nil && metadataType == database.MetadataArtist {
+		tracks, err := database.SelectArtistDownloadTracks(ctx, requestUser.Id, mediaId)
+		if err != nil {
+			logger.Printf("Error querying database for tracks of artist %s: %v", mediaId, err)
+			net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error getting artist tracks", "")
+			return
+		}
+		if len(tracks) == 0 {
+			net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Artist not found", "")
+			return
+		}
+		writeDownloadZip(ctx, w, tracks[0].Artist, getArtistDownloadEntries(tracks), client, options)
+		return
+	}
+
+	if playlistId, err := strconv.Atoi(mediaId); err == nil {
+		playlist, err := database.GetPlaylist(ctx, playlistId)
+		if err != nil {
+			net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to download this playlist", "")
+			return
+		}
+		if playlist.Id > 0 {
+			tracks, err := database.SelectPlaylistDownloadTracks(ctx, requestUser.Id, playlistId)
+			if err != nil {
+				logger.Printf("Error querying database for tracks of playlist %d: %v", playlistId, err)
+				net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error getting playlist tracks", "")
+				return
+			}
+			if len(tracks) == 0 {
+				net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Playlist has no tracks to download", "")
+				return
+			}
+			writeDownloadZip(ctx, w, playlist.Name, getPlaylistDownloadEntries(tracks), client, options)
+			return
+		}
+	}
+
+	// the best quality version is chosen unless a version is requested
+	mediaFile, err := database.GetMediaFile(ctx, mediaId, types.VersionPreference{
+		VersionId:  versionId,
+		Format:     cmp.Or(downloadFormat, "raw"),
+		MaxBitRate: options.MaxBitRate,
+	})
+
+	if errors.Is(err, database.ErrVersionNotFound) {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Version not found.", "")
+		return
+	}
 
 	if err != nil {
 		logger.Printf("Error querying database for media filepath %s: %v", mediaId, err)
@@ -33,23 +167,200 @@ func HandleDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if mediaFilepath == "" {
+	if mediaFile.FilePath == "" {
 		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "File not available to stream.", "")
 		return
 	}
 
-	fileBlob, err := io.GetFileBlob(ctx, mediaFilepath)
-	if err != nil {
-		net.WriteSubsonicError(w, r, types.ErrorGeneric, "file not found", "")
+	fileName := strings.TrimSuffix(filepath.Base(mediaFile.FilePath), filepath.Ext(mediaFile.FilePath))
+	if mediaFile.IsCueTrack {
+		// a CUE sheet track shares its file with the rest of the album, so it is named after its title
+		if song, err := database.GetSong(ctx, mediaId); err == nil && song.Title != "" {
+			fileName = song.Title
+		}
+		// and is downloaded as a lossless copy of its time slice unless another format is requested
+		if options.Format == "" {
+			options.Format = "flac"
+		}
+	}
+
+	if options.Format == "" {
+		setContentDisposition(w, sanitiseFileName(fileName)+filepath.Ext(mediaFile.FilePath))
+		serveRawFile(w, r, mediaFile.FilePath)
+		return
+	}
+
+	setContentDisposition(w, sanitiseFileName(fileName)+ffmpeg.GetFileExtension(options.Format))
+	err = ffmpeg.TranscodeAndStream(ctx, w, r, mediaFile, mediaId, options)
+	if errors.Is(err, ffmpeg.ErrTranscodeQueueTimeout) {
+		w.Header().Del("Content-Disposition")
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Too many transcodes are running, try again later", "")
 		return
 	}
 
-	mimeType := http.DetectContentType(fileBlob)
-	w.Header().Set("Content-Type", mimeType)
-	w.WriteHeader(http.StatusOK)
-	_, err = w.Write(fileBlob)
 	if err != nil {
-		net.WriteSubsonicError(w, r, types.ErrorGeneric, "failed to write file", "")
+		w.Header().Del("Content-Disposition")
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error downloading audio", "")
 		return
 	}
 }
+
+// writeDownloadZip streams a ZIP archive of tracks as it is written, one file at a time, without buffering the files.
+// Files are stored rather than compressed, as audio doesn't compress. Tracks whose file can't be found are left out,
+// and an error part way through a file ends the archive early, as the response can no longer be changed to an error.
+func writeDownloadZip(ctx context.Context, w http.ResponseWriter, archiveName string, entries []downloadEntry, client string, options types.TranscodeOptions) {
+	w.Header().Set("Content-Type", "application/zip")
+	setContentDisposition(w, sanitiseFileName(archiveName)+".zip")
+
+	zipWriter := zip.NewWriter(w)
+	names := map[string]bool{}
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+
+		mediaFile, err := database.GetMediaFile(ctx, entry.trackId, types.VersionPreference{
+			Format:     cmp.Or(options.Format, "raw"),
+			MaxBitRate: options.MaxBitRate,
+		})
+		if err != nil || mediaFile.FilePath == "" {
+			logger.Printf("Download of %s: skipping track %s without a file: %v", archiveName, entry.trackId, err)
+			continue
+		}
+
+		fileInfo, err := os.Stat(mediaFile.FilePath)
+		if err != nil {
+			logger.Printf("Download of %s: skipping track %s: %v", archiveName, entry.trackId, err)
+			continue
+		}
+
+		entryOptions := options
+		if entryOptions.Format == "" && mediaFile.IsCueTrack {
+			entryOptions.Format = "flac"
+		}
+		extension := strings.ToLower(filepath.Ext(mediaFile.FilePath))
+		if entryOptions.Format != "" {
+			extension = ffmpeg.GetFileExtension(entryOptions.Format)
+		}
+
+		entryWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     getUniqueEntryName(names, entry.name, extension),
+			Method:   zip.Store,
+			Modified: fileInfo.ModTime(),
+		})
+		if err != nil {
+			logger.Printf("Download of %s: error adding track %s to archive: %v", archiveName, entry.trackId, err)
+			return
+		}
+
+		if entryOptions.Format == "" {
+			err = copyFile(entryWriter, mediaFile.FilePath)
+		} else {
+			err = ffmpeg.TranscodeToWriter(ctx, entryWriter, client, mediaFile, entry.trackId, entryOptions)
+		}
+		if err != nil {
+			logger.Printf("Download of %s: error writing track %s, ending the archive: %v", archiveName, entry.trackId, err)
+			return
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		logger.Printf("Download of %s: error finishing archive: %v", archiveName, err)
+	}
+}
+
+func copyFile(w io.Writer, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// getAlbumDownloadEntries names the tracks of an album by track number and title in a folder,
+// with the disc number first when the album has more than one disc
+func getAlbumDownloadEntries(tracks []types.DownloadTrack, folder string) []downloadEntry {
+	multiDisc := false
+	for _, track := range tracks {
+		if track.DiscNumber > 1 {
+			multiDisc = true
+			break
+		}
+	}
+
+	entries := []downloadEntry{}
+	for _, track := range tracks {
+		name := fmt.Sprintf("%02d - %s", track.TrackNumber, sanitiseFileName(track.Title))
+		if multiDisc {
+			name = fmt.Sprintf("%d-%s", max(track.DiscNumber, 1), name)
+		}
+		entries = append(entries, downloadEntry{trackId: track.TrackId, name: path.Join(folder, name)})
+	}
+	return entries
+}
+
+// getArtistDownloadEntries puts the tracks of an artist in a folder for each album, named after the album.
+// Albums of the same name are told apart by their year, and by a number when they share that too.
+func getArtistDownloadEntries(tracks []types.DownloadTrack) []downloadEntry {
+	albumIds := []string{}
+	albumTracks := map[string][]types.DownloadTrack{}
+	albumNames := map[string]int{}
+	for _, track := range tracks {
+		if _, found := albumTracks[track.AlbumId]; !found {
+			albumIds = append(albumIds, track.AlbumId)
+			albumNames[strings.ToLower(sanitiseFileName(track.Album))]++
+		}
+		albumTracks[track.AlbumId] = append(albumTracks[track.AlbumId], track)
+	}
+
+	entries := []downloadEntry{}
+	folders := map[string]bool{}
+	for _, albumId := range albumIds {
+		album := albumTracks[albumId][0]
+		folder := sanitiseFileName(album.Album)
+		if albumNames[strings.ToLower(folder)] > 1 && album.Year != "" {
+			folder = fmt.Sprintf("%s (%s)", folder, sanitiseFileName(album.Year))
+		}
+		folder = getUniqueEntryName(folders, folder, "")
+		entries = append(entries, getAlbumDownloadEntries(albumTracks[albumId], folder)...)
+	}
+	return entries
+}
+
+// getPlaylistDownloadEntries names the tracks of a playlist by their position, artist and title, so that they sort in playlist order
+func getPlaylistDownloadEntries(tracks []types.DownloadTrack) []downloadEntry {
+	entries := []downloadEntry{}
+	for i, track := range tracks {
+		name := fmt.Sprintf("%03d - %s - %s", i+1, sanitiseFileName(track.Artist), sanitiseFileName(track.Title))
+		entries = append(entries, downloadEntry{trackId: track.TrackId, name: name})
+	}
+	return entries
+}
+
+// getUniqueEntryName adds a number to the name of a file or folder when the archive already has one of that name,
+// such as a track that is in a playlist more than once
+func getUniqueEntryName(names map[string]bool, name string, extension string) string {
+	uniqueName := name + extension
+	for i := 2; names[strings.ToLower(uniqueName)]; i++ {
+		uniqueName = fmt.Sprintf("%s (%d)%s", name, i, extension)
+	}
+	names[strings.ToLower(uniqueName)] = true
+	return uniqueName
+}
+
+// sanitiseFileName replaces the characters that file systems don't allow in file names, so that a tag can be used as one
+func sanitiseFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return cmp.Or(strings.Trim(name, " ."), "Unknown")
+}
+
+func setContentDisposition(w http.ResponseWriter, fileName string) {
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+}
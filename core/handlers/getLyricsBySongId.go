@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"cmp"
 	"fmt"
 	"net/http"
 	"strings"
@@ -75,7 +76,7 @@ func HandleGetLyricsBySongId(w http.ResponseWriter, r *http.Request) {
 			{
 				DisplayArtist: trackData.Artist,
 				DisplayTitle:  trackData.Title,
-				Lang:          "en",
+				Lang:          cmp.Or(lyricsData.Lang, "en"),
 				Offset:        0,
 				Synced:        lyricsData.SyncedLyrics != "",
 				Line:          lines,
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleGetMusicFolderSettings(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage music folders", "")
+		return
+	}
+
+	musicFolders, err := database.GetMusicFolderSettings(ctx)
+	if err != nil {
+		logger.Printf("Error getting music folder settings: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to get music folders", "")
+		return
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.MusicFolderSettings = &types.MusicFolderSettingsList{
+		MusicFolder: musicFolders,
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
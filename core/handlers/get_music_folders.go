@@ -2,7 +2,8 @@ package handlers
 
 import (
 	"net/http"
-	"zene/core/config"
+	"zene/core/database"
+	"zene/core/logger"
 	"zene/core/net"
 	"zene/core/subsonic"
 	"zene/core/types"
@@ -16,18 +17,24 @@ func HandleGetMusicFolders(w http.ResponseWriter, r *http.Request) {
 	form := net.NormalisedForm(r, w)
 	format := form["f"]
 
-	response := subsonic.GetPopulatedSubsonicResponse(r.Context())
+	ctx := r.Context()
 
-	var musicFolders []types.MusicFolder
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil {
+		logger.Printf("Error getting user by context: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to get music folders", "")
+		return
+	}
 
-	for i := range config.MusicDirs {
-		musicFolder := types.MusicFolder{
-			Id:   i + 1,
-			Name: config.MusicDirs[i],
-		}
-		musicFolders = append(musicFolders, musicFolder)
+	musicFolders, err := database.GetMusicFoldersForUser(ctx, requestUser.Id)
+	if err != nil {
+		logger.Printf("Error getting music folders for user %s: %v", requestUser.Username, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to get music folders", "")
+		return
 	}
 
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
 	response.SubsonicResponse.MusicFolders = &types.MusicFolders{
 		MusicFolder: musicFolders,
 	}
@@ -42,6 +42,11 @@ func HandleOpenSubsonicExtensions(w http.ResponseWriter, r *http.Request) {
 		Versions: []int{1},
 	}
 
+	extension7 := types.OpenSubsonicExtensions{
+		Name:     "transcoding",
+		Versions: []int{1},
+	}
+
 	response.SubsonicResponse.OpenSubsonicExtensions = []*types.OpenSubsonicExtensions{
 		&extension1,
 		&extension2,
@@ -49,6 +54,7 @@ func HandleOpenSubsonicExtensions(w http.ResponseWriter, r *http.Request) {
 		&extension4,
 		&extension5,
 		&extension6,
+		&extension7,
 	}
 
 	net.WriteSubsonicResponse(w, r, response, format)
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleGetPlayers(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage players", "")
+		return
+	}
+
+	players, err := database.GetPlayers(ctx)
+	if err != nil {
+		logger.Printf("Error getting players: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to get players", "")
+		return
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.Players = &types.Players{
+		Player: players,
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
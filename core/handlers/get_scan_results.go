@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleGetScanResults(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	scanIdParam := form["scanid"]
+	statusParam := form["status"]
+	reasonParam := form["reason"]
+	countParam := form["count"]
+	offsetParam := form["offset"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil {
+		logger.Printf("Error getting user by context: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "context user not found", "")
+		return
+	}
+
+	if !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "user not authorized to get scan results", "")
+		return
+	}
+
+	var scanId int
+	if scanIdParam != "" {
+		scanId, err = strconv.Atoi(scanIdParam)
+		if err != nil {
+			net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "scanId parameter must be an integer", "")
+			return
+		}
+	} else {
+		latestScan, err := database.GetLatestScan(ctx)
+		if err != nil {
+			logger.Printf("Error getting latest scan: %v", err)
+			net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "No scans found", "")
+			return
+		}
+		scanId = latestScan.Id
+	}
+
+	count := 100
+	if countParam != "" {
+		count, err = strconv.Atoi(countParam)
+		if err != nil || count < 1 {
+			net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "count parameter must be a positive integer", "")
+			return
+		}
+		count = min(count, 500)
+	}
+
+	offset := 0
+	if offsetParam != "" {
+		offset, err = strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "offset parameter must be a non-negative integer", "")
+			return
+		}
+	}
+
+	scanResults, totalCount, err := database.SelectScanResults(ctx, scanId, statusParam, reasonParam, count, offset)
+	if err != nil {
+		logger.Printf("Error getting scan results: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to get scan results", "")
+		return
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.ScanResults = &types.ScanResults{
+		ScanId:     scanId,
+		TotalCount: totalCount,
+		ScanResult: scanResults,
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
@@ -2,9 +2,9 @@ package handlers
 
 import (
 	"net/http"
-	"zene/core/database"
 	"zene/core/logger"
 	"zene/core/net"
+	"zene/core/scanner"
 	"zene/core/subsonic"
 	"zene/core/types"
 )
@@ -17,25 +17,16 @@ func HandleGetScanStatus(w http.ResponseWriter, r *http.Request) {
 	form := net.NormalisedForm(r, w)
 	format := form["f"]
 
-	scanStatus, err := database.GetLatestScan(r.Context())
+	scanStatus, err := scanner.GetScanStatus(r.Context())
 	if err != nil {
 		logger.Printf("Error getting scan status: %v", err)
 		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to get scan status", "")
 		return
 	}
 
-	scanStatusResponse := types.ScanStatus{
-		Scanning:      scanStatus.CompletedDate == "",
-		Count:         scanStatus.Count,
-		FolderCount:   scanStatus.FolderCount,
-		StartedDate:   scanStatus.StartedDate,
-		Type:          scanStatus.Type,
-		CompletedDate: scanStatus.CompletedDate,
-	}
-
 	response := subsonic.GetPopulatedSubsonicResponse(r.Context())
 
-	response.SubsonicResponse.ScanStatus = &scanStatusResponse
+	response.SubsonicResponse.ScanStatus = &scanStatus
 
 	net.WriteSubsonicResponse(w, r, response, format)
 }
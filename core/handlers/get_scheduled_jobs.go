@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"zene/core/database"
+	"zene/core/net"
+	"zene/core/scheduler"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleGetScheduledJobs(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage scheduled jobs", "")
+		return
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.ScheduledJobs = &types.ScheduledJobs{
+		ScheduledJob: scheduler.GetJobs(),
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleGetTranscodeDecision(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	mediaId := form["mediaid"]
+	mediaType := form["mediatype"]
+	client := form["c"]
+
+	ctx := r.Context()
+
+	if mediaId == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "mediaId parameter is required", "")
+		return
+	}
+
+	var clientInfo types.ClientInfo
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&clientInfo); errors.Is(err, io.EOF) {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "client info must be posted as JSON", "")
+		return
+	} else if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "client info is malformed", "")
+		return
+	}
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil {
+		logger.Printf("Error getting user by context: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "User not found", "")
+		return
+	}
+
+	var versions []types.SongVersion
+	switch mediaType {
+	case "", "song":
+		versions, err = database.GetSongVersions(ctx, mediaId, requestUser.Id)
+	case "podcast":
+		var version types.SongVersion
+		version, err = database.GetPodcastEpisodeVersion(ctx, mediaId)
+		versions = []types.SongVersion{version}
+	default:
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "mediaType must be song or podcast", "")
+		return
+	}
+
+	if errors.Is(err, database.ErrVersionNotFound) || (err == nil && len(versions) == 0) {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "File not available to stream.", "")
+		return
+	}
+
+	if err != nil {
+		logger.Printf("Error querying database for media %s: %v", mediaId, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "File not found in database.", "")
+		return
+	}
+
+	player := getPlayer(ctx, requestUser, client)
+
+	// the best quality version is transcoded when no version can be played as it is
+	best, _ := database.SelectSongVersion(versions, types.VersionPreference{Format: "raw"})
+	decision := ffmpeg.MakeTranscodeDecision(best, clientInfo, player, requestUser.MaxBitRate)
+	if !decision.CanDirectPlay {
+		for _, version := range versions {
+			if version.Id == best.Id {
+				continue
+			}
+			if versionDecision := ffmpeg.MakeTranscodeDecision(version, clientInfo, player, requestUser.MaxBitRate); versionDecision.CanDirectPlay {
+				decision = versionDecision
+				break
+			}
+		}
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.TranscodeDecision = &decision
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
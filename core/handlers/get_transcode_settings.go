@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+// HandleGetTranscodeSettings returns the default DSP filters of the request user, or of another user for admins
+func HandleGetTranscodeSettings(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	userId := form["userid"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil {
+		logger.Printf("Error getting user by context: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "context user not found", "")
+		return
+	}
+
+	userIdInt := requestUser.Id
+	if userId != "" {
+		userIdInt, err = strconv.Atoi(userId)
+		if err != nil {
+			net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "userId parameter should be an integer", "")
+			return
+		}
+	}
+
+	if !requestUser.AdminRole && requestUser.Id != userIdInt {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "user not authorized to get transcode settings for this user", "")
+		return
+	}
+
+	if _, err := database.GetUserById(ctx, userIdInt); err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "User not found", "")
+		return
+	}
+
+	settings, err := database.GetTranscodeSettings(ctx, userIdInt)
+	if err != nil {
+		logger.Printf("Error getting transcode settings for user ID %d: %v", userIdInt, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to get transcode settings", "")
+		return
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.TranscodeSettings = &settings
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
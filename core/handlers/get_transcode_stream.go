@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"strconv"
+	"zene/core/config"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/net"
+	"zene/core/types"
+)
+
+func HandleGetTranscodeStream(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	mediaId := form["mediaid"]
+	mediaType := form["mediatype"]
+	offsetString := form["offset"]
+	transcodeParams := form["transcodeparams"]
+	client := form["c"]
+
+	ctx := r.Context()
+
+	if mediaId == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "mediaId parameter is required", "")
+		return
+	}
+
+	if mediaType != "" && mediaType != "song" && mediaType != "podcast" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "mediaType must be song or podcast", "")
+		return
+	}
+
+	if transcodeParams == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "transcodeParams parameter is required", "")
+		return
+	}
+
+	params, err := ffmpeg.DecodeTranscodeParams(transcodeParams)
+	if err != nil {
+		logger.Printf("Error decoding transcode params for %s: %v", mediaId, err)
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "transcodeParams is not valid", "")
+		return
+	}
+
+	offset := 0
+	if offsetString != "" {
+		if offsetInt, err := strconv.Atoi(offsetString); err == nil && offsetInt >= 0 {
+			offset = offsetInt
+		}
+	}
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil {
+		logger.Printf("Error getting user by context: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "User not found", "")
+		return
+	}
+
+	// the params come from the client, so the user's and player's limits are applied again
+	player := getPlayer(ctx, requestUser, client)
+	if params.Format == "raw" && !player.AllowRaw {
+		params.Format = cmp.Or(player.Format, "aac")
+		params.MaxBitRate = cmp.Or(player.MaxBitRate, config.DefaultBitRate)
+		params.MaxSampleRate = player.MaxSampleRate
+	}
+	if requestUser.MaxBitRate > 0 && params.MaxBitRate > requestUser.MaxBitRate {
+		params.MaxBitRate = requestUser.MaxBitRate
+	}
+
+	// the decision is only about the format, so the user's default filters are added to it
+	settings, err := getTranscodeSettings(ctx, form, requestUser, player)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, err.Error(), "")
+		return
+	}
+
+	mediaFile, err := database.GetMediaFile(ctx, mediaId, types.VersionPreference{
+		VersionId:  params.VersionId,
+		Format:     params.Format,
+		MaxBitRate: params.MaxBitRate,
+	})
+
+	if errors.Is(err, database.ErrVersionNotFound) {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Version not found.", "")
+		return
+	}
+
+	if err != nil || mediaFile.FilePath == "" {
+		logger.Printf("Error querying database for media filepath %s: %v", mediaId, err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "File not available to stream.", "")
+		return
+	}
+
+	// a CUE sheet track is only part of its source file, so it is transcoded losslessly instead
+	if params.Format == "raw" && mediaFile.IsCueTrack {
+		params.Format = "flac"
+		params.MaxBitRate = 0
+	}
+
+	if params.Format == "raw" {
+		serveRawFile(w, r, mediaFile.FilePath)
+		return
+	}
+
+	err = ffmpeg.TranscodeAndStream(ctx, w, r, mediaFile, mediaId, types.TranscodeOptions{
+		Format:        params.Format,
+		MaxBitRate:    params.MaxBitRate,
+		MaxSampleRate: logic.MinPositive(params.MaxSampleRate, settings.MaxSampleRate),
+		TimeOffset:    offset,
+		Normalization: settings.Normalization,
+		Channels:      logic.MinPositive(params.Channels, settings.Channels),
+		MaxBitDepth:   settings.MaxBitDepth,
+	})
+	if errors.Is(err, ffmpeg.ErrTranscodeQueueTimeout) {
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Too many transcodes are running, try again later", "")
+		return
+	}
+
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error streaming audio", "")
+		return
+	}
+}
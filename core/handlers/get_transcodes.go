@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleGetTranscodes(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage transcodes", "")
+		return
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.Transcodes = &types.Transcodes{
+		Transcode: ffmpeg.GetTranscodes(),
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+	"zene/core/waveform"
+)
+
+// defaultWaveformResolution is the number of peaks returned when a request does not ask for a resolution
+const defaultWaveformResolution = 500
+
+// HandleGetWaveform returns the peak amplitudes of a track for drawing a waveform seek bar, as a Subsonic response,
+// or with binary=true as one byte per peak
+func HandleGetWaveform(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	trackId := form["id"]
+	resolutionString := form["resolution"]
+	binary := form["binary"] == "true"
+
+	ctx := r.Context()
+
+	if trackId == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id parameter is required", "")
+		return
+	}
+
+	resolution := defaultWaveformResolution
+	if resolutionString != "" {
+		var err error
+		resolution, err = strconv.Atoi(resolutionString)
+		if err != nil || resolution < 1 || resolution > waveform.MaxResolution {
+			net.WriteSubsonicError(w, r, types.ErrorMissingParameter, fmt.Sprintf("resolution parameter must be an integer from 1 to %d", waveform.MaxResolution), "")
+			return
+		}
+	}
+
+	// the waveform is the same for every version of a track, so it is computed from whichever the user would stream
+	mediaFile, err := database.GetMediaFile(ctx, trackId, types.VersionPreference{Format: "raw"})
+	if errors.Is(err, database.ErrVersionNotFound) || (err == nil && mediaFile.FilePath == "") {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Song not found.", "")
+		return
+	}
+	if err != nil {
+		logger.Printf("Error querying database for media filepath %s: %v", trackId, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "File not found in database.", "")
+		return
+	}
+
+	peaks, err := waveform.GetPeaks(ctx, trackId, mediaFile, resolution)
+	if errors.Is(err, ffmpeg.ErrTranscodeQueueTimeout) {
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Too many transcodes are running, try again later", "")
+		return
+	}
+	if err != nil {
+		logger.Printf("Error computing waveform of %s: %v", trackId, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error computing waveform", "")
+		return
+	}
+
+	if binary {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(peaks)))
+		if _, err := w.Write(peaks); err != nil {
+			logger.Printf("Error writing waveform of %s: %v", trackId, err)
+		}
+		return
+	}
+
+	peakInts := make([]int, len(peaks))
+	for i, peak := range peaks {
+		peakInts[i] = int(peak)
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.Waveform = &types.Waveform{
+		Id:         trackId,
+		Resolution: len(peaks),
+		Duration:   mediaFile.Duration,
+		Peaks:      peakInts,
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"zene/core/config"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/net"
+	"zene/core/types"
+)
+
+// hlsForwardedParams are passed on to the URLs in HLS playlists, as players fetch them without adding authentication,
+// along with the DSP options so that every segment is filtered the same
+var hlsForwardedParams = []string{"u", "p", "t", "s", "apikey", "v", "c", "normalization", "channels", "maxsamplerate", "maxbitdepth"}
+
+// HandleHls returns an HLS playlist of a song or podcast episode, transcoded to AAC.
+// With more than one bitRate parameter, or none, it returns a master playlist with a variant for each bit rate,
+// and with one bitRate it returns the playlist of segments at that bit rate. Bit rates must be ones in config.HlsBitRates.
+func HandleHls(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	streamId := form["id"]
+	versionId := form["versionid"]
+	client := form["c"]
+
+	ctx := r.Context()
+
+	if streamId == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id parameter is required", "")
+		return
+	}
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil {
+		logger.Printf("Error getting user by context: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "User not found", "")
+		return
+	}
+
+	player := getPlayer(ctx, requestUser, client)
+
+	bitRates := []int{}
+	for key, values := range r.Form {
+		if !strings.EqualFold(key, "bitrate") {
+			continue
+		}
+		for _, value := range values {
+			bitRate, err := strconv.Atoi(value)
+			if err != nil || !isHlsBitRate(bitRate, requestUser, player) {
+				net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "bitRate parameter must be one of the configured HLS bit rates", "")
+				return
+			}
+			bitRates = append(bitRates, bitRate)
+		}
+	}
+
+	// without bit rates, the configured variants are offered up to the limits of the user and the player
+	maxBitRate := requestUser.MaxBitRate
+	if len(bitRates) == 0 {
+		bitRates = config.HlsBitRates
+		maxBitRate = logic.MinPositive(requestUser.MaxBitRate, player.MaxBitRate)
+	}
+	bitRates = capBitRates(bitRates, maxBitRate)
+
+	mediaFile, err := database.GetMediaFile(ctx, streamId, types.VersionPreference{
+		VersionId:  versionId,
+		Format:     "aac",
+		MaxBitRate: slices.Max(bitRates),
+	})
+
+	if errors.Is(err, database.ErrVersionNotFound) {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Version not found.", "")
+		return
+	}
+
+	if err != nil || mediaFile.FilePath == "" {
+		logger.Printf("Error querying database for media filepath %s: %v", streamId, err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "File not available to stream.", "")
+		return
+	}
+
+	segmentCount := ffmpeg.GetHlsSegmentCount(mediaFile)
+	if segmentCount == 0 {
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "The duration of the file is not known.", "")
+		return
+	}
+
+	// every variant and segment is transcoded from the same version of a song
+	query := getHlsQuery(r)
+	query.Set("id", streamId)
+	if mediaFile.VersionId != "" {
+		query.Set("versionId", mediaFile.VersionId)
+	}
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	if len(bitRates) > 1 {
+		for _, bitRate := range bitRates {
+			query.Set("bitRate", strconv.Itoa(bitRate))
+			// MPEG-TS adds about a tenth to the audio bit rate
+			fmt.Fprintf(&playlist, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=\"mp4a.40.2\"\n", bitRate*1100)
+			fmt.Fprintf(&playlist, "hls.m3u8?%s\n", query.Encode())
+		}
+	} else {
+		query.Set("bitRate", strconv.Itoa(bitRates[0]))
+		fmt.Fprintf(&playlist, "#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n", ffmpeg.HlsSegmentSeconds)
+		for index := range segmentCount {
+			query.Set("index", strconv.Itoa(index))
+			fmt.Fprintf(&playlist, "#EXTINF:%.3f,\n", ffmpeg.GetHlsSegmentDuration(mediaFile, index))
+			fmt.Fprintf(&playlist, "hlsSegment?%s\n", query.Encode())
+		}
+		playlist.WriteString("#EXT-X-ENDLIST\n")
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write([]byte(playlist.String()))
+}
+
+// getHlsQuery returns the authentication and DSP parameters of a request, to add to the URLs in a playlist
+func getHlsQuery(r *http.Request) url.Values {
+	query := url.Values{}
+	for key, values := range r.Form {
+		if slices.Contains(hlsForwardedParams, strings.ToLower(key)) && len(values) > 0 {
+			query.Set(key, values[0])
+		}
+	}
+	return query
+}
+
+// capBitRates sorts bit rates and removes those above a maximum, keeping the maximum itself when every bit rate is above it
+func capBitRates(bitRates []int, maxBitRate int) []int {
+	capped := []int{}
+	for _, bitRate := range bitRates {
+		if (maxBitRate <= 0 || bitRate <= maxBitRate) && !slices.Contains(capped, bitRate) {
+			capped = append(capped, bitRate)
+		}
+	}
+	if len(capped) == 0 {
+		capped = append(capped, maxBitRate)
+	}
+	slices.Sort(capped)
+	return capped
+}
+
+// isHlsBitRate reports whether a bit rate is one of the variants HandleHls offers to a user, with or without
+// the limit of their player, so that clients cannot fill the audio cache with variants at any bit rate
+func isHlsBitRate(bitRate int, requestUser types.User, player types.Player) bool {
+	return slices.Contains(capBitRates(config.HlsBitRates, requestUser.MaxBitRate), bitRate) ||
+		slices.Contains(capBitRates(config.HlsBitRates, logic.MinPositive(requestUser.MaxBitRate, player.MaxBitRate)), bitRate)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/types"
+)
+
+// HandleHlsSegment serves a segment of an HLS playlist returned by HandleHls
+func HandleHlsSegment(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	streamId := form["id"]
+	versionId := form["versionid"]
+	bitRateString := form["bitrate"]
+	indexString := form["index"]
+	client := form["c"]
+
+	ctx := r.Context()
+
+	if streamId == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id parameter is required", "")
+		return
+	}
+
+	index, err := strconv.Atoi(indexString)
+	if err != nil || index < 0 {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "index parameter must be a segment number", "")
+		return
+	}
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil {
+		logger.Printf("Error getting user by context: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "User not found", "")
+		return
+	}
+
+	player := getPlayer(ctx, requestUser, client)
+
+	bitRate, err := strconv.Atoi(bitRateString)
+	if err != nil || !isHlsBitRate(bitRate, requestUser, player) {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "bitRate parameter must be one of the configured HLS bit rates", "")
+		return
+	}
+
+	settings, err := getTranscodeSettings(ctx, form, requestUser, player)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, err.Error(), "")
+		return
+	}
+
+	mediaFile, err := database.GetMediaFile(ctx, streamId, types.VersionPreference{
+		VersionId:  versionId,
+		Format:     "aac",
+		MaxBitRate: bitRate,
+	})
+
+	if errors.Is(err, database.ErrVersionNotFound) {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Version not found.", "")
+		return
+	}
+
+	if err != nil || mediaFile.FilePath == "" {
+		logger.Printf("Error querying database for media filepath %s: %v", streamId, err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "File not available to stream.", "")
+		return
+	}
+
+	if index >= ffmpeg.GetHlsSegmentCount(mediaFile) {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Segment not found.", "")
+		return
+	}
+
+	err = ffmpeg.TranscodeHlsSegment(ctx, w, r, mediaFile, streamId, types.TranscodeOptions{
+		Format:        "aac",
+		MaxBitRate:    bitRate,
+		MaxSampleRate: settings.MaxSampleRate,
+		Normalization: settings.Normalization,
+		Channels:      settings.Channels,
+		MaxBitDepth:   settings.MaxBitDepth,
+	}, index)
+	if errors.Is(err, ffmpeg.ErrTranscodeQueueTimeout) {
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Too many transcodes are running, try again later", "")
+		return
+	}
+
+	if err != nil {
+		logger.Printf("Error transcoding HLS segment %d of %s: %v", index, streamId, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error streaming audio", "")
+		return
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleKillTranscode(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	id := form["id"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage transcodes", "")
+		return
+	}
+
+	if id == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id parameter is mandatory", "")
+		return
+	}
+
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id must be a valid transcode id", "")
+		return
+	}
+
+	if err := ffmpeg.KillTranscode(idInt); err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Transcode not found", "")
+		return
+	}
+
+	logger.Printf("Transcode %d killed by %s", idInt, requestUser.Username)
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/scheduler"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandlePauseScheduledJob(w http.ResponseWriter, r *http.Request) {
+	handleSetScheduledJobPaused(w, r, true)
+}
+
+func HandleResumeScheduledJob(w http.ResponseWriter, r *http.Request) {
+	handleSetScheduledJobPaused(w, r, false)
+}
+
+func handleSetScheduledJobPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	name := form["name"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage scheduled jobs", "")
+		return
+	}
+
+	if name == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "name parameter is mandatory", "")
+		return
+	}
+
+	scheduledJob, err := scheduler.SetJobPaused(ctx, name, paused)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrJobNotFound) {
+			net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Scheduled job not found", "")
+			return
+		}
+		logger.Printf("Error updating scheduled job %s: %v", name, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to update scheduled job", "")
+		return
+	}
+
+	if paused {
+		logger.Printf("Scheduled job %s paused by %s", name, requestUser.Username)
+	} else {
+		logger.Printf("Scheduled job %s resumed by %s", name, requestUser.Username)
+	}
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.ScheduledJobs = &types.ScheduledJobs{
+		ScheduledJob: []types.ScheduledJob{scheduledJob},
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
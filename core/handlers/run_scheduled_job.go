@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/scheduler"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleRunScheduledJob(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	name := form["name"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage scheduled jobs", "")
+		return
+	}
+
+	if name == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "name parameter is mandatory", "")
+		return
+	}
+
+	scheduledJob, err := scheduler.RunJob(name)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrJobNotFound) {
+			net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Scheduled job not found", "")
+			return
+		}
+		if errors.Is(err, scheduler.ErrJobRunning) {
+			net.WriteSubsonicError(w, r, types.ErrorGeneric, "The scheduled job is already running", "")
+			return
+		}
+		logger.Printf("Error running scheduled job %s: %v", name, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to run scheduled job", "")
+		return
+	}
+
+	logger.Printf("Scheduled job %s triggered by %s", name, requestUser.Username)
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.ScheduledJobs = &types.ScheduledJobs{
+		ScheduledJob: []types.ScheduledJob{scheduledJob},
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
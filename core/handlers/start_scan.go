@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"zene/core/database"
 	"zene/core/logger"
 	"zene/core/net"
 	"zene/core/scanner"
@@ -20,10 +23,32 @@ func HandleStartScan(w http.ResponseWriter, r *http.Request) {
 	format := form["f"]
 	force := form["force"]
 	includeArt := form["include-art"]
+	musicFolderId := form["musicfolderid"]
+	path := form["path"]
+	allowMassDeletion := form["allowmassdeletion"]
 
 	scanOptions := types.ScanOptions{
-		Force:      strings.ToLower(force) == "true",
-		IncludeArt: strings.ToLower(includeArt) == "true",
+		Force:             strings.ToLower(force) == "true",
+		IncludeArt:        strings.ToLower(includeArt) == "true",
+		Path:              path,
+		AllowMassDeletion: strings.ToLower(allowMassDeletion) == "true",
+	}
+
+	if scanOptions.AllowMassDeletion {
+		requestUser, err := database.GetUserByContext(r.Context())
+		if err != nil || !requestUser.AdminRole {
+			net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "Only admins can allow mass deletions", "")
+			return
+		}
+	}
+
+	if musicFolderId != "" {
+		musicFolderIdInt, err := strconv.Atoi(musicFolderId)
+		if err != nil || musicFolderIdInt < 1 {
+			net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "musicFolderId must be a valid music folder id", "")
+			return
+		}
+		scanOptions.MusicFolderId = musicFolderIdInt
 	}
 
 	var scanStatus types.ScanStatus
@@ -32,6 +57,11 @@ func HandleStartScan(w http.ResponseWriter, r *http.Request) {
 	scanStatus, err = scanner.RunScan(context.Background(), scanOptions)
 
 	if err != nil {
+		if errors.Is(err, scanner.ErrInvalidScanTarget) {
+			logger.Printf("Error starting scan: %v", err)
+			net.WriteSubsonicError(w, r, types.ErrorDataNotFound, err.Error(), "")
+			return
+		}
 		if scanStatus.Scanning {
 			logger.Printf("Error starting scan: %v", scanStatus)
 			net.WriteSubsonicError(w, r, types.ErrorGeneric, "A scan is already in progress. Please wait for it to complete before starting a new one.", "")
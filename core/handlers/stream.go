@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"mime"
 	"net/http"
@@ -26,6 +29,9 @@ func HandleStream(w http.ResponseWriter, r *http.Request) {
 	maxBitRateString := form["maxbitrate"]
 	streamFormat := form["format"]
 	timeOffsetString := form["timeoffset"]
+	versionId := form["versionid"]
+	client := form["c"]
+	estimateContentLength := form["estimatecontentlength"] == "true"
 
 	ctx := r.Context()
 
@@ -41,9 +47,12 @@ func HandleStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the player's transcoding profile is used for whatever the client does not ask for
+	player := getPlayer(ctx, requestUser, client)
+
 	var maxBitRate int
 	if maxBitRateString == "" {
-		maxBitRate = config.DefaultBitRate
+		maxBitRate = cmp.Or(player.MaxBitRate, config.DefaultBitRate)
 	} else {
 		var err error
 		maxBitRate, err = strconv.Atoi(maxBitRateString)
@@ -58,7 +67,17 @@ func HandleStream(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if streamFormat == "" {
-		streamFormat = "aac" // default format
+		streamFormat = cmp.Or(player.Format, "aac") // default format
+	}
+
+	if streamFormat == "raw" && !player.AllowRaw {
+		streamFormat = cmp.Or(player.Format, "aac")
+	}
+
+	settings, err := getTranscodeSettings(ctx, form, requestUser, player)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, err.Error(), "")
+		return
 	}
 
 	timeOffset := 0
@@ -68,9 +87,13 @@ func HandleStream(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	mediaFilepath, err := database.GetMediaFilePath(ctx, streamId)
+	mediaFile, err := database.GetMediaFile(ctx, streamId, types.VersionPreference{
+		VersionId:  versionId,
+		Format:     streamFormat,
+		MaxBitRate: maxBitRate,
+	})
 
-	if mediaFilepath == "" || err != nil {
+	if mediaFile.FilePath == "" || err != nil {
 		// check if the file is a podcast episode
 		if requestUser.PodcastRole {
 			episode, _ := database.GetPodcastEpisodeByGuid(ctx, streamId)
@@ -81,41 +104,101 @@ func HandleStream(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if errors.Is(err, database.ErrVersionNotFound) {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Version not found.", "")
+		return
+	}
+
 	if err != nil {
 		logger.Printf("Error querying database for media filepath %s: %v", streamId, err)
 		net.WriteSubsonicError(w, r, types.ErrorGeneric, "File not found in database.", "")
 		return
 	}
 
-	if mediaFilepath == "" {
+	if mediaFile.FilePath == "" {
 		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "File not available to stream.", "")
 		return
 	}
 
-	if streamFormat == "raw" {
-		fileInfo, modTime, file, err := getFile(mediaFilepath)
-		if err != nil {
-			net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error opening file.", "")
-			return
+	// a CUE sheet track is only part of its source file, so it is transcoded losslessly instead
+	if streamFormat == "raw" && mediaFile.IsCueTrack {
+		streamFormat = "flac"
+		maxBitRate = 0
+	}
+
+	// the cache warm-up transcodes with what each player last streamed with, so that it warms the files the player asks for
+	if player.Id > 0 {
+		if err := database.UpdatePlayerLastStream(ctx, player.Id, streamFormat, maxBitRate); err != nil {
+			logger.Printf("Error recording the last stream of player %s for user %s: %v", client, requestUser.Username, err)
 		}
-		defer file.Close()
-
-		w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-		w.Header().Set("Cache-Control", "public, max-age=31536000")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		contentType := mime.TypeByExtension(filepath.Ext(fileInfo.Name()))
-		w.Header().Set("Content-Type", contentType)
-		http.ServeContent(w, r, fileInfo.Name(), modTime, file)
+	}
+
+	if streamFormat == "raw" {
+		serveRawFile(w, r, mediaFile.FilePath)
+		return
+	}
+
+	err = ffmpeg.TranscodeAndStream(ctx, w, r, mediaFile, streamId, types.TranscodeOptions{
+		Format:                streamFormat,
+		MaxBitRate:            maxBitRate,
+		MaxSampleRate:         settings.MaxSampleRate,
+		TimeOffset:            timeOffset,
+		EstimateContentLength: estimateContentLength,
+		Normalization:         settings.Normalization,
+		Channels:              settings.Channels,
+		MaxBitDepth:           settings.MaxBitDepth,
+	})
+	if errors.Is(err, ffmpeg.ErrTranscodeQueueTimeout) {
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Too many transcodes are running, try again later", "")
 		return
 	}
 
-	err = ffmpeg.TranscodeAndStream(ctx, w, r, mediaFilepath, streamId, maxBitRate, timeOffset, streamFormat)
 	if err != nil {
 		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error streaming audio", "")
 		return
 	}
 }
 
+// getPlayer returns the player for a user's client with its transcoding profile, or the default profile when there is no client
+func getPlayer(ctx context.Context, requestUser types.User, client string) types.Player {
+	if client == "" {
+		return types.Player{AllowRaw: true}
+	}
+	player, err := database.UpsertPlayer(ctx, requestUser.Id, client)
+	if err != nil {
+		logger.Printf("Error getting player %s for user %s: %v", client, requestUser.Username, err)
+		return types.Player{AllowRaw: true}
+	}
+	return player
+}
+
+// getTranscodeSettings returns the DSP filters of a stream request, using the user's defaults for the parameters it does not pass.
+// Without a maxSampleRate parameter, the lower of the user's and the player's sample rate caps is used.
+func getTranscodeSettings(ctx context.Context, form map[string]string, requestUser types.User, player types.Player) (types.TranscodeSettings, error) {
+	settings, err := database.GetPlayerTranscodeSettings(ctx, requestUser.Id, player)
+	if err != nil {
+		logger.Printf("Error getting transcode settings for user %s: %v", requestUser.Username, err)
+	}
+	return parseTranscodeSettingsForm(form, settings)
+}
+
+// serveRawFile streams a media file as it is, with support for range requests
+func serveRawFile(w http.ResponseWriter, r *http.Request, filePath string) {
+	fileInfo, modTime, file, err := getFile(filePath)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Error opening file.", "")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
+	w.Header().Set("Cache-Control", "public, max-age=31536000")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	contentType := mime.TypeByExtension(filepath.Ext(fileInfo.Name()))
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, fileInfo.Name(), modTime, file)
+}
+
 func getFile(filePath string) (os.FileInfo, time.Time, *os.File, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/scanner"
+	"zene/core/subsonic"
+	"zene/core/types"
+	"zene/core/watcher"
+)
+
+func HandleUpdateMusicFolder(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	id := form["id"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage music folders", "")
+		return
+	}
+
+	if id == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id parameter is mandatory", "")
+		return
+	}
+
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id must be a valid music folder id", "")
+		return
+	}
+
+	musicFolder, err := database.GetMusicFolderSettingsById(ctx, idInt)
+	if err != nil {
+		logger.Printf("Error getting music folder %d: %v", idInt, err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Music folder not found", "")
+		return
+	}
+
+	musicFolder, err = parseMusicFolderSettingsForm(form, musicFolder)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, err.Error(), "")
+		return
+	}
+
+	musicFolder, err = scanner.UpdateMusicFolder(ctx, musicFolder)
+	if err != nil {
+		logger.Printf("Error updating music folder %d: %v", idInt, err)
+		if errors.Is(err, scanner.ErrInvalidMusicFolder) {
+			net.WriteSubsonicError(w, r, types.ErrorGeneric, err.Error(), "")
+			return
+		}
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to update music folder", "")
+		return
+	}
+
+	logger.Printf("Music folder %s updated by %s", musicFolder.Path, requestUser.Username)
+	watcher.Refresh()
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.MusicFolderSettings = &types.MusicFolderSettingsList{
+		MusicFolder: []types.MusicFolderSettings{musicFolder},
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
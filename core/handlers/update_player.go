@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+func HandleUpdatePlayer(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	id := form["id"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil || !requestUser.AdminRole {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "You do not have permission to manage players", "")
+		return
+	}
+
+	if id == "" {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id parameter is mandatory", "")
+		return
+	}
+
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "id must be a valid player id", "")
+		return
+	}
+
+	player, err := database.GetPlayerById(ctx, idInt)
+	if err != nil {
+		logger.Printf("Error getting player %d: %v", idInt, err)
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "Player not found", "")
+		return
+	}
+
+	player, err = parsePlayerForm(form, player)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, err.Error(), "")
+		return
+	}
+
+	if err := database.UpdatePlayer(ctx, player); err != nil {
+		logger.Printf("Error updating player %d: %v", idInt, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to update player", "")
+		return
+	}
+
+	logger.Printf("Player %s of %s updated by %s", player.Client, player.Username, requestUser.Username)
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.Players = &types.Players{
+		Player: []types.Player{player},
+	}
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
+
+// parsePlayerForm applies the optional format, maxBitRate, maxSampleRate and allowRaw parameters to a player
+func parsePlayerForm(form map[string]string, player types.Player) (types.Player, error) {
+	if playerFormat, ok := form["format"]; ok {
+		playerFormat = strings.ToLower(strings.TrimSpace(playerFormat))
+		if playerFormat != "" && playerFormat != "raw" && !ffmpeg.IsSupportedFormat(playerFormat) {
+			return player, fmt.Errorf("format %s is not supported", playerFormat)
+		}
+		player.Format = playerFormat
+	}
+
+	if maxBitRate := form["maxbitrate"]; maxBitRate != "" {
+		parsedMaxBitRate, err := strconv.Atoi(maxBitRate)
+		if err != nil || parsedMaxBitRate < 0 {
+			return player, fmt.Errorf("maxBitRate must be a number of kbps, or 0 to use the default bit rate")
+		}
+		player.MaxBitRate = parsedMaxBitRate
+	}
+
+	if maxSampleRate := form["maxsamplerate"]; maxSampleRate != "" {
+		parsedMaxSampleRate, err := strconv.Atoi(maxSampleRate)
+		if err != nil || parsedMaxSampleRate < 0 {
+			return player, fmt.Errorf("maxSampleRate must be a number of Hz, or 0 to keep the file's sample rate")
+		}
+		player.MaxSampleRate = parsedMaxSampleRate
+	}
+
+	if allowRaw := form["allowraw"]; allowRaw != "" {
+		parsedAllowRaw, err := strconv.ParseBool(allowRaw)
+		if err != nil {
+			return player, fmt.Errorf("allowRaw must be true or false")
+		}
+		player.AllowRaw = parsedAllowRaw
+	}
+
+	if player.Format == "raw" && !player.AllowRaw {
+		return player, fmt.Errorf("format cannot be raw when allowRaw is false")
+	}
+
+	return player, nil
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/net"
+	"zene/core/subsonic"
+	"zene/core/types"
+)
+
+// HandleUpdateTranscodeSettings sets the default DSP filters of the request user, or of another user for admins.
+// Parameters that are not passed keep their current values.
+func HandleUpdateTranscodeSettings(w http.ResponseWriter, r *http.Request) {
+	if net.MethodIsNotGetOrPost(w, r) {
+		return
+	}
+
+	form := net.NormalisedForm(r, w)
+	format := form["f"]
+	userId := form["userid"]
+
+	ctx := r.Context()
+
+	requestUser, err := database.GetUserByContext(ctx)
+	if err != nil {
+		logger.Printf("Error getting user by context: %v", err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "context user not found", "")
+		return
+	}
+
+	userIdInt := requestUser.Id
+	if userId != "" {
+		userIdInt, err = strconv.Atoi(userId)
+		if err != nil {
+			net.WriteSubsonicError(w, r, types.ErrorMissingParameter, "userId parameter should be an integer", "")
+			return
+		}
+	}
+
+	if !requestUser.AdminRole && requestUser.Id != userIdInt {
+		net.WriteSubsonicError(w, r, types.ErrorNotAuthorized, "user not authorized to update transcode settings for this user", "")
+		return
+	}
+
+	user, err := database.GetUserById(ctx, userIdInt)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorDataNotFound, "User not found", "")
+		return
+	}
+
+	settings, err := database.GetTranscodeSettings(ctx, userIdInt)
+	if err != nil {
+		logger.Printf("Error getting transcode settings for user ID %d: %v", userIdInt, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to get transcode settings", "")
+		return
+	}
+
+	settings, err = parseTranscodeSettingsForm(form, settings)
+	if err != nil {
+		net.WriteSubsonicError(w, r, types.ErrorMissingParameter, err.Error(), "")
+		return
+	}
+
+	if err := database.UpsertTranscodeSettings(ctx, settings); err != nil {
+		logger.Printf("Error updating transcode settings for user ID %d: %v", userIdInt, err)
+		net.WriteSubsonicError(w, r, types.ErrorGeneric, "Failed to update transcode settings", "")
+		return
+	}
+
+	logger.Printf("Transcode settings of %s updated by %s", user.Username, requestUser.Username)
+
+	response := subsonic.GetPopulatedSubsonicResponse(ctx)
+
+	response.SubsonicResponse.TranscodeSettings = &settings
+
+	net.WriteSubsonicResponse(w, r, response, format)
+}
+
+// parseTranscodeSettingsForm applies the optional normalization, channels, maxSampleRate and maxBitDepth parameters to transcode settings
+func parseTranscodeSettingsForm(form map[string]string, settings types.TranscodeSettings) (types.TranscodeSettings, error) {
+	if normalization, ok := form["normalization"]; ok {
+		normalization = strings.ToLower(strings.TrimSpace(normalization))
+		switch normalization {
+		case "", "off":
+			settings.Normalization = ""
+		case types.NormalizationLoudnorm, types.NormalizationTrack, types.NormalizationAlbum:
+			settings.Normalization = normalization
+		default:
+			return settings, fmt.Errorf("normalization must be off, loudnorm, track or album")
+		}
+	}
+
+	if channels := form["channels"]; channels != "" {
+		parsedChannels, err := strconv.Atoi(channels)
+		if err != nil || parsedChannels < 0 || parsedChannels > 2 {
+			return settings, fmt.Errorf("channels must be 1 for mono, 2 for stereo, or 0 to keep the file's channels")
+		}
+		settings.Channels = parsedChannels
+	}
+
+	if maxSampleRate := form["maxsamplerate"]; maxSampleRate != "" {
+		parsedMaxSampleRate, err := strconv.Atoi(maxSampleRate)
+		if err != nil || parsedMaxSampleRate < 0 {
+			return settings, fmt.Errorf("maxSampleRate must be a number of Hz, or 0 to keep the file's sample rate")
+		}
+		settings.MaxSampleRate = parsedMaxSampleRate
+	}
+
+	if maxBitDepth := form["maxbitdepth"]; maxBitDepth != "" {
+		parsedMaxBitDepth, err := strconv.Atoi(maxBitDepth)
+		if err != nil || (parsedMaxBitDepth != 0 && parsedMaxBitDepth != 16 && parsedMaxBitDepth != 24) {
+			return settings, fmt.Errorf("maxBitDepth must be 16, 24, or 0 to keep the file's bit depth")
+		}
+		settings.MaxBitDepth = parsedMaxBitDepth
+	}
+
+	return settings, nil
+}
@@ -19,6 +19,7 @@ func CreateDirs() {
 		{config.AlbumArtFolder, "Album artwork folder already exists"},
 		{config.ArtistArtFolder, "Artist artwork folder already exists"},
 		{config.PodcastArtFolder, "Podcast artwork folder already exists"},
+		{config.WaveformFolder, "Waveform folder already exists"},
 		{config.AudioCacheFolder, "Database folder already exists"},
 		{config.PodcastDirectory, "Podcast folder already exists"},
 	}
@@ -100,7 +100,22 @@ func GetFileBlob(ctx context.Context, filePath string) ([]byte, error) {
 
 func GetFiles(ctx context.Context, directoryPath string, extensions []string) ([]types.File, error) {
 	files := []types.File{}
-	scanError := filepath.WalkDir(directoryPath, func(path string, d os.DirEntry, err error) error {
+	scanError := WalkFiles(ctx, directoryPath, extensions, func(foundFile types.File) error {
+		files = append(files, foundFile)
+		return nil
+	})
+
+	if scanError != nil {
+		logger.Printf("Error scanning files: %v", scanError)
+	}
+
+	return files, nil
+}
+
+// WalkFiles streams every file below directoryPath with a matching extension to fileFunc, without building a list of files.
+// Walking stops and the error is returned if the context is cancelled, a directory cannot be read, or fileFunc returns an error.
+func WalkFiles(ctx context.Context, directoryPath string, extensions []string, fileFunc func(types.File) error) error {
+	return filepath.WalkDir(directoryPath, func(path string, d os.DirEntry, err error) error {
 
 		if err != nil {
 			logger.Printf("Error scanning file path %s: %v", path, err)
@@ -123,6 +138,7 @@ func GetFiles(ctx context.Context, directoryPath string, extensions []string) ([
 
 		var foundFile types.File
 		foundFile.FilePath = path
+		foundFile.FileName = d.Name()
 		foundFile.FilePathAbs, err = filepath.Abs(path)
 		if err != nil {
 			logger.Printf("Error getting absolute file path for %s: %v", path, err)
@@ -134,15 +150,8 @@ func GetFiles(ctx context.Context, directoryPath string, extensions []string) ([
 			return err
 		}
 		foundFile.DateModified = modTime.Format(time.RFC3339Nano)
-		files = append(files, foundFile)
-		return nil
+		return fileFunc(foundFile)
 	})
-
-	if scanError != nil {
-		logger.Printf("Error scanning files: %v", scanError)
-	}
-
-	return files, nil
 }
 
 func DeleteFile(filePath string) error {
@@ -239,3 +239,14 @@ func LowercaseArray(originalStringArray []string) []string {
 	}
 	return result
 }
+
+// MinPositive returns the lowest value above 0, or 0 when there is none
+func MinPositive(values ...int) int {
+	result := 0
+	for _, value := range values {
+		if value > 0 && (result == 0 || value < result) {
+			result = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package logic
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// syntheticIdNamespace is the UUID namespace used for name-based (version 5) synthetic IDs.
+// MusicBrainz IDs are random (version 4) UUIDs, so a synthetic ID can never collide with a real MBID.
+var syntheticIdNamespace = uuid.MustParse("6f1c2f0e-5a52-4c1e-9d0b-7a1f3b9d8e24")
+
+// NormaliseTagValue lowercases a tag value and collapses all runs of whitespace to a single space
+func NormaliseTagValue(value string) string {
+	return strings.Join(strings.Fields(strings.ToLower(value)), " ")
+}
+
+// GenerateSyntheticId returns a deterministic UUID for the given parts, so the same tags always produce the same ID
+func GenerateSyntheticId(kind string, parts ...string) string {
+	normalisedParts := make([]string, 0, len(parts)+1)
+	normalisedParts = append(normalisedParts, kind)
+	for _, part := range parts {
+		normalisedParts = append(normalisedParts, NormaliseTagValue(part))
+	}
+	return uuid.NewSHA1(syntheticIdNamespace, []byte(strings.Join(normalisedParts, "\x1f"))).String()
+}
+
+// IsSyntheticId reports whether an ID was generated by GenerateSyntheticId rather than read from MusicBrainz tags
+func IsSyntheticId(id string) bool {
+	parsedId, err := uuid.Parse(id)
+	if err != nil {
+		return false
+	}
+	return parsedId.Version() == 5
+}
@@ -19,6 +19,14 @@ import (
 )
 
 func GetLyricsForMusicBrainzTrackId(ctx context.Context, musicBrainzTrackId string) (types.LyricsDatabaseRow, error) {
+	localLyrics, err := getLocalLyrics(ctx, musicBrainzTrackId)
+	if err != nil {
+		logger.Printf("Error querying local lyrics in GetLyricsForMusicBrainzTrackId: %v", err)
+	} else if localLyrics.Source != "" {
+		logger.Printf("Found %s lyrics for track ID: %s", localLyrics.Source, musicBrainzTrackId)
+		return localLyrics, nil
+	}
+
 	lyrics, err := database.GetTrackLyrics(ctx, musicBrainzTrackId)
 	if err != nil {
 		logger.Printf("Error querying database in GetLyricsForMusicBrainzTrackId: %v", err)
@@ -93,6 +101,7 @@ func GetLyricsForMusicBrainzTrackIdFromLrclib(ctx context.Context, musicBrainzTr
 
 	var upsertData types.LyricsDatabaseRow
 	upsertData.MusicBrainzTrackID = musicBrainzTrackId
+	upsertData.Source = types.LyricsSourceLrclib
 
 	if res.StatusCode == http.StatusNotFound {
 		// Track not found on lrclib (or track is instrumental!), store "NoLyricsFound" in the database
@@ -0,0 +1,163 @@
+package lyrics
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"zene/core/database"
+	"zene/core/io"
+	"zene/core/logger"
+	"zene/core/types"
+)
+
+var (
+	// matches LRC timestamps like [01:23.45], [01:23:45] or [01:23]
+	lrcTimestampRegex = regexp.MustCompile(`\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	// matches LRC ID tags like [ar:Artist] or [offset:+500]
+	lrcIdTagRegex = regexp.MustCompile(`^\[([a-zA-Z]+):(.*)\]$`)
+)
+
+var sidecarLyricsSources = []struct {
+	Extension string
+	Source    string
+}{
+	{".lrc", types.LyricsSourceSidecarLrc},
+	{".txt", types.LyricsSourceSidecarTxt},
+}
+
+type lrcLine struct {
+	Milliseconds int
+	Text         string
+}
+
+// ParseLyricsText splits lyrics into plain lyrics, and synced lyrics if the text has LRC timestamps.
+// Synced lyrics are normalised to one "[mm:ss.xx] text" line per timestamp, sorted by time.
+func ParseLyricsText(text string) (string, string) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	offset := 0
+	syncedLines := []lrcLine{}
+	plainLines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+
+		if matches := lrcIdTagRegex.FindStringSubmatch(line); matches != nil && !lrcTimestampRegex.MatchString(line) {
+			if strings.EqualFold(matches[1], "offset") {
+				offset, _ = strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(matches[2]), "+"))
+			}
+			continue
+		}
+
+		timestamps := lrcTimestampRegex.FindAllStringSubmatchIndex(line, -1)
+		if len(timestamps) == 0 || timestamps[0][0] != 0 {
+			plainLines = append(plainLines, line)
+			continue
+		}
+
+		// a line can have several leading timestamps when it is repeated
+		lyric := strings.TrimSpace(line[timestamps[len(timestamps)-1][1]:])
+		for _, timestamp := range timestamps {
+			minutes, _ := strconv.Atoi(line[timestamp[2]:timestamp[3]])
+			seconds, _ := strconv.Atoi(line[timestamp[4]:timestamp[5]])
+			fraction := 0
+			if timestamp[6] >= 0 {
+				fractionString := line[timestamp[6]:timestamp[7]]
+				fraction, _ = strconv.Atoi(fractionString)
+				for range 3 - len(fractionString) {
+					fraction *= 10
+				}
+			}
+			syncedLines = append(syncedLines, lrcLine{Milliseconds: (minutes*60+seconds)*1000 + fraction, Text: lyric})
+		}
+		plainLines = append(plainLines, lyric)
+	}
+
+	plain := strings.TrimSpace(strings.Join(plainLines, "\n"))
+	if len(syncedLines) == 0 {
+		return plain, ""
+	}
+
+	slices.SortStableFunc(syncedLines, func(a, b lrcLine) int { return cmp.Compare(a.Milliseconds, b.Milliseconds) })
+	synced := make([]string, 0, len(syncedLines))
+	for _, line := range syncedLines {
+		// a positive offset makes the lyrics appear sooner
+		milliseconds := max(line.Milliseconds-offset, 0)
+		synced = append(synced, fmt.Sprintf("[%02d:%02d.%02d] %s", milliseconds/60000, milliseconds/1000%60, milliseconds%1000/10, line.Text))
+	}
+	return plain, strings.Join(synced, "\n")
+}
+
+// NewLocalLyrics parses lyrics text from a sidecar file or tag, returning false if it is empty
+func NewLocalLyrics(source string, lang string, text string) (types.LocalLyrics, bool) {
+	plain, synced := ParseLyricsText(text)
+	if plain == "" && synced == "" {
+		return types.LocalLyrics{}, false
+	}
+	if strings.EqualFold(lang, "xxx") {
+		lang = ""
+	}
+	return types.LocalLyrics{
+		Source:       source,
+		Priority:     types.LyricsSourcePriorities[source],
+		Lang:         lang,
+		PlainLyrics:  plain,
+		SyncedLyrics: synced,
+	}, true
+}
+
+// GetSidecarLyrics reads .lrc and .txt files with the same name as an audio file
+func GetSidecarLyrics(audioFilePath string) []types.LocalLyrics {
+	basePath := strings.TrimSuffix(audioFilePath, filepath.Ext(audioFilePath))
+
+	results := []types.LocalLyrics{}
+	for _, sidecar := range sidecarLyricsSources {
+		for _, sidecarPath := range []string{basePath + sidecar.Extension, basePath + strings.ToUpper(sidecar.Extension)} {
+			if !io.FileExists(sidecarPath) {
+				continue
+			}
+			data, err := os.ReadFile(sidecarPath)
+			if err != nil {
+				logger.Printf("Error reading lyrics file %s: %v", sidecarPath, err)
+				continue
+			}
+			if localLyrics, ok := NewLocalLyrics(sidecar.Source, "", string(data)); ok {
+				results = append(results, localLyrics)
+			}
+			break
+		}
+	}
+	return results
+}
+
+// getLocalLyrics returns the highest priority lyrics imported from sidecar files and tags.
+// Tracks without imported lyrics have their sidecar files checked, so lyrics added after a scan are still found.
+func getLocalLyrics(ctx context.Context, musicBrainzTrackId string) (types.LyricsDatabaseRow, error) {
+	localLyrics, err := database.GetLocalLyrics(ctx, musicBrainzTrackId)
+	if err != nil || localLyrics.Source != "" {
+		return localLyrics, err
+	}
+
+	track, err := database.SelectTrack(ctx, musicBrainzTrackId)
+	if err != nil || track.FilePath == "" {
+		return types.LyricsDatabaseRow{}, err
+	}
+
+	sidecarLyrics := GetSidecarLyrics(track.FilePath)
+	if len(sidecarLyrics) == 0 {
+		return types.LyricsDatabaseRow{}, nil
+	}
+
+	if err := database.InsertLocalLyrics(ctx, track.FilePath, sidecarLyrics); err != nil {
+		logger.Printf("Error importing sidecar lyrics for %s: %v", track.FilePath, err)
+	}
+	return database.GetLocalLyrics(ctx, musicBrainzTrackId)
+}
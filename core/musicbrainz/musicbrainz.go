@@ -31,6 +31,10 @@ func ClearMbCache() {
 }
 
 func GetMetadataForMusicBrainzAlbumId(musicBrainzAlbumId string) (types.MbRelease, error) {
+	if logic.IsSyntheticId(musicBrainzAlbumId) {
+		return types.MbRelease{}, fmt.Errorf("album ID %s is synthetic and does not exist on musicbrainz", musicBrainzAlbumId)
+	}
+
 	// check cache first
 	mbCacheMu.RLock()
 	data, found := mbCache[musicBrainzAlbumId]
@@ -94,6 +98,10 @@ func GetMetadataForMusicBrainzAlbumId(musicBrainzAlbumId string) (types.MbReleas
 }
 
 func GetAlbumArtUrl(ctx context.Context, musicBrainzAlbumId string) (string, error) {
+	if logic.IsSyntheticId(musicBrainzAlbumId) {
+		return "", fmt.Errorf("album ID %s is synthetic and does not exist on coverartarchive", musicBrainzAlbumId)
+	}
+
 	url := fmt.Sprintf("https://coverartarchive.org/release/%s", musicBrainzAlbumId)
 
 	if err := logic.CheckContext(ctx); err != nil {
@@ -156,6 +164,10 @@ func GetAlbumArtUrl(ctx context.Context, musicBrainzAlbumId string) (string, err
 }
 
 func GetArtistArtUrl(ctx context.Context, musicBrainzArtistId string) (string, error) {
+	if logic.IsSyntheticId(musicBrainzArtistId) {
+		return "", fmt.Errorf("artist ID %s is synthetic and does not exist on musicbrainz", musicBrainzArtistId)
+	}
+
 	url := fmt.Sprintf("https://musicbrainz.org/ws/2/artist/%s?inc=url-rels&fmt=json", musicBrainzArtistId)
 
 	if err := logic.CheckContext(ctx); err != nil {
@@ -0,0 +1,203 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"zene/core/config"
+	"zene/core/cue"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+// number of new or modified files that are probed and upserted together
+const scanBatchSize = 500
+
+// the deletion ratio is only checked above this many missing files, so that small music folders can still be tidied up
+const minimumSafeguardedDeletions = 10
+
+// changeDetector compares a stream of files from the filesystem against an indexed view of the metadata table.
+// Files are upserted in bounded batches as they are found, and any metadata rows that were never matched are deleted at the end.
+type changeDetector struct {
+	ctx               context.Context
+	scanId            int
+	musicFolder       types.MusicFolderSettings
+	force             bool
+	allowMassDeletion bool
+	existing          map[string]string
+	estimatedFiles    int
+	walkedFiles       int
+	newFiles          []types.File
+	modifiedFiles     []types.File
+	changesMade       bool
+	collectUpserted   bool
+	upserted          []types.Metadata
+	cueDirectory      string
+	cueChangedTime    time.Time
+	cueFound          bool
+}
+
+// newChangeDetector creates a change detector from a map of file path to metadata date_modified.
+// If collectUpserted is true the upserted metadata rows are kept, so callers can import art for them.
+func newChangeDetector(ctx context.Context, scanId int, musicFolder types.MusicFolderSettings, force bool, allowMassDeletion bool, existing map[string]string, collectUpserted bool) *changeDetector {
+	// until the walk is complete, the files already in the database are the best estimate of how many files there are
+	addScanTotal(ctx, len(existing))
+	return &changeDetector{
+		ctx:               ctx,
+		scanId:            scanId,
+		musicFolder:       musicFolder,
+		force:             force,
+		allowMassDeletion: allowMassDeletion,
+		existing:          existing,
+		estimatedFiles:    len(existing),
+		newFiles:          make([]types.File, 0, scanBatchSize),
+		modifiedFiles:     make([]types.File, 0, scanBatchSize),
+		collectUpserted:   collectUpserted,
+	}
+}
+
+func (d *changeDetector) addFile(file types.File) error {
+	file = d.withCueSheetDate(file)
+
+	d.walkedFiles++
+	if d.walkedFiles > d.estimatedFiles {
+		addScanTotal(d.ctx, 1)
+	}
+
+	dateModified, found := d.existing[file.FilePathAbs]
+	if found {
+		// matched rows are removed, so whatever is left at the end has no file on disk
+		delete(d.existing, file.FilePathAbs)
+		// dates are compared for any change rather than only newer files, so removing a CUE sheet is detected
+		if d.force || !logic.GetStringTimeFormatted(dateModified).Equal(logic.GetStringTimeFormatted(file.DateModified)) {
+			d.modifiedFiles = append(d.modifiedFiles, file)
+		} else {
+			addScanProcessed(d.ctx, 1)
+		}
+	} else {
+		d.newFiles = append(d.newFiles, file)
+	}
+
+	if len(d.newFiles)+len(d.modifiedFiles) >= scanBatchSize {
+		return d.flush()
+	}
+	return nil
+}
+
+// withCueSheetDate uses the latest CUE sheet changed time in a file's directory as its modified time if it is newer,
+// so that editing or adding a CUE sheet rescans the files next to it.
+// Files are walked directory by directory, so only the last directory is remembered.
+func (d *changeDetector) withCueSheetDate(file types.File) types.File {
+	directory := filepath.Dir(file.FilePathAbs)
+	if directory != d.cueDirectory {
+		d.cueDirectory = directory
+		d.cueChangedTime, d.cueFound = cue.GetLatestSheetChangedTime(directory)
+	}
+	if d.cueFound && d.cueChangedTime.After(logic.GetStringTimeFormatted(file.DateModified)) {
+		file.DateModified = d.cueChangedTime.Format(time.RFC3339Nano)
+	}
+	return file
+}
+
+// flush upserts the pending new and modified files
+func (d *changeDetector) flush() error {
+	defer setScanPhase(d.ctx, types.ScanPhaseWalking)
+
+	if len(d.newFiles) > 0 {
+		metadataSlice, err := upsertMetadataForFiles(d.ctx, d.scanId, d.newFiles, d.musicFolder.Id, types.ScanResultInserted, types.ScanReasonNewFile)
+		if err != nil {
+			return fmt.Errorf("upserting metadata for new files: %v", err)
+		}
+		d.recordUpserted(metadataSlice)
+		d.newFiles = d.newFiles[:0]
+	}
+
+	if len(d.modifiedFiles) > 0 {
+		metadataSlice, err := upsertMetadataForFiles(d.ctx, d.scanId, d.modifiedFiles, d.musicFolder.Id, types.ScanResultUpdated, updateReason(d.force))
+		if err != nil {
+			return fmt.Errorf("upserting metadata for existing files: %v", err)
+		}
+		d.recordUpserted(metadataSlice)
+		d.modifiedFiles = d.modifiedFiles[:0]
+	}
+
+	return nil
+}
+
+func (d *changeDetector) recordUpserted(metadataSlice []types.Metadata) {
+	if len(metadataSlice) > 0 {
+		d.changesMade = true
+	}
+	if d.collectUpserted {
+		d.upserted = append(d.upserted, metadataSlice...)
+	}
+}
+
+// deleteMissing deletes the metadata rows that were not matched by any file, unless the deletion safeguard refuses.
+// It is only called once the walk is complete, so the estimated file count is corrected here.
+func (d *changeDetector) deleteMissing() error {
+	if d.walkedFiles < d.estimatedFiles {
+		addScanTotal(d.ctx, d.walkedFiles-d.estimatedFiles)
+	}
+
+	if len(d.existing) == 0 {
+		logger.Println("Scan: No orphaned metadata rows found")
+		return nil
+	}
+
+	filePaths := make([]string, 0, len(d.existing))
+	for filePath := range d.existing {
+		filePaths = append(filePaths, filePath)
+	}
+
+	if err := d.checkDeletionSafeguard(len(filePaths)); err != nil {
+		logger.Printf("Scan: not deleting %d missing files from music folder %s: %v", len(filePaths), d.musicFolder.Path, err)
+		recordRefusedDeletions(d.ctx, d.scanId, filePaths, err)
+		d.existing = map[string]string{}
+		return nil
+	}
+
+	logger.Printf("Scan: deleting %d orphaned metadata rows", len(filePaths))
+	err := database.DeleteMetadataRows(d.ctx, filePaths)
+	if err != nil {
+		return fmt.Errorf("deleting orphan metadata rows: %v", err)
+	}
+	recordDeletedFiles(d.ctx, d.scanId, filePaths)
+
+	d.existing = map[string]string{}
+	d.changesMade = true
+	return nil
+}
+
+// checkDeletionSafeguard refuses to delete missing files if the music folder's root is missing or empty, which is what a dropped
+// network mount looks like, or if more than SCAN_MAX_DELETION_RATIO of the music folder's files are missing.
+// A scan started with allowMassDeletion skips the safeguard.
+func (d *changeDetector) checkDeletionSafeguard(deletions int) error {
+	if d.allowMassDeletion {
+		return nil
+	}
+
+	entries, err := os.ReadDir(d.musicFolder.Path)
+	if err != nil {
+		return fmt.Errorf("music folder root cannot be read: %v", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("music folder root is empty")
+	}
+
+	if deletions < minimumSafeguardedDeletions {
+		return nil
+	}
+	folderFiles, err := database.CountMusicFolderFiles(d.ctx, d.musicFolder.Id)
+	if err != nil {
+		return err
+	}
+	if float64(deletions) > float64(folderFiles)*config.ScanMaxDeletionRatio {
+		return fmt.Errorf("%d of %d files are missing, which is more than SCAN_MAX_DELETION_RATIO=%g", deletions, folderFiles, config.ScanMaxDeletionRatio)
+	}
+	return nil
+}
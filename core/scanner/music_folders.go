@@ -0,0 +1,150 @@
+package scanner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"zene/core/database"
+	"zene/core/io"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+var ErrInvalidMusicFolder = errors.New("invalid music folder")
+
+// CreateMusicFolder validates a new music folder's path and settings and inserts it.
+// The path must be an existing directory that does not contain, and is not inside, another music folder.
+func CreateMusicFolder(ctx context.Context, musicFolder types.MusicFolderSettings) (types.MusicFolderSettings, error) {
+	cleanPath, err := io.PathWithoutTraversal(musicFolder.Path)
+	if err != nil {
+		return types.MusicFolderSettings{}, fmt.Errorf("%w: %v", ErrInvalidMusicFolder, err)
+	}
+	if !filepath.IsAbs(cleanPath) {
+		return types.MusicFolderSettings{}, fmt.Errorf("%w: path %s must be absolute", ErrInvalidMusicFolder, musicFolder.Path)
+	}
+	musicFolder.Path = filepath.Clean(cleanPath)
+
+	fileInfo, err := os.Stat(musicFolder.Path)
+	if err != nil || !fileInfo.IsDir() {
+		return types.MusicFolderSettings{}, fmt.Errorf("%w: path %s is not a directory", ErrInvalidMusicFolder, musicFolder.Path)
+	}
+
+	existingFolders, err := database.GetMusicFolderSettings(ctx)
+	if err != nil {
+		return types.MusicFolderSettings{}, err
+	}
+	for _, existingFolder := range existingFolders {
+		if pathsOverlap(musicFolder.Path, existingFolder.Path) {
+			return types.MusicFolderSettings{}, fmt.Errorf("%w: path %s overlaps music folder %s", ErrInvalidMusicFolder, musicFolder.Path, existingFolder.Path)
+		}
+	}
+
+	musicFolder, err = normaliseMusicFolderSettings(musicFolder)
+	if err != nil {
+		return types.MusicFolderSettings{}, err
+	}
+	musicFolder.Name = getMusicFolderName(musicFolder.Name, musicFolder.Path)
+
+	musicFolder.Id, err = database.InsertMusicFolder(ctx, musicFolder)
+	if err != nil {
+		return types.MusicFolderSettings{}, err
+	}
+	return database.GetMusicFolderSettingsById(ctx, musicFolder.Id)
+}
+
+// UpdateMusicFolder validates and saves a music folder's name and scan settings
+func UpdateMusicFolder(ctx context.Context, musicFolder types.MusicFolderSettings) (types.MusicFolderSettings, error) {
+	musicFolder, err := normaliseMusicFolderSettings(musicFolder)
+	if err != nil {
+		return types.MusicFolderSettings{}, err
+	}
+	if strings.TrimSpace(musicFolder.Name) == "" {
+		return types.MusicFolderSettings{}, fmt.Errorf("%w: name cannot be empty", ErrInvalidMusicFolder)
+	}
+	musicFolder.Name = getMusicFolderName(musicFolder.Name, musicFolder.Path)
+
+	if err := database.UpdateMusicFolder(ctx, musicFolder); err != nil {
+		return types.MusicFolderSettings{}, err
+	}
+	return database.GetMusicFolderSettingsById(ctx, musicFolder.Id)
+}
+
+// DeleteMusicFolder removes a music folder and all of its songs from the library, without touching any files.
+// It is refused while a scan is running, as the scan could re-insert songs from the folder.
+func DeleteMusicFolder(ctx context.Context, id int) error {
+	scanStartMutex.Lock()
+	defer scanStartMutex.Unlock()
+
+	scanning, err := isScanRunning(ctx)
+	if err != nil {
+		return err
+	}
+	if scanning {
+		return ErrScanInProgress
+	}
+
+	if err := database.DeleteMusicFolder(ctx, id); err != nil {
+		return err
+	}
+
+	// rebuilding the derived tables can take a while, so the request does not wait for it
+	go func() {
+		if err := refreshDerivedTables(context.Background(), nil); err != nil {
+			logger.Printf("Error refreshing derived tables after deleting music folder %d: %v", id, err)
+		}
+	}()
+	return nil
+}
+
+// isScanRunning reports whether a scan started since boot has not completed yet
+func isScanRunning(ctx context.Context) (bool, error) {
+	latestScan, err := database.GetLatestScan(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("getting latest scan: %v", err)
+	}
+	return latestScan.Id > 0 && latestScan.CompletedDate == "" && !logic.GetStringTimeFormatted(latestScan.StartedDate).Before(logic.GetBootTime()), nil
+}
+
+// normaliseMusicFolderSettings checks the scan interval and gives every file type a leading dot in lower case
+func normaliseMusicFolderSettings(musicFolder types.MusicFolderSettings) (types.MusicFolderSettings, error) {
+	if musicFolder.ScanInterval < 0 {
+		return types.MusicFolderSettings{}, fmt.Errorf("%w: scanInterval cannot be negative", ErrInvalidMusicFolder)
+	}
+
+	fileTypes := []string{}
+	for _, fileType := range musicFolder.FileTypes {
+		fileType = strings.ToLower(strings.TrimSpace(fileType))
+		if fileType == "" {
+			continue
+		}
+		if !strings.HasPrefix(fileType, ".") {
+			fileType = "." + fileType
+		}
+		fileTypes = append(fileTypes, fileType)
+	}
+	musicFolder.FileTypes = fileTypes
+	return musicFolder, nil
+}
+
+func pathsOverlap(a string, b string) bool {
+	separator := string(filepath.Separator)
+	return a == b || strings.HasPrefix(a, b+separator) || strings.HasPrefix(b, a+separator)
+}
+
+// getMusicFolderName returns the name, or the path if the name is empty, limited to 255 characters
+func getMusicFolderName(name string, path string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = path
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
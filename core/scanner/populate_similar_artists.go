@@ -6,13 +6,15 @@ import (
 	"zene/core/database"
 	"zene/core/deezer"
 	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
 )
 
 func PopulateSimilarArtistsTable(ctx context.Context) error {
 	logger.Printf("Populating similar_artists table")
-	_, err := database.DB.ExecContext(ctx, "delete from similar_artists where artist_id not in (select distinct musicbrainz_artist_id from metadata);")
+	err := removeOrphanedSimilarArtists(ctx)
 	if err != nil {
-		return fmt.Errorf("cleaning similar_artists table: %v", err)
+		return err
 	}
 
 	// get artists to check
@@ -35,8 +37,45 @@ func PopulateSimilarArtistsTable(ctx context.Context) error {
 
 	logger.Printf("Found %d artists to check for similar artists", len(artistsToCheck))
 
+	return populateSimilarArtists(ctx, artistsToCheck)
+}
+
+// RepopulateSimilarArtistsForArtists replaces the similar_artists rows for only the given artists
+func RepopulateSimilarArtistsForArtists(ctx context.Context, artists []ArtistsToCheck) error {
+	logger.Printf("Repopulating similar_artists table for %d artists", len(artists))
+	err := removeOrphanedSimilarArtists(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, artist := range artists {
+		_, err := database.DB.ExecContext(ctx, "delete from similar_artists where artist_id = ?;", artist.MusicBrainzId)
+		if err != nil {
+			return fmt.Errorf("cleaning similar_artists for %s: %v", artist.ArtistName, err)
+		}
+	}
+
+	return populateSimilarArtists(ctx, artists)
+}
+
+func removeOrphanedSimilarArtists(ctx context.Context) error {
+	_, err := database.DB.ExecContext(ctx, "delete from similar_artists where artist_id not in (select distinct musicbrainz_artist_id from metadata);")
+	if err != nil {
+		return fmt.Errorf("cleaning similar_artists table: %v", err)
+	}
+	return nil
+}
+
+func populateSimilarArtists(ctx context.Context, artistsToCheck []ArtistsToCheck) error {
+	startScanStage(ctx, types.ScanPhaseSimilarArtists, len(artistsToCheck))
+
 	// for each artist fetch the similar artists and insert
 	for _, artist := range artistsToCheck {
+		if err := logic.CheckContext(ctx); err != nil {
+			return err
+		}
+		addScanProcessed(ctx, 1)
+
 		similarArtists, err := deezer.GetSimilarArtistNames(ctx, artist.ArtistName)
 		if err != nil {
 			logger.Printf("Error fetching similar artists for %s: %v", artist.ArtistName, err)
@@ -60,6 +99,5 @@ func PopulateSimilarArtistsTable(ctx context.Context) error {
 			}
 		}
 	}
-
 	return nil
 }
@@ -6,6 +6,8 @@ import (
 	"zene/core/database"
 	"zene/core/deezer"
 	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
 )
 
 func RepopulateTopSongsTable(ctx context.Context) error {
@@ -30,7 +32,19 @@ func RepopulateTopSongsTable(ctx context.Context) error {
 
 	logger.Printf("Found %d artists to check for top songs", len(artistsToCheck))
 
+	return RepopulateTopSongsForArtists(ctx, artistsToCheck)
+}
+
+// RepopulateTopSongsForArtists replaces the top_songs rows for only the given artists
+func RepopulateTopSongsForArtists(ctx context.Context, artistsToCheck []ArtistsToCheck) error {
+	startScanStage(ctx, types.ScanPhaseTopSongs, len(artistsToCheck))
+
 	for _, artist := range artistsToCheck {
+		if err := logic.CheckContext(ctx); err != nil {
+			return err
+		}
+		addScanProcessed(ctx, 1)
+
 		topSongs, err := deezer.GetTopSongs(ctx, artist.ArtistName, 100)
 		if err != nil {
 			logger.Printf("Error fetching top songs for %s: %v", artist.ArtistName, err)
@@ -0,0 +1,214 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+var ErrNoScanInProgress = errors.New("no scan is in progress")
+
+// progress is saved at most this often while items are processed, phase changes are always saved
+const scanProgressSaveInterval = time.Second
+
+// scanProgress is the scan running in this process, so that it can be cancelled and its progress saved as it runs.
+// Only one scan runs at a time, so there is a single instance.
+type scanProgress struct {
+	mutex        sync.Mutex
+	scanId       int
+	cancel       context.CancelFunc
+	phase        string
+	phaseStarted time.Time
+	processed    int
+	total        int
+	lastSaved    time.Time
+}
+
+var currentScan = &scanProgress{}
+
+// scanStartMutex is held from checking that no scan is running until a new scan is registered,
+// so that two callers cannot both find no scan running and start scans at the same time
+var scanStartMutex sync.Mutex
+
+// startScanProgress registers a new running scan and returns a context that is cancelled by CancelScan
+func startScanProgress(ctx context.Context, scanId int) context.Context {
+	scanCtx, cancel := context.WithCancel(ctx)
+
+	currentScan.mutex.Lock()
+	defer currentScan.mutex.Unlock()
+	currentScan.scanId = scanId
+	currentScan.cancel = cancel
+	currentScan.phase = ""
+	currentScan.processed = 0
+	currentScan.total = 0
+	return scanCtx
+}
+
+// finishScanProgress unregisters a scan once it has been marked as completed
+func finishScanProgress(scanId int) {
+	currentScan.mutex.Lock()
+	defer currentScan.mutex.Unlock()
+	if currentScan.scanId != scanId {
+		return
+	}
+	currentScan.cancel()
+	currentScan.scanId = 0
+	currentScan.cancel = nil
+}
+
+// CancelScan stops the running scan. Files that were already upserted are kept, but no metadata is deleted.
+func CancelScan() (int, error) {
+	currentScan.mutex.Lock()
+	defer currentScan.mutex.Unlock()
+	if currentScan.cancel == nil {
+		return 0, ErrNoScanInProgress
+	}
+	logger.Printf("Cancelling scan %d", currentScan.scanId)
+	currentScan.cancel()
+	return currentScan.scanId, nil
+}
+
+// setScanPhase changes the phase of the running scan, keeping its progress counters
+func setScanPhase(ctx context.Context, phase string) {
+	currentScan.mutex.Lock()
+	if currentScan.scanId == 0 || currentScan.phase == phase {
+		currentScan.mutex.Unlock()
+		return
+	}
+	currentScan.phase = phase
+	currentScan.mutex.Unlock()
+	saveScanProgress(ctx, true)
+}
+
+// startScanStage changes the phase of the running scan and resets its progress counters,
+// for phases that process different items than the phase before
+func startScanStage(ctx context.Context, phase string, total int) {
+	currentScan.mutex.Lock()
+	if currentScan.scanId == 0 {
+		currentScan.mutex.Unlock()
+		return
+	}
+	currentScan.phase = phase
+	currentScan.phaseStarted = time.Now()
+	currentScan.processed = 0
+	currentScan.total = total
+	currentScan.mutex.Unlock()
+	saveScanProgress(ctx, true)
+}
+
+// addScanTotal adds to the number of items in the current phase, which can be negative once an estimate is known to be too high
+func addScanTotal(ctx context.Context, count int) {
+	currentScan.mutex.Lock()
+	if currentScan.scanId == 0 {
+		currentScan.mutex.Unlock()
+		return
+	}
+	currentScan.total = max(currentScan.total+count, currentScan.processed)
+	currentScan.mutex.Unlock()
+	saveScanProgress(ctx, false)
+}
+
+func addScanProcessed(ctx context.Context, count int) {
+	currentScan.mutex.Lock()
+	if currentScan.scanId == 0 {
+		currentScan.mutex.Unlock()
+		return
+	}
+	currentScan.processed += count
+	currentScan.total = max(currentScan.total, currentScan.processed)
+	currentScan.mutex.Unlock()
+	saveScanProgress(ctx, false)
+}
+
+// saveScanProgress writes the progress of the running scan to the scans table,
+// unless force is false and it was saved less than scanProgressSaveInterval ago
+func saveScanProgress(ctx context.Context, force bool) {
+	currentScan.mutex.Lock()
+	if currentScan.scanId == 0 || (!force && time.Since(currentScan.lastSaved) < scanProgressSaveInterval) {
+		currentScan.mutex.Unlock()
+		return
+	}
+	currentScan.lastSaved = time.Now()
+	scanId := currentScan.scanId
+	scanRow := database.ScanRow{
+		Phase:            currentScan.phase,
+		Processed:        currentScan.processed,
+		Total:            currentScan.total,
+		PhaseStartedDate: logic.FormatTimeAsString(currentScan.phaseStarted),
+	}
+	currentScan.mutex.Unlock()
+
+	if err := database.UpdateScanPhase(context.WithoutCancel(ctx), scanId, scanRow); err != nil {
+		logger.Printf("Error saving progress of scan %d: %v", scanId, err)
+	}
+}
+
+// completeScan marks a scan as completed, cancelled or failed, and saves the final file and folder counts
+func completeScan(ctx context.Context, scanId int, scanErr error) {
+	phase := types.ScanPhaseComplete
+	if ctx.Err() != nil {
+		phase = types.ScanPhaseCancelled
+	} else if scanErr != nil {
+		phase = types.ScanPhaseFailed
+	}
+
+	// the scan context is cancelled when the scan is, but the scan still has to be marked as completed
+	ctx = context.WithoutCancel(ctx)
+
+	currentScan.mutex.Lock()
+	if currentScan.scanId == scanId {
+		currentScan.phase = phase
+	}
+	currentScan.mutex.Unlock()
+	saveScanProgress(ctx, true)
+
+	fileAndFolderCount, err := database.GetFileAndFolderCounts(ctx)
+	if err != nil {
+		logger.Printf("Error getting file and folder counts for scan %d: %v", scanId, err)
+	}
+
+	err = database.UpdateScanProgress(ctx, scanId, database.ScanRow{
+		Count:         fileAndFolderCount.FileCount,
+		FolderCount:   fileAndFolderCount.FolderCount,
+		CompletedDate: logic.GetCurrentTimeFormatted(),
+	})
+	if err != nil {
+		logger.Printf("Error updating progress of scan %d: %v", scanId, err)
+	}
+
+	finishScanProgress(scanId)
+}
+
+// GetScanStatus returns the latest scan, with an estimated completion time for the current phase
+// based on how quickly its items have been processed so far
+func GetScanStatus(ctx context.Context) (types.ScanStatus, error) {
+	latestScan, err := database.GetLatestScan(ctx)
+	if err != nil {
+		return types.ScanStatus{}, err
+	}
+
+	scanStatus := types.ScanStatus{
+		Scanning:      latestScan.CompletedDate == "",
+		Count:         latestScan.Count,
+		FolderCount:   latestScan.FolderCount,
+		StartedDate:   latestScan.StartedDate,
+		Type:          latestScan.Type,
+		CompletedDate: latestScan.CompletedDate,
+		Phase:         latestScan.Phase,
+		Processed:     latestScan.Processed,
+		Total:         latestScan.Total,
+	}
+
+	if scanStatus.Scanning && latestScan.Processed > 0 && latestScan.Total > latestScan.Processed && latestScan.PhaseStartedDate != "" {
+		elapsed := time.Since(logic.GetStringTimeFormatted(latestScan.PhaseStartedDate))
+		remaining := time.Duration(float64(elapsed) / float64(latestScan.Processed) * float64(latestScan.Total-latestScan.Processed))
+		scanStatus.EstimatedCompletionDate = logic.FormatTimeAsString(time.Now().Add(remaining))
+	}
+
+	return scanStatus, nil
+}
@@ -0,0 +1,278 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"zene/core/art"
+	"zene/core/config"
+	"zene/core/database"
+	"zene/core/io"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+var ErrScanInProgress = errors.New("a scan is already in progress")
+var ErrInvalidScanTarget = errors.New("invalid scan target")
+
+// RunPathScan runs an incremental scan of only the given files or directories.
+// Paths that no longer exist have their metadata removed, directories are walked and files are upserted if they are new or modified.
+func RunPathScan(ctx context.Context, paths []string) error {
+	scanCtx, scanId, err := startPathScan(ctx)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	startScanStage(scanCtx, types.ScanPhaseWalking, 0)
+	changesMade, upsertedMetadata, scanErr := scanPaths(scanCtx, scanId, paths, false, false)
+	importArtForMetadata(scanCtx, upsertedMetadata)
+	logger.Printf("Scan: path scan of %d paths completed in %s", len(paths), time.Since(start))
+
+	if changesMade {
+		if err := refreshDerivedTables(scanCtx, getArtistsForMetadata(upsertedMetadata)); err != nil {
+			logger.Printf("Error refreshing derived tables in RunPathScan: %v", err)
+		}
+	}
+
+	completeScan(scanCtx, scanId, scanErr)
+
+	return scanErr
+}
+
+// startPathScan inserts a watch scan and registers it as the running scan, unless a scan is already running
+func startPathScan(ctx context.Context) (context.Context, int, error) {
+	scanStartMutex.Lock()
+	defer scanStartMutex.Unlock()
+
+	scanning, err := isScanRunning(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if scanning {
+		return nil, 0, ErrScanInProgress
+	}
+
+	scanId, err := database.InsertScan(ctx, database.ScanRow{
+		StartedDate: logic.GetCurrentTimeFormatted(),
+		Type:        "watch",
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("inserting scan: %v", err)
+	}
+
+	if err := database.DeleteOldScanResults(ctx); err != nil {
+		logger.Printf("Error deleting old scan results in RunPathScan: %v", err)
+	}
+
+	return startScanProgress(ctx, int(scanId)), int(scanId), nil
+}
+
+// scanPaths upserts new or modified audio files and removes metadata for missing files at or below each path.
+// It returns the upserted metadata rows so that callers can import art for them.
+func scanPaths(ctx context.Context, scanId int, paths []string, force bool, allowMassDeletion bool) (bool, []types.Metadata, error) {
+	changesMade := false
+	upsertedMetadata := []types.Metadata{}
+
+	for _, path := range paths {
+		if err := logic.CheckContext(ctx); err != nil {
+			return changesMade, upsertedMetadata, err
+		}
+
+		// a CUE sheet applies to the audio files next to it
+		if strings.EqualFold(filepath.Ext(path), ".cue") {
+			path = filepath.Dir(path)
+		}
+
+		musicFolder, found, err := getMusicFolderForPath(ctx, path)
+		if err != nil {
+			return changesMade, upsertedMetadata, err
+		}
+		if !found {
+			logger.Printf("Scan: skipping %s, it is not inside an enabled music folder", path)
+			continue
+		}
+
+		metadataDates, err := database.SelectTrackModifiedDatesUnderPath(ctx, path)
+		if err != nil {
+			return changesMade, upsertedMetadata, fmt.Errorf("getting metadata files for %s: %v", path, err)
+		}
+
+		detector := newChangeDetector(ctx, scanId, musicFolder, force, allowMassDeletion, metadataDates, true)
+		err = walkAudioFilesForPath(ctx, path, GetAudioFileTypes(musicFolder), detector.addFile)
+		if err == nil {
+			err = detector.flush()
+		}
+		if err == nil {
+			err = detector.deleteMissing()
+		}
+		changesMade = changesMade || detector.changesMade
+		upsertedMetadata = append(upsertedMetadata, detector.upserted...)
+		if err != nil {
+			return changesMade, upsertedMetadata, fmt.Errorf("scanning %s: %v", path, err)
+		}
+	}
+
+	return changesMade, upsertedMetadata, nil
+}
+
+// walkAudioFilesForPath calls fileFunc for each audio file at or below a path, or for no files if the path no longer exists
+func walkAudioFilesForPath(ctx context.Context, path string, fileTypes []string, fileFunc func(types.File) error) error {
+	fileInfo, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if fileInfo.IsDir() {
+		return io.WalkFiles(ctx, path, fileTypes, fileFunc)
+	}
+
+	if !slices.Contains(fileTypes, filepath.Ext(path)) {
+		return nil
+	}
+
+	modTime, err := io.GetChangedTime(path)
+	if err != nil {
+		return err
+	}
+
+	return fileFunc(types.File{
+		FileName:     filepath.Base(path),
+		FilePath:     path,
+		FilePathAbs:  path,
+		DateModified: modTime.Format(time.RFC3339Nano),
+	})
+}
+
+// getMusicFolderForPath returns the enabled music folder that contains a path
+func getMusicFolderForPath(ctx context.Context, path string) (types.MusicFolderSettings, bool, error) {
+	musicFolders, err := database.GetEnabledMusicFolderSettings(ctx)
+	if err != nil {
+		return types.MusicFolderSettings{}, false, fmt.Errorf("getting music folders: %v", err)
+	}
+	for _, musicFolder := range musicFolders {
+		if path == musicFolder.Path || strings.HasPrefix(path, musicFolder.Path+string(filepath.Separator)) {
+			return musicFolder, true, nil
+		}
+	}
+	return types.MusicFolderSettings{}, false, nil
+}
+
+// GetAudioFileTypes returns the file extensions scanned in a music folder, which default to AUDIO_FILE_TYPES
+func GetAudioFileTypes(musicFolder types.MusicFolderSettings) []string {
+	if len(musicFolder.FileTypes) > 0 {
+		return musicFolder.FileTypes
+	}
+	return config.AudioFileTypes
+}
+
+// validateScanTarget checks that a targeted scan's music folder and path exist, and makes the path absolute
+func validateScanTarget(ctx context.Context, scanOptions types.ScanOptions) (types.ScanOptions, error) {
+	var musicFolder types.MusicFolderSettings
+	if scanOptions.MusicFolderId > 0 {
+		var err error
+		musicFolder, err = database.GetMusicFolderSettingsById(ctx, scanOptions.MusicFolderId)
+		if err != nil {
+			return scanOptions, fmt.Errorf("%w: %v", ErrInvalidScanTarget, err)
+		}
+		if !musicFolder.Enabled {
+			return scanOptions, fmt.Errorf("%w: music folder %d is disabled", ErrInvalidScanTarget, scanOptions.MusicFolderId)
+		}
+	}
+
+	if scanOptions.Path == "" {
+		return scanOptions, nil
+	}
+
+	cleanPath, err := io.PathWithoutTraversal(scanOptions.Path)
+	if err != nil {
+		return scanOptions, fmt.Errorf("%w: %v", ErrInvalidScanTarget, err)
+	}
+	scanOptions.Path, err = filepath.Abs(cleanPath)
+	if err != nil {
+		return scanOptions, fmt.Errorf("%w: %v", ErrInvalidScanTarget, err)
+	}
+
+	pathMusicFolder, found, err := getMusicFolderForPath(ctx, scanOptions.Path)
+	if err != nil {
+		return scanOptions, err
+	}
+	if !found {
+		return scanOptions, fmt.Errorf("%w: path %s is not inside an enabled music folder", ErrInvalidScanTarget, scanOptions.Path)
+	}
+	if scanOptions.MusicFolderId > 0 && musicFolder.Id != pathMusicFolder.Id {
+		return scanOptions, fmt.Errorf("%w: path %s is not inside music folder %d", ErrInvalidScanTarget, scanOptions.Path, scanOptions.MusicFolderId)
+	}
+
+	return scanOptions, nil
+}
+
+func getArtistsForMusicDir(ctx context.Context, musicDir string) ([]ArtistsToCheck, error) {
+	artists, err := database.SelectArtistsForMusicDir(ctx, musicDir)
+	if err != nil {
+		return nil, err
+	}
+	artistsToCheck := []ArtistsToCheck{}
+	seenArtists := map[string]bool{}
+	for _, artist := range artists {
+		if !seenArtists[artist.MusicBrainzArtistID] {
+			seenArtists[artist.MusicBrainzArtistID] = true
+			artistsToCheck = append(artistsToCheck, ArtistsToCheck{MusicBrainzId: artist.MusicBrainzArtistID, ArtistName: artist.Artist})
+		}
+	}
+	return artistsToCheck, nil
+}
+
+func getArtistsForMetadata(metadataSlice []types.Metadata) []ArtistsToCheck {
+	artistsToCheck := []ArtistsToCheck{}
+	seenArtists := map[string]bool{}
+	for _, metadata := range metadataSlice {
+		if !seenArtists[metadata.MusicBrainzArtistID] {
+			seenArtists[metadata.MusicBrainzArtistID] = true
+			artistsToCheck = append(artistsToCheck, ArtistsToCheck{MusicBrainzId: metadata.MusicBrainzArtistID, ArtistName: metadata.Artist})
+		}
+	}
+	return artistsToCheck
+}
+
+// importArtForMetadata imports album and artist art for the albums and artists in a set of upserted metadata rows
+func importArtForMetadata(ctx context.Context, metadataSlice []types.Metadata) {
+	if len(metadataSlice) == 0 {
+		return
+	}
+
+	albumIds := map[string]bool{}
+	artistIds := map[string]bool{}
+	for _, metadata := range metadataSlice {
+		albumIds[metadata.MusicBrainzAlbumID] = true
+		artistIds[metadata.MusicBrainzArtistID] = true
+	}
+	startScanStage(ctx, types.ScanPhaseArt, len(albumIds)+len(artistIds))
+
+	importedAlbums := map[string]bool{}
+	importedArtists := map[string]bool{}
+
+	for _, metadata := range metadataSlice {
+		if err := logic.CheckContext(ctx); err != nil {
+			return
+		}
+		if !importedAlbums[metadata.MusicBrainzAlbumID] {
+			importedAlbums[metadata.MusicBrainzAlbumID] = true
+			art.ImportArtForAlbum(ctx, metadata.MusicBrainzAlbumID, metadata.Album, metadata.AlbumArtist)
+			addScanProcessed(ctx, 1)
+		}
+		if !importedArtists[metadata.MusicBrainzArtistID] {
+			importedArtists[metadata.MusicBrainzArtistID] = true
+			art.ImportArtForArtist(ctx, metadata.MusicBrainzArtistID, metadata.Artist, metadata.Artist == metadata.AlbumArtist)
+			addScanProcessed(ctx, 1)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"zene/core/database"
+	"zene/core/ffprobe"
+	"zene/core/logger"
+	"zene/core/types"
+)
+
+// getScanResultForMetadataError maps an error from reading a file's metadata to a scan result status and reason
+func getScanResultForMetadataError(err error) (string, string) {
+	switch {
+	case errors.Is(err, ffprobe.ErrMissingMusicBrainzTrackId):
+		return types.ScanResultSkipped, types.ScanReasonMissingMusicBrainzTrack
+	case errors.Is(err, ffprobe.ErrMissingMusicBrainzAlbumId):
+		return types.ScanResultSkipped, types.ScanReasonMissingMusicBrainzAlbum
+	case errors.Is(err, ffprobe.ErrMissingMusicBrainzArtistId):
+		return types.ScanResultSkipped, types.ScanReasonMissingMusicBrainzArtist
+	case errors.Is(err, ffprobe.ErrFfprobe):
+		return types.ScanResultFailed, types.ScanReasonFfprobeError
+	case errors.Is(err, ffprobe.ErrMusicBrainzLookup):
+		return types.ScanResultFailed, types.ScanReasonMusicBrainzError
+	default:
+		return types.ScanResultFailed, types.ScanReasonMetadataError
+	}
+}
+
+func updateReason(force bool) string {
+	if force {
+		return types.ScanReasonForcedRescan
+	}
+	return types.ScanReasonFileModified
+}
+
+// recordScanResults saves per-file scan results, logging rather than failing the scan on error
+func recordScanResults(ctx context.Context, scanResults []database.ScanResultRow) {
+	if err := database.InsertScanResults(ctx, scanResults); err != nil {
+		logger.Printf("Error recording scan results: %v", err)
+	}
+}
+
+func recordDeletedFiles(ctx context.Context, scanId int, filePaths []string) {
+	recordMissingFiles(ctx, scanId, filePaths, types.ScanResultDeleted, types.ScanReasonFileNotFound, "")
+}
+
+// recordRefusedDeletions records the missing files that the deletion safeguard kept in the library
+func recordRefusedDeletions(ctx context.Context, scanId int, filePaths []string, refusal error) {
+	recordMissingFiles(ctx, scanId, filePaths, types.ScanResultSkipped, types.ScanReasonDeletionRefused, refusal.Error())
+}
+
+func recordMissingFiles(ctx context.Context, scanId int, filePaths []string, status string, reason string, detail string) {
+	scanResults := make([]database.ScanResultRow, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		scanResults = append(scanResults, database.ScanResultRow{ScanId: scanId, FilePath: filePath, Status: status, Reason: reason, Detail: detail})
+	}
+	recordScanResults(ctx, scanResults)
+}
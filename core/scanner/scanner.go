@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sync"
 	"time"
@@ -15,11 +16,15 @@ import (
 	"zene/core/io"
 	"zene/core/logger"
 	"zene/core/logic"
+	"zene/core/lyrics"
 	"zene/core/musicbrainz"
 	"zene/core/types"
 )
 
 func RunScan(ctx context.Context, scanOptions types.ScanOptions) (types.ScanStatus, error) {
+	scanStartMutex.Lock()
+	defer scanStartMutex.Unlock()
+
 	latestScan, err := database.GetLatestScan(ctx)
 	if err != nil && err != sql.ErrNoRows {
 		logger.Printf("Error getting latest scan: %v", err)
@@ -30,6 +35,11 @@ func RunScan(ctx context.Context, scanOptions types.ScanOptions) (types.ScanStat
 		}, err
 	}
 
+	scanOptions, err = validateScanTarget(ctx, scanOptions)
+	if err != nil {
+		return types.ScanStatus{}, err
+	}
+
 	var scanType string
 	if scanOptions.Force {
 		scanType = "full"
@@ -55,6 +65,15 @@ func RunScan(ctx context.Context, scanOptions types.ScanOptions) (types.ScanStat
 			if err != nil {
 				logger.Printf("Error updating orphaned scan progress in RunScan: %v", err)
 			}
+			err = database.UpdateScanPhase(ctx, latestScan.Id, database.ScanRow{
+				Phase:            types.ScanPhaseFailed,
+				Processed:        latestScan.Processed,
+				Total:            latestScan.Total,
+				PhaseStartedDate: latestScan.PhaseStartedDate,
+			})
+			if err != nil {
+				logger.Printf("Error updating orphaned scan phase in RunScan: %v", err)
+			}
 		} else {
 			return types.ScanStatus{
 				Scanning:      true,
@@ -78,7 +97,12 @@ func RunScan(ctx context.Context, scanOptions types.ScanOptions) (types.ScanStat
 		return types.ScanStatus{}, err
 	}
 
-	go scanMusicDirs(ctx, int(scanId), scanOptions)
+	if err := database.DeleteOldScanResults(ctx); err != nil {
+		logger.Printf("Error deleting old scan results in RunScan: %v", err)
+	}
+
+	scanCtx := startScanProgress(ctx, int(scanId))
+	go scanMusicDirs(scanCtx, int(scanId), scanOptions)
 
 	return types.ScanStatus{
 		Scanning:    true,
@@ -90,76 +114,147 @@ func RunScan(ctx context.Context, scanOptions types.ScanOptions) (types.ScanStat
 }
 
 func scanMusicDirs(ctx context.Context, scanId int, scanOptions types.ScanOptions) {
-	scanUpdate := database.ScanRow{
-		Count:         0,
-		FolderCount:   0,
-		CompletedDate: logic.GetCurrentTimeFormatted(),
+	start := time.Now()
+	err := runScanPhases(ctx, scanId, scanOptions)
+	if err != nil {
+		logger.Printf("Error in scan %d: %v", scanId, err)
+	}
+	completeScan(ctx, scanId, err)
+	logger.Printf("Scan completed in %s", time.Since(start))
+}
+
+// runScanPhases scans the files in each music folder, then imports art and refreshes the derived tables if anything changed.
+// It stops at the first error, including the scan being cancelled.
+func runScanPhases(ctx context.Context, scanId int, scanOptions types.ScanOptions) error {
+	if err := logic.CheckContext(ctx); err != nil {
+		return err
 	}
 
-	if ctx.Err() != nil {
-		err := database.UpdateScanProgress(ctx, scanId, scanUpdate)
+	if scanOptions.Path != "" {
+		startScanStage(ctx, types.ScanPhaseWalking, 0)
+		changesMade, scopedArtists, err := scanSubPath(ctx, scanId, scanOptions)
 		if err != nil {
-			logger.Printf("Error updating scan progress for cancelled scan in scanMusicDirs: %v", err)
+			return fmt.Errorf("scanning path %s: %w", scanOptions.Path, err)
+		}
+		if changesMade {
+			return refreshDerivedTables(ctx, scopedArtists)
 		}
-		logger.Printf("scan was cancelled, context error in scanMusicDirs: %v", ctx.Err())
-		return
+		return nil
 	}
 
-	start := time.Now()
-	changesMade := false
+	var musicFolders []types.MusicFolderSettings
 	var err error
+	if scanOptions.MusicFolderId > 0 {
+		musicFolder, err := database.GetMusicFolderSettingsById(ctx, scanOptions.MusicFolderId)
+		if err != nil {
+			return fmt.Errorf("getting music folder %d: %w", scanOptions.MusicFolderId, err)
+		}
+		musicFolders = []types.MusicFolderSettings{musicFolder}
+	} else {
+		musicFolders, err = database.GetEnabledMusicFolderSettings(ctx)
+		if err != nil {
+			return fmt.Errorf("getting music folders: %w", err)
+		}
+	}
 
-	defer func() { logger.Printf("Scan completed in %s", time.Since(start)) }()
-
-	for _, musicDir := range config.MusicDirs {
-		changesMade, err = scanMusicDir(ctx, musicDir, scanOptions)
+	startScanStage(ctx, types.ScanPhaseWalking, 0)
+	changesMade := false
+	for _, musicFolder := range musicFolders {
+		dirChangesMade, err := scanMusicDir(ctx, scanId, musicFolder, scanOptions)
+		changesMade = changesMade || dirChangesMade
 		if err != nil {
-			logger.Printf("Error scanning music directory %s in scanMusicDirs: %v", musicDir, err)
-			return
+			return fmt.Errorf("scanning music directory %s: %w", musicFolder.Path, err)
+		}
+	}
+
+	if scanOptions.IncludeArt {
+		startScanStage(ctx, types.ScanPhaseArt, 0)
+		for _, musicFolder := range musicFolders {
+			if err := getArtworkForMusicDir(ctx, musicFolder.Path); err != nil {
+				return err
+			}
 		}
+	} else {
+		logger.Printf("Scan: skipping album and artist artwork retrieval")
 	}
 
-	if changesMade {
-		musicbrainz.ClearMbCache()
+	if !changesMade {
+		return nil
+	}
 
-		err := database.RepopulateGenreCountsTable(ctx)
+	var scopedArtists []ArtistsToCheck
+	if scanOptions.MusicFolderId > 0 {
+		scopedArtists, err = getArtistsForMusicDir(ctx, musicFolders[0].Path)
 		if err != nil {
-			logger.Printf("Error repopulating genre counts table in scanMusicDirs: %v", err)
-			return
+			return fmt.Errorf("getting artists for music directory %s: %w", musicFolders[0].Path, err)
 		}
+	}
+	return refreshDerivedTables(ctx, scopedArtists)
+}
 
+// refreshDerivedTables rebuilds the tables that are derived from metadata after a scan has made changes.
+// If scopedArtists is nil the whole library is refreshed, otherwise similar artists and top songs are only refreshed for those artists.
+func refreshDerivedTables(ctx context.Context, scopedArtists []ArtistsToCheck) error {
+	musicbrainz.ClearMbCache()
+
+	err := database.RepopulateGenreCountsTable(ctx)
+	if err != nil {
+		return fmt.Errorf("repopulating genre counts table: %v", err)
+	}
+
+	if scopedArtists == nil {
 		err = PopulateSimilarArtistsTable(ctx)
 		if err != nil {
-			logger.Printf("Error populating similar artists table scanMusicDirs: %v", err)
-			return
+			return fmt.Errorf("populating similar artists table: %v", err)
 		}
 
 		err = RepopulateTopSongsTable(ctx)
 		if err != nil {
-			logger.Printf("Error repopulating top songs table in scanMusicDirs: %v", err)
-			return
+			return fmt.Errorf("repopulating top songs table: %v", err)
 		}
+		return nil
 	}
 
-	fileAndFolderCount, err := database.GetFileAndFolderCounts(ctx)
+	err = RepopulateSimilarArtistsForArtists(ctx, scopedArtists)
+	if err != nil {
+		return fmt.Errorf("repopulating similar artists table: %v", err)
+	}
+
+	err = RepopulateTopSongsForArtists(ctx, scopedArtists)
+	if err != nil {
+		return fmt.Errorf("repopulating top songs table: %v", err)
+	}
+
+	return nil
+}
+
+// scanSubPath scans a single directory subtree, then imports art for it if requested.
+// It returns the artists found in the subtree so derived tables can be refreshed for just those artists.
+func scanSubPath(ctx context.Context, scanId int, scanOptions types.ScanOptions) (bool, []ArtistsToCheck, error) {
+	logger.Printf("Starting targeted scan of path %s", scanOptions.Path)
+
+	changesMade, _, err := scanPaths(ctx, scanId, []string{scanOptions.Path}, scanOptions.Force, scanOptions.AllowMassDeletion)
 	if err != nil {
-		logger.Printf("Error getting file and folder counts in scanMusicDirs: %v", err)
-		return
+		return changesMade, nil, err
 	}
 
-	scanUpdate = database.ScanRow{
-		Count:         fileAndFolderCount.FileCount,
-		FolderCount:   fileAndFolderCount.FolderCount,
-		CompletedDate: logic.GetCurrentTimeFormatted(),
+	pathMetadata, err := database.SelectAlbumsAndArtistsUnderPath(ctx, scanOptions.Path)
+	if err != nil {
+		return changesMade, nil, fmt.Errorf("getting albums and artists under path: %v", err)
 	}
 
-	if err := database.UpdateScanProgress(ctx, scanId, scanUpdate); err != nil {
-		logger.Printf("Error updating scan progress in scanMusicDirs: %v", err)
+	if scanOptions.IncludeArt {
+		importArtForMetadata(ctx, pathMetadata)
+	} else {
+		logger.Printf("Scan: skipping album and artist artwork retrieval for path %s", scanOptions.Path)
 	}
+
+	return changesMade, getArtistsForMetadata(pathMetadata), nil
 }
 
-func scanMusicDir(ctx context.Context, musicDir string, scanOptions types.ScanOptions) (bool, error) {
+func scanMusicDir(ctx context.Context, scanId int, musicFolder types.MusicFolderSettings, scanOptions types.ScanOptions) (bool, error) {
 	changesMade := false
+	musicDir := musicFolder.Path
 
 	if scanOptions.Force {
 		logger.Printf("Starting forced scan of music dir %s", musicDir)
@@ -172,120 +267,73 @@ func scanMusicDir(ctx context.Context, musicDir string, scanOptions types.ScanOp
 		logger.Printf("This scan will not reset album and artist artwork for music dir %s", musicDir)
 	}
 
-	// get a list of files from the filesystem
-	logger.Printf("Scan: Getting list of audio files in the filesystem")
-	audioFiles, err := getAudioFiles(ctx, musicDir)
-	if err != nil {
-		return false, fmt.Errorf("scanning music directory for audio files: %v", err)
-	}
-
-	// get a current list of files from the metadata table
+	// get an indexed view of the current files in the metadata table
 	logger.Printf("Scan: Getting list of metadata in the database")
-	metadataFiles, err := database.SelectTrackFilesForScanner(ctx, musicDir)
+	metadataDates, err := database.SelectTrackModifiedDatesForScanner(ctx, musicDir)
 	if err != nil {
 		return false, fmt.Errorf("scanning database for metadata files: %v", err)
 	}
 
-	// for each file found, either insert or update a metadata row
-
-	existingMetadataToUpdate := []types.File{}
-	newMetadataToInsert := []types.File{}
+	detector := newChangeDetector(ctx, scanId, musicFolder, scanOptions.Force, scanOptions.AllowMassDeletion, metadataDates, false)
 
-	for _, audioFile := range audioFiles {
-		matchingMetadata, err := logic.FilterArray(metadataFiles, func(mf types.File) (bool, error) {
-			return mf.FilePathAbs == audioFile.FilePathAbs, nil
-		})
-		if err != nil {
-			logger.Printf("Error filtering metadata files for audio file %s: %v", audioFile.FilePathAbs, err)
-			return false, fmt.Errorf("filtering metadata files: %v", err)
-		}
-		if len(matchingMetadata) > 0 {
-			// Update existing metadata..
-			metadataDateModified := matchingMetadata[0].DateModified
-			if scanOptions.Force || logic.GetStringTimeFormatted(metadataDateModified).Before(logic.GetStringTimeFormatted(audioFile.DateModified)) {
-				// if the file's modified date is more recent than in the database
-				existingMetadataToUpdate = append(existingMetadataToUpdate, audioFile)
-			}
-		} else {
-			// Insert new metadata
-			newMetadataToInsert = append(newMetadataToInsert, audioFile)
-		}
+	// a missing root is most likely an unmounted drive, so the other music folders are still scanned
+	// and the deletion safeguard keeps this folder's songs in the library
+	if _, err := os.Stat(musicDir); err != nil {
+		logger.Printf("Scan: music dir %s cannot be read: %v", musicDir, err)
+		err := detector.deleteMissing()
+		return detector.changesMade, err
 	}
 
-	if len(existingMetadataToUpdate) > 0 {
-		err = upsertMetadataForFiles(ctx, existingMetadataToUpdate)
-		if err != nil {
-			return false, fmt.Errorf("upserting metadata for existing files: %v", err)
-		}
-		changesMade = true
+	// stream files from the filesystem, inserting or updating metadata rows in batches as they are found
+	logger.Printf("Scan: Walking audio files in the filesystem")
+	err = io.WalkFiles(ctx, musicDir, GetAudioFileTypes(musicFolder), detector.addFile)
+	if err != nil {
+		// do not delete anything if the walk did not complete, as unvisited files would look missing
+		return detector.changesMade, fmt.Errorf("scanning music directory for audio files: %v", err)
 	}
-
-	if len(newMetadataToInsert) > 0 {
-		err = upsertMetadataForFiles(ctx, newMetadataToInsert)
-		if err != nil {
-			return false, fmt.Errorf("upserting metadata for new files: %v", err)
-		}
-		changesMade = true
+	err = detector.flush()
+	if err != nil {
+		return detector.changesMade, err
 	}
 
-	// for each metadata row that does not exist in the files list, delete that row
+	// for each metadata row that was not matched by a file, delete that row
 	logger.Printf("Scan: fetching orphaned metadata rows to delete")
-	metadataRowsToDelete := logic.FilesInSliceOnceNotInSliceTwo(metadataFiles, audioFiles)
-	fileCount := 0
-	var filepaths []string
-	for _, row := range metadataRowsToDelete {
-		filepaths = append(filepaths, row.FilePathAbs)
+	err = detector.deleteMissing()
+	if err != nil {
+		return detector.changesMade, err
 	}
+	changesMade = detector.changesMade
 
-	if len(filepaths) == 0 {
-		logger.Println("Scan: No orphaned metadata rows found")
-	} else {
-		logger.Printf("Scan: deleting orphaned metadata rows")
-		err = database.DeleteMetadataRows(ctx, filepaths)
-		if err != nil {
-			return false, fmt.Errorf("deleting orphan metadata rows: %v", err)
-		} else {
-			fileCount += len(filepaths)
-		}
-		if fileCount > 0 {
-			changesMade = true
-			logger.Printf("Scan: %d orphaned metadata rows removed", fileCount)
-		}
+	if err := database.UpdateMusicFolderLastScanned(ctx, musicFolder.Id); err != nil {
+		logger.Printf("Error updating last scanned time for music dir %s: %v", musicDir, err)
 	}
 
-	if !scanOptions.IncludeArt {
-		logger.Printf("Scan: skipping album and artist artwork retrieval for music dir %s", musicDir)
-		return changesMade, nil
-	}
+	return changesMade, nil
+}
 
-	err = getAlbumArtworkForMusicDir(ctx, musicDir)
+// getArtworkForMusicDir imports album and artist art for every album and artist in a music directory
+func getArtworkForMusicDir(ctx context.Context, musicDir string) error {
+	err := getAlbumArtworkForMusicDir(ctx, musicDir)
 	if err != nil {
-		return changesMade, fmt.Errorf("getting album artwork for music dir %s: %v", musicDir, err)
+		return fmt.Errorf("getting album artwork for music dir %s: %w", musicDir, err)
 	}
 
 	err = getArtistArtworkForMusicDir(ctx, musicDir)
 	if err != nil {
-		return changesMade, fmt.Errorf("getting artist artwork for music dir %s: %v", musicDir, err)
+		return fmt.Errorf("getting artist artwork for music dir %s: %w", musicDir, err)
 	}
-
-	return changesMade, nil
-}
-
-func getAudioFiles(ctx context.Context, musicDir string) ([]types.File, error) {
-	audioFiles, err := io.GetFiles(ctx, musicDir, config.AudioFileTypes)
-	if err != nil {
-		return []types.File{}, fmt.Errorf("getting slice of audio files from the filesystem: %v", err)
-	}
-	return audioFiles, nil
+	return nil
 }
 
-func upsertMetadataForFiles(ctx context.Context, files []types.File) error {
+func upsertMetadataForFiles(ctx context.Context, scanId int, files []types.File, musicFolderId int, status string, reason string) ([]types.Metadata, error) {
 	metadataSlice := make([]types.Metadata, 0, len(files))
+	scanResults := make([]database.ScanResultRow, 0, len(files))
 	metadataMutex := &sync.Mutex{}
 	wg := &sync.WaitGroup{}
 	bufferedChannel := make(chan struct{}, config.FfprobeConcurrentProcesses)
 
 	logger.Printf("Scan: Fetching metadata tags for %d files", len(files))
+	setScanPhase(ctx, types.ScanPhaseProbing)
 
 	for _, file := range files {
 		file := file // capture range variable
@@ -295,62 +343,94 @@ func upsertMetadataForFiles(ctx context.Context, files []types.File) error {
 		go func() {
 			defer wg.Done()
 			defer func() { <-bufferedChannel }()
+			defer addScanProcessed(ctx, 1)
 
-			fileMetadata, err := ffprobe.GetMetadata(ctx, file.FilePathAbs)
+			fileMetadatas, err := ffprobe.GetMetadataWithCueTracks(ctx, file.FilePathAbs)
+			if err != nil && ctx.Err() != nil {
+				// the scan was cancelled, so the file was not probed rather than failing
+				return
+			}
 			if err != nil {
 				logger.Printf("Skipping %s: error retrieving metadata from file: %v", file.FilePathAbs, err)
+				resultStatus, resultReason := getScanResultForMetadataError(err)
+				metadataMutex.Lock()
+				scanResults = append(scanResults, database.ScanResultRow{ScanId: scanId, FilePath: file.FilePathAbs, Status: resultStatus, Reason: resultReason, Detail: err.Error()})
+				metadataMutex.Unlock()
 				return
 			}
 
-			metadata := types.Metadata{
-				FilePath:            file.FilePathAbs,
-				FileName:            filepath.Base(file.FilePathAbs),
-				DateAdded:           logic.GetCurrentTimeFormatted(),
-				DateModified:        file.DateModified,
-				Format:              fileMetadata.Format,
-				Duration:            fileMetadata.Duration,
-				Size:                fileMetadata.Size,
-				Bitrate:             fileMetadata.Bitrate,
-				Title:               fileMetadata.Title,
-				Artist:              fileMetadata.Artist,
-				Album:               fileMetadata.Album,
-				AlbumArtist:         fileMetadata.AlbumArtist,
-				Genre:               fileMetadata.Genre,
-				TrackNumber:         fileMetadata.TrackNumber,
-				TotalTracks:         fileMetadata.TotalTracks,
-				DiscNumber:          fileMetadata.DiscNumber,
-				TotalDiscs:          fileMetadata.TotalDiscs,
-				ReleaseDate:         fileMetadata.ReleaseDate,
-				MusicBrainzArtistID: fileMetadata.MusicBrainzArtistID,
-				MusicBrainzAlbumID:  fileMetadata.MusicBrainzAlbumID,
-				MusicBrainzTrackID:  fileMetadata.MusicBrainzTrackID,
-				Label:               fileMetadata.Label,
-				Codec:               fileMetadata.Codec,
-				BitDepth:            fileMetadata.BitDepth,
-				SampleRate:          fileMetadata.SampleRate,
-				Channels:            fileMetadata.Channels,
+			// files split by a CUE sheet have one metadata row per track
+			fileMetadataRows := make([]types.Metadata, 0, len(fileMetadatas))
+			for _, fileMetadata := range fileMetadatas {
+				metadata := types.Metadata{
+					FilePath:            fileMetadata.FilePath,
+					FileName:            filepath.Base(fileMetadata.FilePath),
+					DateAdded:           logic.GetCurrentTimeFormatted(),
+					DateModified:        file.DateModified,
+					Format:              fileMetadata.Format,
+					Duration:            fileMetadata.Duration,
+					Size:                fileMetadata.Size,
+					Bitrate:             fileMetadata.Bitrate,
+					Title:               fileMetadata.Title,
+					Artist:              fileMetadata.Artist,
+					Album:               fileMetadata.Album,
+					AlbumArtist:         fileMetadata.AlbumArtist,
+					Genre:               fileMetadata.Genre,
+					TrackNumber:         fileMetadata.TrackNumber,
+					TotalTracks:         fileMetadata.TotalTracks,
+					DiscNumber:          fileMetadata.DiscNumber,
+					TotalDiscs:          fileMetadata.TotalDiscs,
+					ReleaseDate:         fileMetadata.ReleaseDate,
+					MusicBrainzArtistID: fileMetadata.MusicBrainzArtistID,
+					MusicBrainzAlbumID:  fileMetadata.MusicBrainzAlbumID,
+					MusicBrainzTrackID:  fileMetadata.MusicBrainzTrackID,
+					Label:               fileMetadata.Label,
+					Codec:               fileMetadata.Codec,
+					BitDepth:            fileMetadata.BitDepth,
+					SampleRate:          fileMetadata.SampleRate,
+					Channels:            fileMetadata.Channels,
+					Credits:             fileMetadata.Credits,
+					ReplayGain:          fileMetadata.ReplayGain,
+					Lyrics:              fileMetadata.Lyrics,
+					SourceFilePath:      fileMetadata.SourceFilePath,
+					CueStart:            fileMetadata.CueStart,
+					CueEnd:              fileMetadata.CueEnd,
+					MusicFolderId:       musicFolderId,
+				}
+				if fileMetadata.SourceFilePath == "" {
+					metadata.Lyrics = append(metadata.Lyrics, lyrics.GetSidecarLyrics(file.FilePathAbs)...)
+				}
+				fileMetadataRows = append(fileMetadataRows, metadata)
 			}
 
 			metadataMutex.Lock()
-			metadataSlice = append(metadataSlice, metadata)
+			metadataSlice = append(metadataSlice, fileMetadataRows...)
+			scanResults = append(scanResults, database.ScanResultRow{ScanId: scanId, FilePath: file.FilePathAbs, Status: status, Reason: reason})
 			metadataMutex.Unlock()
 		}()
 	}
 
 	wg.Wait()
 
+	if err := logic.CheckContext(ctx); err != nil {
+		return nil, err
+	}
+
 	logger.Printf("Scan: Upserting metadata for %d files", len(metadataSlice))
+	setScanPhase(ctx, types.ScanPhaseUpserting)
 	err := database.UpsertMetadataRows(ctx, metadataSlice)
 	if err != nil {
-		return fmt.Errorf("upserting metadata rows: %v", err)
+		return nil, fmt.Errorf("upserting metadata rows: %v", err)
 	}
 
+	recordScanResults(ctx, scanResults)
+
 	if len(metadataSlice) > 0 {
 		logger.Printf("Scan: metadata tags for %d files upserted", len(metadataSlice))
 	} else {
 		logger.Printf("Scan: no metadata tags found for files")
 	}
-	return nil
+	return metadataSlice, nil
 }
 
 func getAlbumArtworkForMusicDir(ctx context.Context, musicDir string) error {
@@ -360,8 +440,13 @@ func getAlbumArtworkForMusicDir(ctx context.Context, musicDir string) error {
 		logger.Printf("Error fetching albums from database: %v", err)
 		return err
 	}
+	addScanTotal(ctx, len(albums))
 	for _, album := range albums {
+		if err := logic.CheckContext(ctx); err != nil {
+			return err
+		}
 		art.ImportArtForAlbum(ctx, album.MusicBrainzAlbumID, album.Album, album.Artist)
+		addScanProcessed(ctx, 1)
 	}
 	return nil
 }
@@ -375,8 +460,13 @@ func getArtistArtworkForMusicDir(ctx context.Context, musicDir string) error {
 		logger.Printf("Error fetching artists from database: %v", err)
 		return err
 	}
+	addScanTotal(ctx, len(artists))
 	for _, artist := range artists {
+		if err := logic.CheckContext(ctx); err != nil {
+			return err
+		}
 		art.ImportArtForArtist(ctx, artist.MusicBrainzArtistID, artist.Artist, artist.IsAlbumArtist)
+		addScanProcessed(ctx, 1)
 	}
 
 	return nil
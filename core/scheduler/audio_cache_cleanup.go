@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 	"zene/core/config"
 	"zene/core/database"
+	"zene/core/ffmpeg"
 	"zene/core/io"
 	"zene/core/logger"
 	"zene/core/logic"
@@ -20,6 +23,8 @@ func maxCacheSizeBytes() int64 {
 }
 
 func cleanupAudioCache(ctx context.Context) {
+	removeStaleHlsDirectories()
+
 	err := removeOrphanCache(ctx)
 	if err != nil {
 		logger.Printf("Error removing orphan cache files: %v", err)
@@ -123,6 +128,11 @@ func removeOrphanCache(ctx context.Context) error {
 		return fmt.Errorf("getting audio cache files from filesystem: %v", err)
 	}
 
+	// files in subdirectories are HLS segments that are still being transcoded, not cached files
+	cacheFiles = slices.DeleteFunc(cacheFiles, func(file types.File) bool {
+		return filepath.Dir(file.FilePath) != filepath.Clean(config.AudioCacheFolder)
+	})
+
 	audioCacheRows, err := database.SelectAllAudioCacheEntries(ctx)
 	if err != nil {
 		return fmt.Errorf("getting audio cache files from database: %v", err)
@@ -176,3 +186,28 @@ func removeOrphanCache(ctx context.Context) error {
 
 	return nil
 }
+
+// removeStaleHlsDirectories deletes the directories of HLS transcodes that were running when the server stopped
+func removeStaleHlsDirectories() {
+	entries, err := os.ReadDir(config.AudioCacheFolder)
+	if err != nil {
+		logger.Printf("Failed to read audio cache directory: %v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), ffmpeg.HlsTempDirectoryPrefix) {
+			continue
+		}
+		// a recent directory may be a transcode that is still running
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < time.Hour {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(config.AudioCacheFolder, entry.Name())); err != nil {
+			logger.Printf("Failed to delete stale HLS directory %s: %v", entry.Name(), err)
+			continue
+		}
+		logger.Printf("Deleted stale HLS directory: %s", entry.Name())
+	}
+}
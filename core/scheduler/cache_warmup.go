@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"zene/core/config"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/types"
+)
+
+// cacheWarmupFullness is how full the warm-up fills the audio cache, leaving the rest of AUDIO_CACHE_MAX_MB
+// to the transcodes users play so that warming up doesn't make the cleanup evict them
+const cacheWarmupFullness = 0.9
+
+// warmupTrack is a track to pre-transcode for a user, with the options their player streams it with
+type warmupTrack struct {
+	user    types.User
+	trackId string
+	options types.TranscodeOptions
+}
+
+// warmAudioCache pre-transcodes the tracks users are likely to play next into the audio cache, in the format and bit rate
+// each user's player last streamed with: the next tracks in their saved play queue first, then the tracks of their most
+// recently starred albums, then those of the most recently added albums. It stops when the cache is nearly full or
+// the server is too busy to give it a transcode slot, and leaves AUDIO_CACHE_MAX_MB to the cleanup's eviction.
+func warmAudioCache(ctx context.Context) error {
+	users, err := database.GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("getting users for cache warm-up: %v", err)
+	}
+	players, err := database.GetPlayers(ctx)
+	if err != nil {
+		return fmt.Errorf("getting players for cache warm-up: %v", err)
+	}
+
+	var playqueueTracks, starredTracks, recentTracks []warmupTrack
+	for _, user := range users {
+		if !user.StreamRole {
+			continue
+		}
+		options, found := getWarmupOptions(ctx, user, players)
+		if !found {
+			continue
+		}
+
+		userCtx := context.WithValue(ctx, types.ContextKey("userId"), user.Id)
+		playqueue, err := database.GetPlayqueue(userCtx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Printf("Cache warm-up: error getting play queue of %s: %v", user.Username, err)
+		}
+		start := min(max(playqueue.CurrentIndex, 0), len(playqueue.TrackIds))
+		end := min(start+config.CacheWarmupTracks, len(playqueue.TrackIds))
+		playqueueTracks = appendWarmupTracks(playqueueTracks, user, options, playqueue.TrackIds[start:end])
+
+		if config.CacheWarmupAlbums == 0 {
+			continue
+		}
+		trackIds, err := database.SelectStarredAlbumTrackIds(ctx, user.Id, config.CacheWarmupAlbums)
+		if err != nil {
+			logger.Printf("Cache warm-up: error getting starred albums of %s: %v", user.Username, err)
+		}
+		starredTracks = appendWarmupTracks(starredTracks, user, options, trackIds)
+
+		trackIds, err = database.SelectRecentAlbumTrackIds(ctx, user.Id, config.CacheWarmupAlbums)
+		if err != nil {
+			logger.Printf("Cache warm-up: error getting recently added albums for %s: %v", user.Username, err)
+		}
+		recentTracks = appendWarmupTracks(recentTracks, user, options, trackIds)
+	}
+
+	tracks := slices.Concat(playqueueTracks, starredTracks, recentTracks)
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	var transcoded atomic.Int64
+	var stopped atomic.Bool
+	queue := make(chan warmupTrack)
+	var wg sync.WaitGroup
+	for range config.CacheWarmupConcurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for track := range queue {
+				if stopped.Load() {
+					continue
+				}
+				if isAudioCacheFull() {
+					logger.Printf("Cache warm-up: the audio cache is nearly full, stopping")
+					stopped.Store(true)
+					continue
+				}
+				done, err := warmTrack(ctx, track)
+				if errors.Is(err, ffmpeg.ErrTranscodeQueueTimeout) {
+					logger.Printf("Cache warm-up: the server is busy transcoding, stopping")
+					stopped.Store(true)
+					continue
+				}
+				if err != nil {
+					logger.Printf("Cache warm-up: error transcoding %s for %s: %v", track.trackId, track.user.Username, err)
+					continue
+				}
+				if done {
+					transcoded.Add(1)
+				}
+			}
+		}()
+	}
+
+	for _, track := range tracks {
+		if ctx.Err() != nil || stopped.Load() {
+			break
+		}
+		queue <- track
+	}
+	close(queue)
+	wg.Wait()
+
+	if transcoded.Load() > 0 {
+		logger.Printf("Cache warm-up: transcoded %d of %d tracks", transcoded.Load(), len(tracks))
+		cleanupAudioCache(ctx)
+	}
+	return ctx.Err()
+}
+
+func appendWarmupTracks(tracks []warmupTrack, user types.User, options types.TranscodeOptions, trackIds []string) []warmupTrack {
+	for _, trackId := range trackIds {
+		tracks = append(tracks, warmupTrack{user: user, trackId: trackId, options: options})
+	}
+	return tracks
+}
+
+// getWarmupOptions returns the format and bit rate a user's most recently seen player last streamed with, so that the
+// warmed transcodes are the ones stream looks for in the cache. Users who have not streamed from a player since
+// players started recording their streams, or whose player last streamed a raw file, have nothing to warm up.
+func getWarmupOptions(ctx context.Context, user types.User, players []types.Player) (types.TranscodeOptions, bool) {
+	index := slices.IndexFunc(players, func(p types.Player) bool { return p.UserId == user.Id && p.LastFormat != "" })
+	if index < 0 {
+		return types.TranscodeOptions{}, false
+	}
+	player := players[index]
+
+	format := player.LastFormat
+	if format == "raw" {
+		return types.TranscodeOptions{}, false
+	}
+
+	maxBitRate := player.LastMaxBitRate
+	if user.MaxBitRate > 0 && user.MaxBitRate < maxBitRate {
+		maxBitRate = user.MaxBitRate
+	}
+
+	// the DSP filters stream uses when a request doesn't pass them, so that the warmed transcodes have the same cache keys
+	settings, err := database.GetPlayerTranscodeSettings(ctx, user.Id, player)
+	if err != nil {
+		logger.Printf("Cache warm-up: error getting transcode settings of %s: %v", user.Username, err)
+	}
+
+	return types.TranscodeOptions{
+		Format:        format,
+		MaxBitRate:    maxBitRate,
+		MaxSampleRate: settings.MaxSampleRate,
+		Normalization: settings.Normalization,
+		Channels:      settings.Channels,
+		MaxBitDepth:   settings.MaxBitDepth,
+	}, true
+}
+
+// warmTrack pre-transcodes the version of a track a user's player would stream, and reports whether it was transcoded
+func warmTrack(ctx context.Context, track warmupTrack) (bool, error) {
+	userCtx := context.WithValue(ctx, types.ContextKey("userId"), track.user.Id)
+	mediaFile, err := database.GetMediaFile(userCtx, track.trackId, types.VersionPreference{
+		Format:     track.options.Format,
+		MaxBitRate: track.options.MaxBitRate,
+	})
+	if err != nil {
+		return false, fmt.Errorf("getting media file: %v", err)
+	}
+
+	// the transcode is not counted against the user's transcodes, as it runs without the user in its context
+	return ffmpeg.WarmCache(ctx, mediaFile, track.trackId, track.options)
+}
+
+// isAudioCacheFull reports whether the audio cache has reached the size the warm-up fills it to
+func isAudioCacheFull() bool {
+	entries, err := os.ReadDir(config.AudioCacheFolder)
+	if err != nil {
+		logger.Printf("Cache warm-up: failed to read audio cache directory: %v", err)
+		return true
+	}
+
+	var totalSize int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			totalSize += info.Size()
+		}
+	}
+	return float64(totalSize) >= float64(maxCacheSizeBytes())*cacheWarmupFullness
+}
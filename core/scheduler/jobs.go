@@ -0,0 +1,249 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"zene/core/config"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/logic"
+	"zene/core/types"
+)
+
+var ErrJobNotFound = errors.New("scheduled job not found")
+var ErrJobRunning = errors.New("scheduled job is already running")
+
+// job is a registered background job. Each job runs in its own goroutine, so a job never overlaps itself.
+type job struct {
+	name         string
+	description  string
+	runAtStartup bool
+	run          func(ctx context.Context) error
+
+	mutex              sync.Mutex
+	scheduleExpression string
+	schedule           schedule
+	paused             bool
+	running            bool
+	lastRun            string
+	lastDuration       int64
+	lastError          string
+	nextRun            time.Time
+	// trigger runs the job now, wake makes the job loop recalculate its next run after it is paused or resumed
+	trigger chan struct{}
+	wake    chan struct{}
+}
+
+// jobs is the job registry, in the order the jobs were registered
+var jobs = []*job{}
+var jobsMutex sync.RWMutex
+
+// registerJob adds a job to the registry and starts it. The default schedule can be overridden with SCHEDULE_<NAME>,
+// and the paused state and last run are restored from the database.
+func registerJob(ctx context.Context, name string, description string, defaultSchedule string, runAtStartup bool, run func(ctx context.Context) error, states map[string]types.ScheduledJob) {
+	scheduleExpression := defaultSchedule
+	if configured, found := config.JobSchedules[name]; found {
+		scheduleExpression = configured
+	}
+	jobSchedule, err := parseSchedule(scheduleExpression)
+	if err != nil {
+		logger.Printf("Scheduler: invalid schedule for job %s, using %s: %v", name, defaultSchedule, err)
+		scheduleExpression = defaultSchedule
+		jobSchedule, err = parseSchedule(defaultSchedule)
+		if err != nil {
+			logger.Printf("Scheduler: invalid default schedule for job %s: %v", name, err)
+			return
+		}
+	}
+
+	newJob := &job{
+		name:               name,
+		description:        description,
+		runAtStartup:       runAtStartup,
+		run:                run,
+		scheduleExpression: scheduleExpression,
+		schedule:           jobSchedule,
+		trigger:            make(chan struct{}, 1),
+		wake:               make(chan struct{}, 1),
+	}
+	if state, found := states[name]; found {
+		newJob.paused = state.Paused
+		newJob.lastRun = state.LastRun
+		newJob.lastDuration = state.LastDuration
+		newJob.lastError = state.LastError
+	}
+
+	jobsMutex.Lock()
+	jobs = append(jobs, newJob)
+	jobsMutex.Unlock()
+
+	if newJob.paused {
+		logger.Printf("Scheduler: job %s is paused", name)
+	} else {
+		logger.Printf("Scheduler: starting job %s with schedule %s", name, scheduleExpression)
+	}
+	go newJob.loop(ctx)
+}
+
+func (j *job) loop(ctx context.Context) {
+	j.mutex.Lock()
+	runNow := j.runAtStartup && !j.paused
+	j.mutex.Unlock()
+	if runNow {
+		j.execute(ctx)
+	}
+
+	for {
+		j.mutex.Lock()
+		j.nextRun = time.Time{}
+		if !j.paused {
+			j.nextRun = j.schedule.Next(time.Now())
+		}
+		nextRun := j.nextRun
+		j.mutex.Unlock()
+
+		var timer *time.Timer
+		var timerChannel <-chan time.Time
+		if !nextRun.IsZero() {
+			timer = time.NewTimer(time.Until(nextRun))
+			timerChannel = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Printf("Scheduler: stopping job %s", j.name)
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-timerChannel:
+			j.execute(ctx)
+		case <-j.trigger:
+			j.execute(ctx)
+		case <-j.wake:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (j *job) execute(ctx context.Context) {
+	j.mutex.Lock()
+	j.running = true
+	j.mutex.Unlock()
+
+	start := time.Now()
+	err := j.run(ctx)
+	duration := time.Since(start).Milliseconds()
+
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+		logger.Printf("Scheduler: job %s failed: %v", j.name, err)
+	}
+
+	j.mutex.Lock()
+	j.running = false
+	j.lastRun = logic.FormatTimeAsString(start)
+	j.lastDuration = duration
+	j.lastError = lastError
+	lastRun := j.lastRun
+	j.mutex.Unlock()
+
+	if err := database.UpdateScheduledJobRun(context.WithoutCancel(ctx), j.name, lastRun, duration, lastError); err != nil {
+		logger.Printf("Scheduler: error saving last run of job %s: %v", j.name, err)
+	}
+}
+
+func (j *job) toScheduledJob() types.ScheduledJob {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	scheduledJob := types.ScheduledJob{
+		Name:         j.name,
+		Description:  j.description,
+		Schedule:     j.scheduleExpression,
+		Paused:       j.paused,
+		Running:      j.running,
+		LastRun:      j.lastRun,
+		LastDuration: j.lastDuration,
+		LastError:    j.lastError,
+	}
+	if !j.nextRun.IsZero() {
+		scheduledJob.NextRun = logic.FormatTimeAsString(j.nextRun)
+	}
+	return scheduledJob
+}
+
+func getJob(name string) (*job, error) {
+	jobsMutex.RLock()
+	defer jobsMutex.RUnlock()
+	for _, j := range jobs {
+		if j.name == name {
+			return j, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+}
+
+// GetJobs returns every registered job with its schedule and last run
+func GetJobs() []types.ScheduledJob {
+	jobsMutex.RLock()
+	defer jobsMutex.RUnlock()
+	scheduledJobs := make([]types.ScheduledJob, 0, len(jobs))
+	for _, j := range jobs {
+		scheduledJobs = append(scheduledJobs, j.toScheduledJob())
+	}
+	return scheduledJobs
+}
+
+// RunJob runs a job now, even if it is paused
+func RunJob(name string) (types.ScheduledJob, error) {
+	j, err := getJob(name)
+	if err != nil {
+		return types.ScheduledJob{}, err
+	}
+
+	j.mutex.Lock()
+	running := j.running
+	j.mutex.Unlock()
+	if running {
+		return j.toScheduledJob(), ErrJobRunning
+	}
+
+	select {
+	case j.trigger <- struct{}{}:
+	default:
+		// a run has already been requested
+	}
+	return j.toScheduledJob(), nil
+}
+
+// SetJobPaused pauses or resumes a job's schedule. A paused job stays paused after a restart, but can still be run with RunJob.
+func SetJobPaused(ctx context.Context, name string, paused bool) (types.ScheduledJob, error) {
+	j, err := getJob(name)
+	if err != nil {
+		return types.ScheduledJob{}, err
+	}
+
+	if err := database.UpdateScheduledJobPaused(ctx, name, paused); err != nil {
+		return types.ScheduledJob{}, err
+	}
+
+	j.mutex.Lock()
+	j.paused = paused
+	j.nextRun = time.Time{}
+	if !paused {
+		j.nextRun = j.schedule.Next(time.Now())
+	}
+	j.mutex.Unlock()
+
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+	return j.toScheduledJob(), nil
+}
@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"context"
+	"math"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/types"
+)
+
+// ReplayGain 2.0 gains are relative to -18 LUFS
+const replayGainReferenceLoudness = -18.0
+
+type trackLoudness struct {
+	Loudness float64
+	Peak     *float64
+	Duration float64
+}
+
+// analyseReplayGain measures the loudness of tracks without ReplayGain tags and stores their track and album gain
+func analyseReplayGain(ctx context.Context) {
+	albumIds, err := database.SelectAlbumsMissingReplayGain(ctx)
+	if err != nil {
+		logger.Printf("Error selecting albums missing ReplayGain: %v", err)
+		return
+	}
+
+	if len(albumIds) == 0 {
+		return
+	}
+
+	logger.Printf("ReplayGain analysis: analysing %d albums", len(albumIds))
+	tracksAnalysed := 0
+	for _, albumId := range albumIds {
+		if ctx.Err() != nil {
+			return
+		}
+		tracksAnalysed += analyseAlbumReplayGain(ctx, albumId)
+	}
+	logger.Printf("ReplayGain analysis: analysed %d tracks", tracksAnalysed)
+}
+
+func analyseAlbumReplayGain(ctx context.Context, albumId string) int {
+	tracks, err := database.SelectReplayGainTracksForAlbum(ctx, albumId)
+	if err != nil {
+		logger.Printf("Error selecting ReplayGain tracks for album %s: %v", albumId, err)
+		return 0
+	}
+
+	// tracks with ReplayGain tags are included in the album gain, using the loudness their track gain implies
+	measured := []trackLoudness{}
+	analysed := map[string]trackLoudness{}
+	for _, track := range tracks {
+		switch track.ReplayGain.Source {
+		case "":
+			if ctx.Err() != nil {
+				return len(analysed)
+			}
+			loudness, truePeak, err := ffmpeg.GetLoudness(ctx, track.MediaFile)
+			if err != nil || math.IsInf(loudness, 0) || math.IsNaN(loudness) {
+				logger.Printf("Error measuring loudness of %s: %v", track.FilePath, err)
+				if ctx.Err() == nil {
+					err = database.UpdateReplayGain(ctx, track.FilePath, types.ReplayGain{Source: types.ReplayGainSourceFailed})
+					if err != nil {
+						logger.Printf("Error updating ReplayGain for %s: %v", track.FilePath, err)
+					}
+				}
+				continue
+			}
+			peak := math.Pow(10, truePeak/20)
+			result := trackLoudness{Loudness: loudness, Peak: &peak, Duration: track.Duration}
+			analysed[track.FilePath] = result
+			measured = append(measured, result)
+		case types.ReplayGainSourceFailed:
+			continue
+		default:
+			if track.ReplayGain.TrackGain != nil {
+				measured = append(measured, trackLoudness{
+					Loudness: replayGainReferenceLoudness - *track.ReplayGain.TrackGain,
+					Peak:     track.ReplayGain.TrackPeak,
+					Duration: track.Duration,
+				})
+			}
+		}
+	}
+
+	if len(analysed) == 0 {
+		return 0
+	}
+
+	albumGain := roundGain(replayGainReferenceLoudness - getAlbumLoudness(measured))
+	var albumPeak *float64
+	for _, track := range measured {
+		if track.Peak != nil && (albumPeak == nil || *track.Peak > *albumPeak) {
+			albumPeak = track.Peak
+		}
+	}
+
+	for filePath, track := range analysed {
+		trackGain := roundGain(replayGainReferenceLoudness - track.Loudness)
+		err := database.UpdateReplayGain(ctx, filePath, types.ReplayGain{
+			TrackGain: &trackGain,
+			TrackPeak: track.Peak,
+			AlbumGain: &albumGain,
+			AlbumPeak: albumPeak,
+			Source:    types.ReplayGainSourceAnalysis,
+		})
+		if err != nil {
+			logger.Printf("Error updating ReplayGain for %s: %v", filePath, err)
+		}
+	}
+
+	return len(analysed)
+}
+
+// getAlbumLoudness averages the loudness of an album's tracks by their energy, weighted by duration
+func getAlbumLoudness(tracks []trackLoudness) float64 {
+	var energy, duration float64
+	for _, track := range tracks {
+		trackDuration := track.Duration
+		if trackDuration <= 0 {
+			trackDuration = 1
+		}
+		energy += trackDuration * math.Pow(10, track.Loudness/10)
+		duration += trackDuration
+	}
+	return 10 * math.Log10(energy/duration)
+}
+
+func roundGain(gain float64) float64 {
+	return math.Round(gain*100) / 100
+}
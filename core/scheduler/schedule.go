@@ -0,0 +1,195 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule returns the next time a job should run after a given time, or the zero time if it never runs on its own
+type schedule interface {
+	Next(after time.Time) time.Time
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (s intervalSchedule) Next(after time.Time) time.Time {
+	return after.Add(s.interval)
+}
+
+// manualSchedule is for jobs that only run at startup and when they are triggered
+type manualSchedule struct{}
+
+func (manualSchedule) Next(after time.Time) time.Time {
+	return time.Time{}
+}
+
+// cronSchedule is a standard five field cron expression, in local time.
+// Each field is a bit set of the values it matches.
+type cronSchedule struct {
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// when both day fields are restricted, a day matches if either field matches, as in cron
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+type cronField struct {
+	min int
+	max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week, 0 and 7 are both Sunday
+}
+
+var cronAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// parseSchedule parses a schedule, which can be a five field cron expression, an alias like @daily,
+// an interval like "@every 30m" or "30m", or "@manual" for jobs that only run at startup and when triggered
+func parseSchedule(expression string) (schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "@manual" {
+		return manualSchedule{}, nil
+	}
+	if alias, found := cronAliases[expression]; found {
+		expression = alias
+	}
+
+	// anything without spaces is an interval, as a cron expression always has five fields
+	intervalString, isEvery := strings.CutPrefix(expression, "@every ")
+	if isEvery || !strings.Contains(expression, " ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(intervalString))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %v", intervalString, err)
+		}
+		if interval < time.Minute {
+			return nil, fmt.Errorf("interval %s must be at least one minute", interval)
+		}
+		return intervalSchedule{interval: interval}, nil
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", expression, len(cronFields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		fieldBits, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %v", expression, err)
+		}
+		bits[i] = fieldBits
+	}
+
+	// Sunday can be written as 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return cronSchedule{
+		minutes:       bits[0],
+		hours:         bits[1],
+		daysOfMonth:   bits[2],
+		months:        bits[3],
+		daysOfWeek:    bits[4],
+		anyDayOfMonth: strings.HasPrefix(fields[2], "*"),
+		anyDayOfWeek:  strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField parses a comma separated list of *, values and ranges, each with an optional /step
+func parseCronField(field string, limits cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepString, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepString)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepString)
+			}
+		}
+
+		start, end := limits.min, limits.max
+		if rangePart != "*" {
+			startString, endString, isRange := strings.Cut(rangePart, "-")
+			var err error
+			start, err = strconv.Atoi(startString)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", startString)
+			}
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(endString)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q", endString)
+				}
+			} else if hasStep {
+				end = limits.max
+			}
+		}
+		if start < limits.min || end > limits.max || start > end {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, limits.min, limits.max)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func (s cronSchedule) Next(after time.Time) time.Time {
+	next := after.Truncate(time.Minute).Add(time.Minute)
+	// every schedule matches at least once in five years, including the 29th of February
+	limit := next.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+		if s.months&(1<<int(next.Month())) == 0 {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if s.hours&(1<<next.Hour()) == 0 {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if s.minutes&(1<<next.Minute()) == 0 {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+	return time.Time{}
+}
+
+func (s cronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.daysOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := s.daysOfWeek&(1<<int(t.Weekday())) != 0
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
@@ -2,195 +2,124 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
 	"time"
+	"zene/core/config"
 	"zene/core/database"
 	"zene/core/logger"
+	"zene/core/logic"
 	"zene/core/scanner"
 	"zene/core/types"
 )
 
 func Initialise(ctx context.Context) {
-	startAudioCacheCleanupRoutine(ctx)
-	startNowPlayingCleanupRoutine(ctx)
-	startAlbumArtCleanupRoutine(ctx)
-	startArtistArtCleanupRoutine(ctx)
-	startOrphanedPlaylistEntriesCleanupRoutine(ctx)
-	startPodcastCleanupRoutine(ctx)
-	startPodcastEpisodeRefreshRoutine(ctx)
-	startScanScheduleRoutine(ctx)
-}
-
-func startAudioCacheCleanupRoutine(ctx context.Context) {
-	logger.Println("Scheduler: starting audio cache cleanup routine")
-	cleanupAudioCache(ctx)
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Println("Scheduler: stopping audio cache cleanup routine")
-				return
-			case <-ticker.C:
-				cleanupAudioCache(ctx)
-			}
-		}
-	}()
-}
-
-func startNowPlayingCleanupRoutine(ctx context.Context) {
-	logger.Println("Scheduler: starting now playing cleanup routine")
-	err := database.CleanupNowPlaying(ctx)
+	states, err := database.GetScheduledJobStates(ctx)
 	if err != nil {
-		logger.Printf("Error cleaning up now playing: %v", err)
+		logger.Printf("Scheduler: error getting scheduled job states: %v", err)
 	}
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Println("Scheduler: stopping now playing cleanup routine")
-				return
-			case <-ticker.C:
-				err := database.CleanupNowPlaying(ctx)
-				if err != nil {
-					logger.Printf("Error cleaning up now playing: %v", err)
-				}
-			}
-		}
-	}()
-}
 
-func startOrphanedPlaylistEntriesCleanupRoutine(ctx context.Context) {
-	logger.Println("Scheduler: starting orphaned playlist entries cleanup routine")
-	err := database.RemoveOrphanedPlaylistEntries(ctx)
-	if err != nil {
-		logger.Printf("Error removing orphaned playlist entries: %v", err)
+	registerJob(ctx, "audio_cache_cleanup", "Removes transcoded audio that is stale, orphaned or over the cache size limit", "@every 1h", true,
+		func(ctx context.Context) error {
+			cleanupAudioCache(ctx)
+			return nil
+		}, states)
+	if config.CacheWarmupConcurrency > 0 {
+		registerJob(ctx, "audio_cache_warmup", "Pre-transcodes the next tracks in play queues and starred and recently added albums into the audio cache", "@every 1h", false,
+			warmAudioCache, states)
+	} else {
+		logger.Println("Scheduler: audio cache warm-up is disabled")
+	}
+	registerJob(ctx, "now_playing_cleanup", "Removes now playing entries older than ten minutes", "@every 5m", true,
+		database.CleanupNowPlaying, states)
+	registerJob(ctx, "album_art_cleanup", "Removes album art for albums that are no longer in the library", "@every 1h", true,
+		func(ctx context.Context) error {
+			cleanupAlbumArt(ctx)
+			return nil
+		}, states)
+	registerJob(ctx, "artist_art_cleanup", "Removes artist art for artists that are no longer in the library", "@every 1h", true,
+		func(ctx context.Context) error {
+			cleanupArtistArt(ctx)
+			return nil
+		}, states)
+	registerJob(ctx, "waveform_cleanup", "Removes waveforms for tracks that are no longer in the library", "@every 1h", true,
+		func(ctx context.Context) error {
+			cleanupWaveforms(ctx)
+			return nil
+		}, states)
+	if config.WaveformPrecompute {
+		registerJob(ctx, "waveform_precompute", "Computes the waveforms of tracks added or changed by scans", "@every 5m", false,
+			precomputeWaveforms, states)
+	}
+	registerJob(ctx, "orphaned_playlist_entries_cleanup", "Removes playlist entries for songs that are no longer in the library", "@every 1h", true,
+		database.RemoveOrphanedPlaylistEntries, states)
+	registerJob(ctx, "deleted_tracks_cleanup", "Forgets tracks deleted more than DELETED_TRACK_RETENTION_DAYS ago and removes their playlist entries", "@every 1h", true,
+		database.PurgeDeletedTracks, states)
+	registerJob(ctx, "podcast_cleanup", "Removes podcast channels and episodes whose files are missing", "@every 1h", true,
+		func(ctx context.Context) error {
+			cleanupMissingPodcasts(ctx)
+			return nil
+		}, states)
+	registerJob(ctx, "podcast_episode_refresh", "Fetches new episodes for every podcast channel", "@every 1h", true,
+		func(ctx context.Context) error {
+			fetchNewPodcastEpisodes(ctx)
+			return nil
+		}, states)
+	registerJob(ctx, "library_scan", "Starts an incremental scan of every enabled music folder", "@manual", true,
+		runLibraryScan, states)
+	registerJob(ctx, "music_folder_scans", "Scans each enabled music folder once its scan interval has passed", "@every 1m", false,
+		runDueMusicFolderScan, states)
+
+	if config.ReplayGainAnalysis {
+		registerJob(ctx, "replay_gain_analysis", "Measures ReplayGain for tracks and albums without ReplayGain tags", "@every 1h", true,
+			func(ctx context.Context) error {
+				analyseReplayGain(ctx)
+				return nil
+			}, states)
+	} else {
+		logger.Println("Scheduler: ReplayGain analysis is disabled")
 	}
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Println("Scheduler: stopping orphaned playlist entries cleanup routine")
-				return
-			case <-ticker.C:
-				err := database.RemoveOrphanedPlaylistEntries(ctx)
-				if err != nil {
-					logger.Printf("Error removing orphaned playlist entries: %v", err)
-				}
-			}
-		}
-	}()
 }
 
-func startPodcastCleanupRoutine(ctx context.Context) {
-	logger.Println("Scheduler: starting podcast cleanup routine")
-	cleanupMissingPodcasts(ctx)
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Println("Scheduler: stopping podcast cleanup routine")
-				return
-			case <-ticker.C:
-				cleanupMissingPodcasts(ctx)
-			}
-		}
-	}()
+func runLibraryScan(ctx context.Context) error {
+	_, err := scanner.RunScan(ctx, types.ScanOptions{
+		Force:      false,
+		IncludeArt: true,
+	})
+	return err
 }
 
-func startPodcastEpisodeRefreshRoutine(ctx context.Context) {
-	logger.Println("Scheduler: starting podcast episode refresh routine")
-	go func() {
-		fetchNewPodcastEpisodes(ctx)
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
+// runDueMusicFolderScan scans the first enabled music folder whose scan interval has passed since it was last scanned.
+// Only one scan can run at a time, so any other due folders are scanned on a later run.
+func runDueMusicFolderScan(ctx context.Context) error {
+	musicFolders, err := database.GetEnabledMusicFolderSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("getting music folders for scheduled scan: %v", err)
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Println("Scheduler: stopping podcast episode refresh routine")
-				return
-			case <-ticker.C:
-				fetchNewPodcastEpisodes(ctx)
-			}
+	for _, musicFolder := range musicFolders {
+		if musicFolder.ScanInterval <= 0 {
+			continue
 		}
-	}()
-}
-
-func startAlbumArtCleanupRoutine(ctx context.Context) {
-	logger.Println("Scheduler: starting album art cleanup routine")
-	cleanupAlbumArt(ctx)
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Println("Scheduler: stopping album art cleanup routine")
-				return
-			case <-ticker.C:
-				cleanupAlbumArt(ctx)
+		if musicFolder.LastScanned != "" {
+			nextScan := logic.GetStringTimeFormatted(musicFolder.LastScanned).Add(time.Duration(musicFolder.ScanInterval) * time.Minute)
+			if time.Now().Before(nextScan) {
+				continue
 			}
 		}
-	}()
-}
-
-func startArtistArtCleanupRoutine(ctx context.Context) {
-	logger.Println("Scheduler: starting artist art cleanup routine")
-	cleanupArtistArt(ctx)
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
 
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Println("Scheduler: stopping artist art cleanup routine")
-				return
-			case <-ticker.C:
-				cleanupArtistArt(ctx)
-			}
+		scanStatus, err := scanner.RunScan(ctx, types.ScanOptions{
+			Force:         false,
+			IncludeArt:    true,
+			MusicFolderId: musicFolder.Id,
+		})
+		if err != nil && scanStatus.Scanning {
+			// the folder is scanned on a later run, once the running scan has finished
+			return nil
 		}
-	}()
-}
-
-func startScanScheduleRoutine(ctx context.Context) {
-	logger.Println("Scheduler: starting scan schedule routine")
-
-	scanOptions := types.ScanOptions{
-		Force:      false,
-		IncludeArt: true,
-	}
-	_, err := scanner.RunScan(ctx, scanOptions)
-	if err != nil {
-		logger.Printf("Error starting scan schedule routine: %v", err)
-	}
-	go func() {
-		ticker := time.NewTicker(45 * time.Minute)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				logger.Println("Scheduler: stopping album art cleanup routine")
-				return
-			case <-ticker.C:
-				_, err := scanner.RunScan(ctx, scanOptions)
-				if err != nil {
-					logger.Printf("Error running scan: %v", err)
-				}
-			}
+		if err != nil {
+			return fmt.Errorf("scanning music folder %s: %v", musicFolder.Path, err)
 		}
-	}()
+		return nil
+	}
+	return nil
 }
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"zene/core/config"
+	"zene/core/database"
+	"zene/core/io"
+	"zene/core/logger"
+	"zene/core/waveform"
+)
+
+// cleanupWaveforms deletes cached waveforms of track versions that are no longer in the library,
+// and temporary files left behind by waveforms that were being computed when the server stopped
+func cleanupWaveforms(ctx context.Context) {
+
+	tracks, err := database.SelectWaveformTracks(ctx)
+	if err != nil {
+		logger.Printf("Error selecting tracks: %v", err)
+		return
+	}
+
+	waveformFiles, err := io.GetFiles(ctx, config.WaveformFolder, []string{waveform.FileExtension, ".tmp"})
+	if err != nil {
+		logger.Printf("Error getting waveform files: %v", err)
+		return
+	}
+
+	if len(waveformFiles) == 0 {
+		return
+	}
+
+	cacheIds := []string{}
+	for _, track := range tracks {
+		cacheIds = append(cacheIds, waveform.GetCacheId(track.TrackId, track.MediaFile))
+	}
+	slices.Sort(cacheIds)
+
+	waveformFilesDeleted := 0
+	for _, file := range waveformFiles {
+		fileName := filepath.Base(file.FilePath)
+		cacheId, isWaveform := strings.CutSuffix(fileName, waveform.FileExtension)
+		if isWaveform {
+			if _, found := slices.BinarySearch(cacheIds, cacheId); found {
+				continue
+			}
+		} else if info, err := os.Stat(file.FilePath); err != nil || time.Since(info.ModTime()) < time.Hour {
+			// a recent temporary file may be a waveform that is still being computed
+			continue
+		}
+
+		logger.Printf("Deleting orphaned waveform file: %s", fileName)
+		err = io.DeleteFile(file.FilePath)
+		if err != nil {
+			logger.Printf("Error deleting orphaned waveform file %s: %v", fileName, err)
+		} else {
+			waveformFilesDeleted++
+		}
+	}
+
+	if waveformFilesDeleted > 0 {
+		logger.Printf("Waveform cleanup: deleted %d orphaned waveform files.", waveformFilesDeleted)
+	}
+}
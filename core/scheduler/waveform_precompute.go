@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"zene/core/database"
+	"zene/core/ffmpeg"
+	"zene/core/logger"
+	"zene/core/waveform"
+)
+
+// lastWaveformScanId is the last completed scan whose tracks have had their waveforms precomputed. It is 0 after a restart,
+// so the tracks of the retained scan results are checked again, and only those without an up to date waveform are computed.
+var lastWaveformScanId int
+
+// precomputeWaveforms computes the waveforms of the tracks inserted or updated by the scans completed since it last ran,
+// so that it never holds up a scan. Tracks whose waveform can't be computed are logged and skipped, and when the server is
+// too busy to give it a transcode slot it stops without moving past the scans, so that their remaining tracks are computed next time.
+func precomputeWaveforms(ctx context.Context) error {
+	latestScan, err := database.GetLatestCompletedScan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("getting latest completed scan: %v", err)
+	}
+	if latestScan.Id <= lastWaveformScanId {
+		return nil
+	}
+
+	tracks, err := database.SelectWaveformTracksForScans(ctx, lastWaveformScanId, latestScan.Id)
+	if err != nil {
+		return fmt.Errorf("selecting tracks to compute waveforms for: %v", err)
+	}
+
+	computed := 0
+	stopped := false
+	for _, track := range tracks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		done, err := waveform.Precompute(ctx, track.TrackId, track.MediaFile)
+		if errors.Is(err, ffmpeg.ErrTranscodeQueueTimeout) {
+			// the scans are looked at again on the next run, which skips the tracks that were computed
+			logger.Printf("Waveform precompute: the server is busy transcoding, stopping until the next run")
+			stopped = true
+			break
+		}
+		if err != nil {
+			logger.Printf("Waveform precompute: error computing waveform of %s: %v", track.MediaFile.FilePath, err)
+		}
+		if done {
+			computed++
+		}
+	}
+	if !stopped {
+		lastWaveformScanId = latestScan.Id
+	}
+
+	if computed > 0 {
+		logger.Printf("Waveform precompute: computed %d waveforms", computed)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"zene/core/types"
+)
+
+const (
+	flacBlockStreamInfo    = 0
+	flacBlockVorbisComment = 4
+)
+
+func readFlac(reader io.ReadSeeker) (types.FfprobeStandard, error) {
+	if err := skipId3v2(reader); err != nil {
+		return types.FfprobeStandard{}, err
+	}
+
+	marker, err := readBytes(reader, 4)
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+	if !bytes.Equal(marker, []byte("fLaC")) {
+		return types.FfprobeStandard{}, fmt.Errorf("%w: missing fLaC marker", ErrInvalidFile)
+	}
+
+	result := types.FfprobeStandard{
+		FormatName: "flac",
+		Codec:      "flac",
+		Tags:       map[string]string{},
+	}
+
+	for lastBlock := false; !lastBlock; {
+		header, err := readBytes(reader, 4)
+		if err != nil {
+			return types.FfprobeStandard{}, err
+		}
+		lastBlock = header[0]&0x80 != 0
+		blockType := header[0] & 0x7f
+		blockLength := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+
+		switch blockType {
+		case flacBlockStreamInfo:
+			data, err := readBytes(reader, int(blockLength))
+			if err != nil {
+				return types.FfprobeStandard{}, err
+			}
+			if err := parseFlacStreamInfo(data, &result); err != nil {
+				return types.FfprobeStandard{}, err
+			}
+		case flacBlockVorbisComment:
+			data, err := readBytes(reader, int(blockLength))
+			if err != nil {
+				return types.FfprobeStandard{}, err
+			}
+			if err := parseVorbisComments(data, result.Tags); err != nil {
+				return types.FfprobeStandard{}, err
+			}
+		default:
+			// pictures, seek tables and padding are not needed
+			if _, err := reader.Seek(blockLength, io.SeekCurrent); err != nil {
+				return types.FfprobeStandard{}, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// parseFlacStreamInfo reads the sample rate, channels, bit depth and duration from a STREAMINFO block
+func parseFlacStreamInfo(data []byte, result *types.FfprobeStandard) error {
+	if len(data) < 18 {
+		return fmt.Errorf("%w: STREAMINFO block too short", ErrInvalidFile)
+	}
+	// 20 bits sample rate, 3 bits channels - 1, 5 bits bits per sample - 1, 36 bits total samples
+	packed := binary.BigEndian.Uint64(data[10:18])
+	sampleRate := int(packed >> 44)
+	channels := int((packed>>41)&0x07) + 1
+	bitDepth := int((packed>>36)&0x1f) + 1
+	totalSamples := packed & 0xfffffffff
+
+	result.SampleRate = sampleRate
+	result.Channels = channels
+	result.BitDepth = bitDepth
+	if sampleRate > 0 && totalSamples > 0 {
+		result.Duration = formatDuration(float64(totalSamples) / float64(sampleRate))
+	}
+	return nil
+}
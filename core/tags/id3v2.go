@@ -0,0 +1,439 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const id3v2HeaderLength = 10
+
+// id3v2 text frames that ffprobe renames, for v2.3/v2.4 and v2.2 frame ids.
+// Conductor, remixer and lyricist frames are named for their roles, which ffprobe does not do.
+var id3v2FrameKeys = map[string]string{
+	"TALB": "album",
+	"TCOM": "composer",
+	"TCON": "genre",
+	"TCOP": "copyright",
+	"TENC": "encoded_by",
+	"TIT1": "grouping",
+	"TIT2": "title",
+	"TLAN": "language",
+	"TPE1": "artist",
+	"TPE2": "album_artist",
+	"TPE3": "conductor",
+	"TPE4": "remixer",
+	"TEXT": "lyricist",
+	"TPOS": "disc",
+	"TPUB": "publisher",
+	"TRCK": "track",
+	"TSSE": "encoder",
+	"TSOA": "album-sort",
+	"TSOP": "artist-sort",
+	"TSOT": "title-sort",
+	"TDRC": "date",
+	"TDRL": "date",
+	"TYER": "date",
+	"TDOR": "originaldate",
+	"TORY": "originaldate",
+	"TT1":  "grouping",
+	"TT2":  "title",
+	"TAL":  "album",
+	"TCM":  "composer",
+	"TCO":  "genre",
+	"TP1":  "artist",
+	"TP2":  "album_artist",
+	"TP3":  "conductor",
+	"TP4":  "remixer",
+	"TXT":  "lyricist",
+	"TPA":  "disc",
+	"TPB":  "publisher",
+	"TRK":  "track",
+	"TYE":  "date",
+	"TOR":  "originaldate",
+}
+
+type id3v2Header struct {
+	MajorVersion byte
+	Flags        byte
+	Size         int64
+}
+
+// skipId3v2 moves the reader past an ID3v2 tag if there is one at the current position
+func skipId3v2(reader io.ReadSeeker) error {
+	start, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	header, found, err := readId3v2Header(reader)
+	if err != nil {
+		return err
+	}
+	if !found {
+		_, err = reader.Seek(start, io.SeekStart)
+		return err
+	}
+	_, err = reader.Seek(header.Size, io.SeekCurrent)
+	return err
+}
+
+func readId3v2Header(reader io.Reader) (id3v2Header, bool, error) {
+	data := make([]byte, id3v2HeaderLength)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return id3v2Header{}, false, nil
+	}
+	if !bytes.Equal(data[0:3], []byte("ID3")) {
+		return id3v2Header{}, false, nil
+	}
+	header := id3v2Header{
+		MajorVersion: data[3],
+		Flags:        data[5],
+		Size:         int64(syncsafeInt(data[6:10])),
+	}
+	if header.Flags&0x10 != 0 {
+		// a footer repeats the header at the end of the tag
+		header.Size += id3v2HeaderLength
+	}
+	return header, true, nil
+}
+
+// readId3v2 reads an ID3v2 tag at the current position of the reader into tags.
+// It returns the total length of the tag, or 0 if there is no tag.
+func readId3v2(reader io.ReadSeeker, tags map[string]string) (int64, error) {
+	header, found, err := readId3v2Header(reader)
+	if err != nil || !found {
+		if _, seekErr := reader.Seek(0, io.SeekStart); seekErr != nil {
+			return 0, seekErr
+		}
+		return 0, err
+	}
+
+	data, err := readBytes(reader, int(header.Size))
+	if err != nil {
+		return 0, err
+	}
+	tagLength := id3v2HeaderLength + header.Size
+
+	if header.MajorVersion < 2 || header.MajorVersion > 4 {
+		return tagLength, nil
+	}
+
+	if header.MajorVersion < 4 && header.Flags&0x80 != 0 {
+		data = removeUnsynchronisation(data)
+	}
+
+	if header.Flags&0x40 != 0 && header.MajorVersion > 2 {
+		data = skipId3v2ExtendedHeader(data, header.MajorVersion)
+	}
+
+	parseId3v2Frames(data, header, tags)
+	return tagLength, nil
+}
+
+func skipId3v2ExtendedHeader(data []byte, majorVersion byte) []byte {
+	if len(data) < 4 {
+		return nil
+	}
+	var extendedHeaderLength int
+	if majorVersion == 4 {
+		// v2.4 includes the size bytes in the size
+		extendedHeaderLength = syncsafeInt(data[0:4])
+	} else {
+		extendedHeaderLength = int(binary.BigEndian.Uint32(data[0:4])) + 4
+	}
+	if extendedHeaderLength > len(data) {
+		return nil
+	}
+	return data[extendedHeaderLength:]
+}
+
+func parseId3v2Frames(data []byte, header id3v2Header, tags map[string]string) {
+	frameIdLength := 4
+	frameHeaderLength := 10
+	if header.MajorVersion == 2 {
+		frameIdLength = 3
+		frameHeaderLength = 6
+	}
+
+	for offset := 0; offset+frameHeaderLength <= len(data); {
+		frameId := string(data[offset : offset+frameIdLength])
+		if data[offset] == 0 {
+			// padding
+			return
+		}
+
+		var frameLength int
+		var frameFlags uint16
+		switch header.MajorVersion {
+		case 2:
+			frameLength = int(data[offset+3])<<16 | int(data[offset+4])<<8 | int(data[offset+5])
+		case 3:
+			frameLength = int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+			frameFlags = binary.BigEndian.Uint16(data[offset+8 : offset+10])
+		default:
+			frameLength = syncsafeInt(data[offset+4 : offset+8])
+			frameFlags = binary.BigEndian.Uint16(data[offset+8 : offset+10])
+		}
+
+		offset += frameHeaderLength
+		if frameLength <= 0 || offset+frameLength > len(data) {
+			return
+		}
+		frameData := data[offset : offset+frameLength]
+		offset += frameLength
+
+		frameData, ok := unpackId3v2FrameData(frameData, frameFlags, header)
+		if !ok {
+			continue
+		}
+		parseId3v2Frame(frameId, frameData, tags)
+	}
+}
+
+// unpackId3v2FrameData strips the frame flag fields, and reports false for compressed or encrypted frames
+func unpackId3v2FrameData(frameData []byte, frameFlags uint16, header id3v2Header) ([]byte, bool) {
+	switch header.MajorVersion {
+	case 3:
+		if frameFlags&0x00c0 != 0 {
+			return nil, false
+		}
+		if frameFlags&0x0020 != 0 && len(frameData) > 0 {
+			frameData = frameData[1:]
+		}
+	case 4:
+		if frameFlags&0x000c != 0 {
+			return nil, false
+		}
+		if frameFlags&0x0040 != 0 && len(frameData) > 0 {
+			frameData = frameData[1:]
+		}
+		if frameFlags&0x0001 != 0 && len(frameData) >= 4 {
+			frameData = frameData[4:]
+		}
+		if frameFlags&0x0002 != 0 || header.Flags&0x80 != 0 {
+			frameData = removeUnsynchronisation(frameData)
+		}
+	}
+	return frameData, true
+}
+
+func parseId3v2Frame(frameId string, frameData []byte, tags map[string]string) {
+	if len(frameData) < 1 {
+		return
+	}
+
+	switch {
+	case frameId == "TXXX" || frameId == "TXX":
+		values := decodeId3v2Strings(frameData[0], frameData[1:])
+		if len(values) < 2 {
+			return
+		}
+		for _, value := range values[1:] {
+			addTag(tags, values[0], value)
+		}
+	case frameId == "USLT" || frameId == "ULT":
+		// unsynchronised lyrics are keyed by language, the same as ffprobe does
+		if len(frameData) < 4 {
+			return
+		}
+		values := decodeId3v2Strings(frameData[0], frameData[4:])
+		if len(values) < 2 {
+			return
+		}
+		addTag(tags, "lyrics-"+strings.TrimRight(string(frameData[1:4]), "\x00"), values[1])
+	case frameId == "SYLT" || frameId == "SLT":
+		if lyrics := parseId3v2SynchronisedLyrics(frameData); lyrics != "" {
+			addTag(tags, "SYLT", lyrics)
+		}
+	case strings.HasPrefix(frameId, "T"):
+		key, found := id3v2FrameKeys[frameId]
+		if !found {
+			key = frameId
+		}
+		for _, value := range decodeId3v2Strings(frameData[0], frameData[1:]) {
+			if key == "genre" {
+				value = id3v2GenreName(value)
+			}
+			addTag(tags, key, value)
+		}
+	}
+}
+
+// parseId3v2SynchronisedLyrics converts a SYLT frame with millisecond timestamps to LRC formatted lyrics
+func parseId3v2SynchronisedLyrics(frameData []byte) string {
+	// encoding, language, timestamp format and content type are followed by a content descriptor
+	if len(frameData) < 6 || frameData[4] != 2 {
+		return ""
+	}
+	encoding := frameData[0]
+	data := frameData[6:]
+
+	terminatorLength := 1
+	if encoding == 1 || encoding == 2 {
+		terminatorLength = 2
+	}
+	nextString := func() (string, bool) {
+		for i := 0; i+terminatorLength <= len(data); i += terminatorLength {
+			if data[i] == 0 && (terminatorLength == 1 || data[i+1] == 0) {
+				values := decodeId3v2Strings(encoding, data[:i])
+				data = data[i+terminatorLength:]
+				if len(values) == 0 {
+					return "", true
+				}
+				return values[0], true
+			}
+		}
+		return "", false
+	}
+
+	if _, found := nextString(); !found {
+		return ""
+	}
+
+	lines := []string{}
+	for len(data) > 0 {
+		text, found := nextString()
+		if !found || len(data) < 4 {
+			break
+		}
+		milliseconds := int(binary.BigEndian.Uint32(data[0:4]))
+		data = data[4:]
+		lines = append(lines, fmt.Sprintf("[%02d:%02d.%02d]%s", milliseconds/60000, milliseconds/1000%60, milliseconds%1000/10, strings.TrimSpace(text)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// decodeId3v2Strings decodes the null separated strings of a text frame in the given text encoding
+func decodeId3v2Strings(encoding byte, data []byte) []string {
+	var values []string
+	switch encoding {
+	case 1, 2:
+		var units []uint16
+		bigEndian := encoding == 2
+		for i := 0; i+1 < len(data); i += 2 {
+			unit := binary.LittleEndian.Uint16(data[i:])
+			if bigEndian {
+				unit = binary.BigEndian.Uint16(data[i:])
+			}
+			switch {
+			case unit == 0xfeff && len(units) == 0 && encoding == 1:
+				continue
+			case unit == 0xfffe && len(units) == 0 && encoding == 1:
+				bigEndian = !bigEndian
+				continue
+			case unit == 0:
+				values = append(values, string(utf16.Decode(units)))
+				units = nil
+				continue
+			}
+			units = append(units, unit)
+		}
+		if len(units) > 0 {
+			values = append(values, string(utf16.Decode(units)))
+		}
+	case 3:
+		values = strings.Split(string(data), "\x00")
+	default:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		values = strings.Split(string(runes), "\x00")
+	}
+
+	for len(values) > 0 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	return values
+}
+
+var id3v2GenreReferenceRegex = regexp.MustCompile(`^\((\d+)\)(.*)$`)
+
+// id3v2GenreName replaces numeric ID3v1 genre references such as "(17)" or "17" with the genre name
+func id3v2GenreName(value string) string {
+	reference := value
+	if matches := id3v2GenreReferenceRegex.FindStringSubmatch(value); len(matches) == 3 {
+		if matches[2] != "" {
+			return matches[2]
+		}
+		reference = matches[1]
+	}
+	index, err := strconv.Atoi(reference)
+	if err != nil || index < 0 || index >= len(id3v1Genres) {
+		return value
+	}
+	return id3v1Genres[index]
+}
+
+func syncsafeInt(data []byte) int {
+	return int(data[0]&0x7f)<<21 | int(data[1]&0x7f)<<14 | int(data[2]&0x7f)<<7 | int(data[3]&0x7f)
+}
+
+// removeUnsynchronisation reverses ID3v2 unsynchronisation, which inserts a zero byte after every 0xff
+func removeUnsynchronisation(data []byte) []byte {
+	result := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		result = append(result, data[i])
+		if data[i] == 0xff && i+1 < len(data) && data[i+1] == 0x00 {
+			i++
+		}
+	}
+	return result
+}
+
+// readId3v1 reads an ID3v1 tag from the last 128 bytes of a file into tags, without replacing any existing tags.
+// It reports whether a tag was found.
+func readId3v1(reader io.ReadSeeker, fileSize int64, tags map[string]string) (bool, error) {
+	if fileSize < 128 {
+		return false, nil
+	}
+	if _, err := reader.Seek(fileSize-128, io.SeekStart); err != nil {
+		return false, err
+	}
+	data, err := readBytes(reader, 128)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(data[0:3], []byte("TAG")) {
+		return false, nil
+	}
+
+	id3v1Value := func(field []byte) string {
+		value, _, _ := bytes.Cut(field, []byte{0})
+		return strings.TrimSpace(string(value))
+	}
+	setIfMissing := func(key string, value string) {
+		if tags[key] == "" && value != "" {
+			tags[key] = value
+		}
+	}
+
+	setIfMissing("title", id3v1Value(data[3:33]))
+	setIfMissing("artist", id3v1Value(data[33:63]))
+	setIfMissing("album", id3v1Value(data[63:93]))
+	setIfMissing("date", id3v1Value(data[93:97]))
+	if data[125] == 0 && data[126] != 0 {
+		// ID3v1.1 stores the track number in the last byte of the comment
+		setIfMissing("track", strconv.Itoa(int(data[126])))
+	}
+	if int(data[127]) < len(id3v1Genres) {
+		setIfMissing("genre", id3v1Genres[data[127]])
+	}
+	return true, nil
+}
+
+var id3v1Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop", "Jazz", "Metal",
+	"New Age", "Oldies", "Other", "Pop", "R&B", "Rap", "Reggae", "Rock", "Techno", "Industrial",
+	"Alternative", "Ska", "Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk",
+	"Fusion", "Trance", "Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic",
+	"Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream", "Southern Rock", "Comedy", "Cult", "Gangsta",
+	"Top 40", "Christian Rap", "Pop/Funk", "Jungle", "Native American", "Cabaret", "New Wave", "Psychadelic", "Rave", "Showtunes",
+	"Trailer", "Lo-Fi", "Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+}
@@ -0,0 +1,325 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"zene/core/types"
+)
+
+// the moov atom is loaded into memory, so refuse anything unreasonably large
+const mp4MaxMoovLength = 64 * 1024 * 1024
+
+// ilst items that ffprobe renames
+var mp4ItemKeys = map[string]string{
+	"\xa9nam": "title",
+	"\xa9ART": "artist",
+	"aART":    "album_artist",
+	"\xa9alb": "album",
+	"\xa9gen": "genre",
+	"\xa9day": "date",
+	"\xa9wrt": "composer",
+	"\xa9grp": "grouping",
+	"\xa9too": "encoder",
+	"\xa9cmt": "comment",
+	"\xa9lyr": "lyrics",
+	"cprt":    "copyright",
+	"desc":    "description",
+	"soaa":    "sort_album_artist",
+	"soal":    "sort_album",
+	"soar":    "sort_artist",
+	"sonm":    "sort_name",
+}
+
+type mp4Atom struct {
+	Type string
+	Data []byte
+}
+
+func readMp4(reader io.ReadSeeker, fileSize int64) (types.FfprobeStandard, error) {
+	moov, err := findMp4TopLevelAtom(reader, fileSize, "moov")
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+
+	result := types.FfprobeStandard{
+		FormatName: "mov,mp4,m4a,3gp,3g2,mj2",
+		Tags:       map[string]string{},
+	}
+
+	for _, atom := range parseMp4Atoms(moov) {
+		switch atom.Type {
+		case "mvhd":
+			if duration := parseMp4Duration(atom.Data); duration > 0 {
+				result.Duration = formatDuration(duration)
+			}
+		case "trak":
+			parseMp4Track(atom.Data, &result)
+		case "udta":
+			parseMp4UserData(atom.Data, result.Tags)
+		case "meta":
+			parseMp4Meta(atom.Data, result.Tags)
+		}
+	}
+
+	if result.Codec == "" {
+		return types.FfprobeStandard{}, fmt.Errorf("%w: no audio track found", ErrInvalidFile)
+	}
+	return result, nil
+}
+
+// findMp4TopLevelAtom walks the top level atoms of a file by seeking, and reads the body of the first atom of the given type
+func findMp4TopLevelAtom(reader io.ReadSeeker, fileSize int64, atomType string) ([]byte, error) {
+	for offset := int64(0); offset+8 <= fileSize; {
+		if _, err := reader.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		header, err := readBytes(reader, 8)
+		if err != nil {
+			return nil, err
+		}
+		atomLength := int64(binary.BigEndian.Uint32(header[0:4]))
+		headerLength := int64(8)
+		switch atomLength {
+		case 0:
+			atomLength = fileSize - offset
+		case 1:
+			extendedLength, err := readBytes(reader, 8)
+			if err != nil {
+				return nil, err
+			}
+			atomLength = int64(binary.BigEndian.Uint64(extendedLength))
+			headerLength = 16
+		}
+		if atomLength < headerLength {
+			return nil, fmt.Errorf("%w: invalid mp4 atom length", ErrInvalidFile)
+		}
+
+		if string(header[4:8]) == atomType {
+			bodyLength := atomLength - headerLength
+			if bodyLength > mp4MaxMoovLength {
+				return nil, fmt.Errorf("%w: %s atom too large", ErrInvalidFile, atomType)
+			}
+			return readBytes(reader, int(bodyLength))
+		}
+		offset += atomLength
+	}
+	return nil, fmt.Errorf("%w: no %s atom found", ErrInvalidFile, atomType)
+}
+
+// parseMp4Atoms splits a buffer into its child atoms, ignoring anything truncated
+func parseMp4Atoms(data []byte) []mp4Atom {
+	atoms := []mp4Atom{}
+	for offset := 0; offset+8 <= len(data); {
+		atomLength := int(binary.BigEndian.Uint32(data[offset:]))
+		headerLength := 8
+		switch atomLength {
+		case 0:
+			atomLength = len(data) - offset
+		case 1:
+			if offset+16 > len(data) {
+				return atoms
+			}
+			atomLength = int(binary.BigEndian.Uint64(data[offset+8:]))
+			headerLength = 16
+		}
+		if atomLength < headerLength || offset+atomLength > len(data) {
+			return atoms
+		}
+		atoms = append(atoms, mp4Atom{
+			Type: string(data[offset+4 : offset+8]),
+			Data: data[offset+headerLength : offset+atomLength],
+		})
+		offset += atomLength
+	}
+	return atoms
+}
+
+func findMp4Atom(data []byte, path ...string) ([]byte, bool) {
+	for _, atomType := range path {
+		found := false
+		for _, atom := range parseMp4Atoms(data) {
+			if atom.Type == atomType {
+				data = atom.Data
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// parseMp4Duration reads the duration in seconds from an mvhd or mdhd atom
+func parseMp4Duration(data []byte) float64 {
+	if len(data) < 1 {
+		return 0
+	}
+	var timescale, duration uint64
+	if data[0] == 1 {
+		if len(data) < 32 {
+			return 0
+		}
+		timescale = uint64(binary.BigEndian.Uint32(data[20:24]))
+		duration = binary.BigEndian.Uint64(data[24:32])
+	} else {
+		if len(data) < 20 {
+			return 0
+		}
+		timescale = uint64(binary.BigEndian.Uint32(data[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return float64(duration) / float64(timescale)
+}
+
+// parseMp4Track reads the codec and stream properties from the first audio track
+func parseMp4Track(trak []byte, result *types.FfprobeStandard) {
+	if result.Codec != "" {
+		return
+	}
+	mdia, found := findMp4Atom(trak, "mdia")
+	if !found {
+		return
+	}
+	hdlr, found := findMp4Atom(mdia, "hdlr")
+	if !found || len(hdlr) < 12 || string(hdlr[8:12]) != "soun" {
+		return
+	}
+	stsd, found := findMp4Atom(mdia, "minf", "stbl", "stsd")
+	if !found || len(stsd) < 8 {
+		return
+	}
+
+	// stsd is a full atom with an entry count before the sample entries
+	entries := parseMp4Atoms(stsd[8:])
+	if len(entries) == 0 || len(entries[0].Data) < 28 {
+		return
+	}
+	entry := entries[0]
+
+	switch entry.Type {
+	case "mp4a":
+		result.Codec = "aac"
+	case "alac":
+		result.Codec = "alac"
+	case "ac-3":
+		result.Codec = "ac3"
+	case "ec-3":
+		result.Codec = "eac3"
+	case "fLaC":
+		result.Codec = "flac"
+	case "Opus":
+		result.Codec = "opus"
+	default:
+		return
+	}
+
+	// the audio sample entry has 6 reserved bytes, a data reference index and 8 more reserved bytes before the channel count
+	result.Channels = int(binary.BigEndian.Uint16(entry.Data[16:18]))
+	sampleSize := int(binary.BigEndian.Uint16(entry.Data[18:20]))
+	result.SampleRate = int(binary.BigEndian.Uint32(entry.Data[24:28]) >> 16)
+
+	if entry.Type == "alac" {
+		result.BitDepth = sampleSize
+		// the alac magic cookie has the exact bit depth, channels and sample rate, which can exceed 16 bits
+		if cookie, found := findMp4Atom(entry.Data[28:], "alac"); found && len(cookie) >= 28 {
+			result.BitDepth = int(cookie[9])
+			result.Channels = int(cookie[13])
+			result.SampleRate = int(binary.BigEndian.Uint32(cookie[24:28]))
+		}
+	}
+
+	if mdhd, found := findMp4Atom(mdia, "mdhd"); found && result.Duration == "" {
+		if duration := parseMp4Duration(mdhd); duration > 0 {
+			result.Duration = formatDuration(duration)
+		}
+	}
+}
+
+func parseMp4UserData(udta []byte, tags map[string]string) {
+	if meta, found := findMp4Atom(udta, "meta"); found {
+		parseMp4Meta(meta, tags)
+	}
+}
+
+func parseMp4Meta(meta []byte, tags map[string]string) {
+	// meta is a full atom in iTunes files, with 4 bytes of version and flags before its children
+	if len(meta) >= 4 && bytes.Equal(meta[0:4], []byte{0, 0, 0, 0}) {
+		meta = meta[4:]
+	}
+	ilst, found := findMp4Atom(meta, "ilst")
+	if !found {
+		return
+	}
+
+	for _, item := range parseMp4Atoms(ilst) {
+		parseMp4Item(item, tags)
+	}
+}
+
+func parseMp4Item(item mp4Atom, tags map[string]string) {
+	var name string
+	var values [][]byte
+	var dataTypes []uint32
+	for _, child := range parseMp4Atoms(item.Data) {
+		switch child.Type {
+		case "name":
+			if len(child.Data) > 4 {
+				name = string(child.Data[4:])
+			}
+		case "data":
+			if len(child.Data) >= 8 {
+				dataTypes = append(dataTypes, binary.BigEndian.Uint32(child.Data[0:4])&0x00ffffff)
+				values = append(values, child.Data[8:])
+			}
+		}
+	}
+
+	for i, value := range values {
+		switch item.Type {
+		case "----":
+			// freeform items such as "MusicBrainz Album Id" are keyed by their name
+			addTag(tags, name, string(value))
+		case "trkn", "disk":
+			key := "track"
+			if item.Type == "disk" {
+				key = "disc"
+			}
+			if len(value) < 6 {
+				continue
+			}
+			number := binary.BigEndian.Uint16(value[2:4])
+			total := binary.BigEndian.Uint16(value[4:6])
+			if number == 0 {
+				continue
+			}
+			tagValue := strconv.Itoa(int(number))
+			if total > 0 {
+				tagValue += "/" + strconv.Itoa(int(total))
+			}
+			addTag(tags, key, tagValue)
+		case "gnre":
+			// a numeric ID3v1 genre, offset by one
+			if len(value) >= 2 {
+				index := int(binary.BigEndian.Uint16(value[0:2])) - 1
+				if index >= 0 && index < len(id3v1Genres) {
+					addTag(tags, "genre", id3v1Genres[index])
+				}
+			}
+		default:
+			key, found := mp4ItemKeys[item.Type]
+			// type 1 is UTF-8 text, anything else such as cover art is not a tag
+			if !found || dataTypes[i] != 1 {
+				continue
+			}
+			addTag(tags, key, string(value))
+		}
+	}
+}
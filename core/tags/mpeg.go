@@ -0,0 +1,227 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"zene/core/types"
+)
+
+// how far past the ID3v2 tag to look for the first MPEG audio frame
+const mpegFrameSearchLength = 64 * 1024
+
+// bitrates in kbps, indexed by [MPEG-1 or MPEG-2/2.5][layer 1, 2 or 3][bitrate index]
+var mpegBitrates = [2][3][16]int{
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+// sample rates indexed by [MPEG-1, MPEG-2, MPEG-2.5][sample rate index]
+var mpegSampleRates = [3][3]int{
+	{44100, 48000, 32000},
+	{22050, 24000, 16000},
+	{11025, 12000, 8000},
+}
+
+type mpegFrameHeader struct {
+	Version         int // 0 for MPEG-1, 1 for MPEG-2, 2 for MPEG-2.5
+	Layer           int // 1, 2 or 3
+	Bitrate         int // kbps
+	SampleRate      int
+	Channels        int
+	SamplesPerFrame int
+}
+
+func readMp3(reader io.ReadSeeker, fileSize int64) (types.FfprobeStandard, error) {
+	result := types.FfprobeStandard{
+		FormatName: "mp3",
+		Tags:       map[string]string{},
+	}
+
+	audioStart, err := readId3v2(reader, result.Tags)
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+
+	audioEnd := fileSize
+	hasId3v1, err := readId3v1(reader, fileSize, result.Tags)
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+	if hasId3v1 {
+		audioEnd -= 128
+	}
+
+	if _, err := reader.Seek(audioStart, io.SeekStart); err != nil {
+		return types.FfprobeStandard{}, err
+	}
+	data := make([]byte, mpegFrameSearchLength)
+	readLength, err := io.ReadFull(reader, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return types.FfprobeStandard{}, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	data = data[:readLength]
+
+	frameOffset, header, found := findMpegFrame(data)
+	if !found {
+		return types.FfprobeStandard{}, fmt.Errorf("%w: no MPEG audio frame found", ErrInvalidFile)
+	}
+
+	result.Codec = "mp" + strconv.Itoa(header.Layer)
+	result.SampleRate = header.SampleRate
+	result.Channels = header.Channels
+	audioLength := audioEnd - audioStart - int64(frameOffset)
+
+	frameCount, frameBytes := readMpegVbrHeader(data[frameOffset:], header)
+	if frameCount > 0 {
+		duration := float64(frameCount) * float64(header.SamplesPerFrame) / float64(header.SampleRate)
+		result.Duration = formatDuration(duration)
+		if frameBytes > 0 {
+			audioLength = int64(frameBytes)
+		}
+		result.Bitrate = strconv.FormatInt(int64(float64(audioLength)*8/duration), 10)
+	} else if header.Bitrate > 0 {
+		// without a VBR header the stream is treated as constant bitrate, the same as ffprobe does
+		result.Duration = formatDuration(float64(audioLength) * 8 / float64(header.Bitrate*1000))
+		result.Bitrate = strconv.Itoa(header.Bitrate * 1000)
+	}
+
+	return result, nil
+}
+
+// findMpegFrame finds the first valid frame header that is followed by another valid frame header
+func findMpegFrame(data []byte) (int, mpegFrameHeader, bool) {
+	for offset := 0; offset+4 <= len(data); offset++ {
+		if data[offset] != 0xff || data[offset+1]&0xe0 != 0xe0 {
+			continue
+		}
+		header, frameLength, ok := parseMpegFrameHeader(data[offset:])
+		if !ok {
+			continue
+		}
+		nextOffset := offset + frameLength
+		if nextOffset+4 <= len(data) {
+			if _, _, nextOk := parseMpegFrameHeader(data[nextOffset:]); !nextOk {
+				continue
+			}
+		}
+		return offset, header, true
+	}
+	return 0, mpegFrameHeader{}, false
+}
+
+// parseMpegFrameHeader parses a 4 byte frame header, returning the header and the length of the frame in bytes
+func parseMpegFrameHeader(data []byte) (mpegFrameHeader, int, bool) {
+	if len(data) < 4 || data[0] != 0xff || data[1]&0xe0 != 0xe0 {
+		return mpegFrameHeader{}, 0, false
+	}
+
+	var header mpegFrameHeader
+	switch (data[1] >> 3) & 0x03 {
+	case 0:
+		header.Version = 2
+	case 2:
+		header.Version = 1
+	case 3:
+		header.Version = 0
+	default:
+		return mpegFrameHeader{}, 0, false
+	}
+
+	layerBits := (data[1] >> 1) & 0x03
+	if layerBits == 0 {
+		return mpegFrameHeader{}, 0, false
+	}
+	header.Layer = 4 - int(layerBits)
+
+	bitrateIndex := data[2] >> 4
+	sampleRateIndex := (data[2] >> 2) & 0x03
+	if bitrateIndex == 0x0f || sampleRateIndex == 0x03 {
+		return mpegFrameHeader{}, 0, false
+	}
+	padding := int((data[2] >> 1) & 0x01)
+
+	bitrateVersion := min(header.Version, 1)
+	header.Bitrate = mpegBitrates[bitrateVersion][header.Layer-1][bitrateIndex]
+	header.SampleRate = mpegSampleRates[header.Version][sampleRateIndex]
+
+	header.Channels = 2
+	if (data[3]>>6)&0x03 == 3 {
+		header.Channels = 1
+	}
+
+	switch {
+	case header.Layer == 1:
+		header.SamplesPerFrame = 384
+	case header.Layer == 3 && header.Version > 0:
+		header.SamplesPerFrame = 576
+	default:
+		header.SamplesPerFrame = 1152
+	}
+
+	if header.Bitrate == 0 {
+		// free format, the frame length cannot be calculated
+		return header, 0, true
+	}
+
+	var frameLength int
+	if header.Layer == 1 {
+		frameLength = (12*header.Bitrate*1000/header.SampleRate + padding) * 4
+	} else {
+		frameLength = header.SamplesPerFrame/8*header.Bitrate*1000/header.SampleRate + padding
+	}
+	return header, frameLength, true
+}
+
+// readMpegVbrHeader reads the frame and byte counts from a Xing, Info or VBRI header in the first frame
+func readMpegVbrHeader(frame []byte, header mpegFrameHeader) (int, int) {
+	// the Xing header follows the side information, which depends on the version and channel count
+	sideInformationLength := 32
+	switch {
+	case header.Version == 0 && header.Channels == 1:
+		sideInformationLength = 17
+	case header.Version > 0 && header.Channels == 2:
+		sideInformationLength = 17
+	case header.Version > 0:
+		sideInformationLength = 9
+	}
+
+	xingOffset := 4 + sideInformationLength
+	if len(frame) >= xingOffset+16 {
+		tag := frame[xingOffset : xingOffset+4]
+		if bytes.Equal(tag, []byte("Xing")) || bytes.Equal(tag, []byte("Info")) {
+			flags := binary.BigEndian.Uint32(frame[xingOffset+4:])
+			offset := xingOffset + 8
+			frameCount := 0
+			byteCount := 0
+			if flags&0x01 != 0 {
+				frameCount = int(binary.BigEndian.Uint32(frame[offset:]))
+				offset += 4
+			}
+			if flags&0x02 != 0 && len(frame) >= offset+4 {
+				byteCount = int(binary.BigEndian.Uint32(frame[offset:]))
+			}
+			return frameCount, byteCount
+		}
+	}
+
+	// VBRI headers are always 32 bytes after the frame header
+	vbriOffset := 4 + 32
+	if len(frame) >= vbriOffset+18 && bytes.Equal(frame[vbriOffset:vbriOffset+4], []byte("VBRI")) {
+		byteCount := int(binary.BigEndian.Uint32(frame[vbriOffset+10:]))
+		frameCount := int(binary.BigEndian.Uint32(frame[vbriOffset+14:]))
+		return frameCount, byteCount
+	}
+
+	return 0, 0
+}
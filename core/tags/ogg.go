@@ -0,0 +1,176 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"zene/core/types"
+)
+
+const (
+	oggPageHeaderLength = 27
+	// how far from the end of the file to look for the last page, which holds the final granule position
+	oggLastPageSearchLength = 64 * 1024
+	// the comment header is the second packet, and is not expected to span more pages than this
+	oggMaxHeaderPages = 256
+)
+
+var (
+	oggCapturePattern = []byte("OggS")
+	vorbisIdHeader    = []byte("\x01vorbis")
+	vorbisCommentHead = []byte("\x03vorbis")
+	opusIdHeader      = []byte("OpusHead")
+	opusCommentHeader = []byte("OpusTags")
+)
+
+type oggPage struct {
+	GranulePosition int64
+	Serial          uint32
+	Segments        []byte
+	Data            []byte
+}
+
+func readOgg(reader io.ReadSeeker, fileSize int64) (types.FfprobeStandard, error) {
+	packets, serial, err := readOggHeaderPackets(reader, 2)
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+	idPacket, commentPacket := packets[0], packets[1]
+
+	result := types.FfprobeStandard{
+		FormatName: "ogg",
+		Tags:       map[string]string{},
+	}
+
+	var preSkip int64
+	switch {
+	case bytes.HasPrefix(idPacket, vorbisIdHeader):
+		if len(idPacket) < 16 || !bytes.HasPrefix(commentPacket, vorbisCommentHead) {
+			return types.FfprobeStandard{}, fmt.Errorf("%w: invalid vorbis headers", ErrInvalidFile)
+		}
+		result.Codec = "vorbis"
+		result.Channels = int(idPacket[11])
+		result.SampleRate = int(binary.LittleEndian.Uint32(idPacket[12:16]))
+		commentPacket = commentPacket[len(vorbisCommentHead):]
+	case bytes.HasPrefix(idPacket, opusIdHeader):
+		if len(idPacket) < 19 || !bytes.HasPrefix(commentPacket, opusCommentHeader) {
+			return types.FfprobeStandard{}, fmt.Errorf("%w: invalid opus headers", ErrInvalidFile)
+		}
+		result.Codec = "opus"
+		result.Channels = int(idPacket[9])
+		// opus always decodes at 48kHz, the input sample rate in the header is informational only
+		result.SampleRate = 48000
+		preSkip = int64(binary.LittleEndian.Uint16(idPacket[10:12]))
+		commentPacket = commentPacket[len(opusCommentHeader):]
+	default:
+		// FLAC and Speex in Ogg are left to ffprobe
+		return types.FfprobeStandard{}, ErrUnsupportedFormat
+	}
+
+	if err := parseVorbisComments(commentPacket, result.Tags); err != nil {
+		return types.FfprobeStandard{}, err
+	}
+
+	granulePosition, err := readOggLastGranulePosition(reader, fileSize, serial)
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+	if granulePosition > preSkip && result.SampleRate > 0 {
+		result.Duration = formatDuration(float64(granulePosition-preSkip) / float64(result.SampleRate))
+	}
+
+	return result, nil
+}
+
+// readOggHeaderPackets reassembles the first packets of the first logical stream in the file
+func readOggHeaderPackets(reader io.Reader, packetCount int) ([][]byte, uint32, error) {
+	packets := [][]byte{}
+	var currentPacket []byte
+	var serial uint32
+
+	for pageIndex := 0; len(packets) < packetCount; pageIndex++ {
+		if pageIndex >= oggMaxHeaderPages {
+			return nil, 0, fmt.Errorf("%w: ogg header packets not found", ErrInvalidFile)
+		}
+		page, err := readOggPage(reader)
+		if err != nil {
+			return nil, 0, err
+		}
+		if pageIndex == 0 {
+			serial = page.Serial
+		} else if page.Serial != serial {
+			// pages from other multiplexed streams
+			continue
+		}
+
+		offset := 0
+		for _, lacingValue := range page.Segments {
+			end := offset + int(lacingValue)
+			currentPacket = append(currentPacket, page.Data[offset:end]...)
+			offset = end
+			if lacingValue < 255 {
+				packets = append(packets, currentPacket)
+				currentPacket = nil
+				if len(packets) == packetCount {
+					break
+				}
+			}
+		}
+	}
+
+	return packets, serial, nil
+}
+
+func readOggPage(reader io.Reader) (oggPage, error) {
+	header, err := readBytes(reader, oggPageHeaderLength)
+	if err != nil {
+		return oggPage{}, err
+	}
+	if !bytes.Equal(header[0:4], oggCapturePattern) {
+		return oggPage{}, fmt.Errorf("%w: missing ogg capture pattern", ErrInvalidFile)
+	}
+
+	page := oggPage{
+		GranulePosition: int64(binary.LittleEndian.Uint64(header[6:14])),
+		Serial:          binary.LittleEndian.Uint32(header[14:18]),
+	}
+	page.Segments, err = readBytes(reader, int(header[26]))
+	if err != nil {
+		return oggPage{}, err
+	}
+
+	dataLength := 0
+	for _, lacingValue := range page.Segments {
+		dataLength += int(lacingValue)
+	}
+	page.Data, err = readBytes(reader, dataLength)
+	if err != nil {
+		return oggPage{}, err
+	}
+	return page, nil
+}
+
+// readOggLastGranulePosition finds the granule position of the last page of a logical stream, which is its length in samples
+func readOggLastGranulePosition(reader io.ReadSeeker, fileSize int64, serial uint32) (int64, error) {
+	searchLength := min(fileSize, int64(oggLastPageSearchLength))
+	if _, err := reader.Seek(fileSize-searchLength, io.SeekStart); err != nil {
+		return 0, err
+	}
+	data, err := readBytes(reader, int(searchLength))
+	if err != nil {
+		return 0, err
+	}
+
+	for offset := bytes.LastIndex(data, oggCapturePattern); offset >= 0; offset = bytes.LastIndex(data[:offset], oggCapturePattern) {
+		if offset+oggPageHeaderLength > len(data) {
+			continue
+		}
+		header := data[offset : offset+oggPageHeaderLength]
+		granulePosition := int64(binary.LittleEndian.Uint64(header[6:14]))
+		if binary.LittleEndian.Uint32(header[14:18]) == serial && granulePosition > 0 {
+			return granulePosition, nil
+		}
+	}
+	return 0, nil
+}
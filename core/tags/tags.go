@@ -0,0 +1,90 @@
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"zene/core/types"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported format for native tag reader")
+	ErrInvalidFile       = errors.New("invalid audio file")
+)
+
+// Read parses the tags and stream properties of an audio file without spawning ffprobe.
+// Tags are keyed the same way ffprobe keys them, so the result can be parsed with ffprobe.ParseMetadata.
+// Stream properties the reader cannot determine are left empty, see HasStreamProperties.
+func Read(audiofilePath string) (types.FfprobeStandard, error) {
+	file, err := os.Open(audiofilePath)
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+
+	var result types.FfprobeStandard
+	switch strings.ToLower(filepath.Ext(audiofilePath)) {
+	case ".mp3":
+		result, err = readMp3(file, fileInfo.Size())
+	case ".flac":
+		result, err = readFlac(file)
+	case ".ogg", ".oga", ".opus":
+		result, err = readOgg(file, fileInfo.Size())
+	case ".m4a", ".m4b", ".mp4", ".aac", ".alac":
+		result, err = readMp4(file, fileInfo.Size())
+	default:
+		return types.FfprobeStandard{}, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return types.FfprobeStandard{}, err
+	}
+
+	result.Filename = audiofilePath
+	result.Size = strconv.FormatInt(fileInfo.Size(), 10)
+	if result.Bitrate == "" && result.Duration != "" {
+		duration, _ := strconv.ParseFloat(result.Duration, 64)
+		if duration > 0 {
+			result.Bitrate = strconv.FormatInt(int64(float64(fileInfo.Size())*8/duration), 10)
+		}
+	}
+	return result, nil
+}
+
+// HasStreamProperties reports whether the reader determined every stream property that ffprobe would provide
+func HasStreamProperties(result types.FfprobeStandard) bool {
+	return result.Duration != "" && result.Bitrate != "" && result.Codec != "" && result.SampleRate > 0 && result.Channels > 0
+}
+
+func formatDuration(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 6, 64)
+}
+
+// addTag adds a tag value, joining repeated tags with ";" the same way ffprobe does
+func addTag(tags map[string]string, key string, value string) {
+	value = strings.TrimRight(value, "\x00")
+	if key == "" || value == "" {
+		return
+	}
+	if existing, found := tags[key]; found && existing != "" {
+		tags[key] = existing + ";" + value
+		return
+	}
+	tags[key] = value
+}
+
+func readBytes(reader io.Reader, length int) ([]byte, error) {
+	buffer := make([]byte, length)
+	if _, err := io.ReadFull(reader, buffer); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	return buffer, nil
+}
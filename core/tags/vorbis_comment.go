@@ -0,0 +1,69 @@
+package tags
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// vorbis comment fields that ffprobe renames
+var vorbisCommentKeys = map[string]string{
+	"ALBUMARTIST": "album_artist",
+	"TRACKNUMBER": "track",
+	"DISCNUMBER":  "disc",
+	"DESCRIPTION": "comment",
+}
+
+// parseVorbisComments parses a Vorbis comment block (FLAC, Ogg Vorbis and Opus) into tags
+func parseVorbisComments(data []byte, tags map[string]string) error {
+	offset := 0
+	readUint32 := func() (int, error) {
+		if offset+4 > len(data) {
+			return 0, fmt.Errorf("%w: truncated vorbis comment block", ErrInvalidFile)
+		}
+		value := int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+		return value, nil
+	}
+	readLength := func() (int, error) {
+		length, err := readUint32()
+		if err != nil {
+			return 0, err
+		}
+		if offset+length > len(data) {
+			return 0, fmt.Errorf("%w: vorbis comment length out of range", ErrInvalidFile)
+		}
+		return length, nil
+	}
+
+	vendorLength, err := readLength()
+	if err != nil {
+		return err
+	}
+	offset += vendorLength
+
+	commentCount, err := readUint32()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < commentCount; i++ {
+		commentLength, err := readLength()
+		if err != nil {
+			return err
+		}
+		comment := string(data[offset : offset+commentLength])
+		offset += commentLength
+
+		key, value, found := strings.Cut(comment, "=")
+		if !found {
+			continue
+		}
+		key = strings.ToUpper(key)
+		if renamedKey, found := vorbisCommentKeys[key]; found {
+			key = renamedKey
+		}
+		addTag(tags, key, value)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ type Artist struct {
 	AlbumCount     int             `xml:"albumCount,attr" json:"albumCount"`
 	Starred        string          `xml:"starred,attr,omitempty" json:"starred,omitempty"`
 	Album          []SubsonicChild `xml:"album,omitempty" json:"album,omitempty"`
+	AppearsOn      []SubsonicChild `xml:"appearsOn,omitempty" json:"appearsOn,omitempty"`
 	MusicBrainzId  string          `xml:"musicBrainzId,attr,omitempty" json:"musicBrainzId,omitempty"`
 	SortName       string          `xml:"sortName,attr,omitempty" json:"sortName,omitempty"`
 	UserRating     int             `xml:"userRating,attr,omitempty" json:"userRating"`
@@ -0,0 +1,24 @@
+package types
+
+const (
+	CreditRoleArtist      = "artist"
+	CreditRoleAlbumArtist = "albumartist"
+	CreditRoleComposer    = "composer"
+	CreditRoleLyricist    = "lyricist"
+	CreditRoleConductor   = "conductor"
+	CreditRolePerformer   = "performer"
+	CreditRoleArranger    = "arranger"
+	CreditRoleRemixer     = "remixer"
+	CreditRoleProducer    = "producer"
+	CreditRoleEngineer    = "engineer"
+	CreditRoleMixer       = "mixer"
+	CreditRoleDjMixer     = "djmixer"
+)
+
+type ArtistCredit struct {
+	Role                string `json:"role"`
+	SubRole             string `json:"sub_role"`
+	Position            int    `json:"position"`
+	Name                string `json:"name"`
+	MusicBrainzArtistID string `json:"musicbrainz_artist_id"`
+}
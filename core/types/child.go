@@ -47,6 +47,8 @@ type SubsonicChild struct {
 	Moods              []string            `xml:"moods" json:"moods,omitempty"`
 	DisplayComposer    string              `xml:"displayComposer,attr,omitempty" json:"displayComposer,omitempty"`
 	ExplicitStatus     string              `xml:"explicitStatus,attr,omitempty" json:"explicitStatus,omitempty"`
+	ReplayGain         *ChildReplayGain    `xml:"replayGain" json:"replayGain,omitempty"`
+	Versions           []SongVersion       `xml:"version" json:"versions,omitempty"`
 }
 
 type ChildGenre struct {
@@ -59,8 +61,9 @@ type ChildArtist struct {
 }
 
 type ChildContributors struct {
-	Role   string       `xml:"role,attr" json:"role"`
-	Artist *ChildArtist `xml:"artist" json:"artist"`
+	Role    string       `xml:"role,attr" json:"role"`
+	SubRole string       `xml:"subRole,attr,omitempty" json:"subRole,omitempty"`
+	Artist  *ChildArtist `xml:"artist" json:"artist"`
 }
 
 type ChildRecordLabel struct {
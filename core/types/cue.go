@@ -0,0 +1,44 @@
+package types
+
+type CueSheet struct {
+	Title      string
+	Performer  string
+	Songwriter string
+	Genre      string
+	Date       string
+	Files      []CueFile
+}
+
+type CueFile struct {
+	Name   string
+	Tracks []CueTrack
+}
+
+// CueTrack is an audio track in a CUE sheet, Start and End are in seconds from the start of the file.
+// The last track in a file has an End of 0, meaning it plays to the end of the file.
+type CueTrack struct {
+	Number     int
+	Title      string
+	Performer  string
+	Songwriter string
+	Isrc       string
+	Start      float64
+	End        float64
+}
+
+// MediaFile is the file to stream for a song or podcast episode.
+// Songs from a CUE sheet are a time slice of their source file, an End of 0 means the end of the file.
+// VersionId is set for songs, as a song can have a version in more than one music folder.
+// SampleRate, BitDepth, Channels and Duration, in seconds, are 0 when they are not known.
+type MediaFile struct {
+	FilePath   string
+	IsCueTrack bool
+	Start      float64
+	End        float64
+	VersionId  string
+	SampleRate int
+	BitDepth   int
+	Channels   int
+	Duration   float64
+	ReplayGain ReplayGain
+}
@@ -0,0 +1,14 @@
+package types
+
+// DownloadTrack is a track of an album, artist or playlist downloaded as a ZIP archive, with the tags its file is named from
+type DownloadTrack struct {
+	TrackId     string
+	Title       string
+	Artist      string
+	Album       string
+	AlbumId     string
+	Year        string
+	AlbumArtist string
+	TrackNumber int
+	DiscNumber  int
+}
@@ -28,4 +28,32 @@ type LyricsDatabaseRow struct {
 	MusicBrainzTrackID string `json:"musicBrainzTrackId"`
 	PlainLyrics        string `json:"plainLyrics"`
 	SyncedLyrics       string `json:"syncedLyrics"`
+	Source             string `json:"source"`
+	Lang               string `json:"lang"`
+}
+
+const (
+	LyricsSourceSidecarLrc     = "sidecar_lrc"
+	LyricsSourceEmbeddedSynced = "embedded_synced"
+	LyricsSourceSidecarTxt     = "sidecar_txt"
+	LyricsSourceEmbedded       = "embedded"
+	LyricsSourceLrclib         = "lrclib"
+)
+
+// LyricsSourcePriorities orders lyrics sources from most to least preferred, lower values win
+var LyricsSourcePriorities = map[string]int{
+	LyricsSourceSidecarLrc:     10,
+	LyricsSourceEmbeddedSynced: 20,
+	LyricsSourceSidecarTxt:     30,
+	LyricsSourceEmbedded:       40,
+	LyricsSourceLrclib:         100,
+}
+
+// LocalLyrics are lyrics read from a sidecar file or embedded tag of a track
+type LocalLyrics struct {
+	Source       string `json:"source"`
+	Priority     int    `json:"priority"`
+	Lang         string `json:"lang"`
+	PlainLyrics  string `json:"plainLyrics"`
+	SyncedLyrics string `json:"syncedLyrics"`
 }
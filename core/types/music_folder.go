@@ -8,3 +8,19 @@ type MusicFolder struct {
 	Id   int    `xml:"id,attr" json:"id"`
 	Name string `xml:"name,attr" json:"name"`
 }
+
+type MusicFolderSettingsList struct {
+	MusicFolder []MusicFolderSettings `xml:"musicFolder" json:"musicFolder"`
+}
+
+// MusicFolderSettings is a music folder with the settings that only admins can see and change.
+// An empty FileTypes list means the AUDIO_FILE_TYPES default, and a ScanInterval of 0 disables scheduled scans.
+type MusicFolderSettings struct {
+	Id           int      `xml:"id,attr" json:"id"`
+	Name         string   `xml:"name,attr" json:"name"`
+	Path         string   `xml:"path,attr" json:"path"`
+	Enabled      bool     `xml:"enabled,attr" json:"enabled"`
+	ScanInterval int      `xml:"scanInterval,attr" json:"scanInterval"`
+	FileTypes    []string `xml:"fileType" json:"fileTypes"`
+	LastScanned  string   `xml:"lastScanned,attr,omitempty" json:"lastScanned,omitempty"`
+}
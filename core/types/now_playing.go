@@ -46,6 +46,7 @@ type SubsonicNowPlayingEntry struct {
 	Moods              []string            `xml:"moods>mood" json:"moods,omitempty"`
 	DisplayComposer    string              `xml:"displayComposer,attr,omitempty" json:"displayComposer,omitempty"`
 	ExplicitStatus     string              `xml:"explicitStatus,attr,omitempty" json:"explicitStatus,omitempty"`
+	ReplayGain         *ChildReplayGain    `xml:"replayGain" json:"replayGain,omitempty"`
 	Username           string              `xml:"username,attr" json:"username"`
 	MinutesAgo         int                 `xml:"minutesAgo,attr" json:"minutesAgo"`
 	PlayerId           int                 `xml:"playerId,attr" json:"playerId"`
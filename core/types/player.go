@@ -0,0 +1,23 @@
+package types
+
+type Players struct {
+	Player []Player `xml:"player" json:"player"`
+}
+
+// Player is a client app of a user, identified by the c parameter, with the transcoding profile used when a stream request
+// does not ask for a format or bit rate. An empty Format means aac, a MaxBitRate of 0 means DEFAULT_BIT_RATE,
+// and a MaxSampleRate of 0 keeps the file's sample rate. LastFormat and LastMaxBitRate are what the player last streamed with,
+// whether it asked for them or got its profile, and are empty until it streams.
+type Player struct {
+	Id             int    `xml:"id,attr" json:"id"`
+	UserId         int    `xml:"userId,attr" json:"userId"`
+	Username       string `xml:"username,attr" json:"username"`
+	Client         string `xml:"client,attr" json:"client"`
+	Format         string `xml:"format,attr" json:"format"`
+	MaxBitRate     int    `xml:"maxBitRate,attr" json:"maxBitRate"`
+	MaxSampleRate  int    `xml:"maxSampleRate,attr" json:"maxSampleRate"`
+	AllowRaw       bool   `xml:"allowRaw,attr" json:"allowRaw"`
+	LastSeen       string `xml:"lastSeen,attr" json:"lastSeen"`
+	LastFormat     string `xml:"lastFormat,attr" json:"lastFormat"`
+	LastMaxBitRate int    `xml:"lastMaxBitRate,attr" json:"lastMaxBitRate"`
+}
@@ -0,0 +1,32 @@
+package types
+
+const (
+	ReplayGainSourceTags     = "tags"
+	ReplayGainSourceAnalysis = "analysis"
+	ReplayGainSourceFailed   = "failed"
+)
+
+// ReplayGain holds the gain in dB and linear peak of a track and its album, nil values are unknown
+type ReplayGain struct {
+	TrackGain *float64 `json:"track_gain"`
+	TrackPeak *float64 `json:"track_peak"`
+	AlbumGain *float64 `json:"album_gain"`
+	AlbumPeak *float64 `json:"album_peak"`
+	Source    string   `json:"source"`
+}
+
+type ReplayGainTrack struct {
+	FilePath   string     `json:"file_path"`
+	MediaFile  MediaFile  `json:"media_file"`
+	Duration   float64    `json:"duration"`
+	ReplayGain ReplayGain `json:"replay_gain"`
+}
+
+type ChildReplayGain struct {
+	TrackGain    *float64 `xml:"trackGain,attr,omitempty" json:"trackGain,omitempty"`
+	AlbumGain    *float64 `xml:"albumGain,attr,omitempty" json:"albumGain,omitempty"`
+	TrackPeak    *float64 `xml:"trackPeak,attr,omitempty" json:"trackPeak,omitempty"`
+	AlbumPeak    *float64 `xml:"albumPeak,attr,omitempty" json:"albumPeak,omitempty"`
+	BaseGain     *float64 `xml:"baseGain,attr,omitempty" json:"baseGain,omitempty"`
+	FallbackGain *float64 `xml:"fallbackGain,attr,omitempty" json:"fallbackGain,omitempty"`
+}
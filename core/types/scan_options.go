@@ -1,6 +1,9 @@
 package types
 
 type ScanOptions struct {
-	Force      bool `json:"force"`
-	IncludeArt bool `json:"include-art"`
+	Force             bool   `json:"force"`
+	IncludeArt        bool   `json:"include-art"`
+	MusicFolderId     int    `json:"musicFolderId"`
+	Path              string `json:"path"`
+	AllowMassDeletion bool   `json:"allowMassDeletion"`
 }
@@ -0,0 +1,37 @@
+package types
+
+const (
+	ScanResultInserted = "inserted"
+	ScanResultUpdated  = "updated"
+	ScanResultDeleted  = "deleted"
+	ScanResultSkipped  = "skipped"
+	ScanResultFailed   = "failed"
+)
+
+const (
+	ScanReasonNewFile                  = "new file"
+	ScanReasonFileModified             = "file modified"
+	ScanReasonForcedRescan             = "forced rescan"
+	ScanReasonFileNotFound             = "file not found"
+	ScanReasonDeletionRefused          = "deletion refused"
+	ScanReasonMissingMusicBrainzArtist = "missing MusicBrainz artist id"
+	ScanReasonMissingMusicBrainzAlbum  = "missing MusicBrainz album id"
+	ScanReasonMissingMusicBrainzTrack  = "missing MusicBrainz track id"
+	ScanReasonFfprobeError             = "ffprobe error"
+	ScanReasonMusicBrainzError         = "musicbrainz lookup error"
+	ScanReasonMetadataError            = "metadata error"
+)
+
+type ScanResult struct {
+	FilePath string `xml:"filePath,attr" json:"filePath"`
+	Status   string `xml:"status,attr" json:"status"`
+	Reason   string `xml:"reason,attr" json:"reason"`
+	Detail   string `xml:"detail,attr,omitempty" json:"detail,omitempty"`
+	Created  string `xml:"created,attr" json:"created"`
+}
+
+type ScanResults struct {
+	ScanId     int          `xml:"scanId,attr" json:"scanId"`
+	TotalCount int          `xml:"totalCount,attr" json:"totalCount"`
+	ScanResult []ScanResult `xml:"scanResult" json:"scanResult"`
+}
@@ -1,5 +1,18 @@
 package types
 
+// scan phases, in the order a scan runs them. Walking, probing and upserting repeat for each batch of files.
+const (
+	ScanPhaseWalking        = "walking"
+	ScanPhaseProbing        = "probing"
+	ScanPhaseUpserting      = "upserting"
+	ScanPhaseArt            = "art"
+	ScanPhaseSimilarArtists = "similarArtists"
+	ScanPhaseTopSongs       = "topSongs"
+	ScanPhaseComplete       = "complete"
+	ScanPhaseCancelled      = "cancelled"
+	ScanPhaseFailed         = "failed"
+)
+
 type ScanStatus struct {
 	Scanning      bool   `xml:"scanning,attr" json:"scanning"`
 	Count         int    `xml:"count,attr" json:"count"`
@@ -7,4 +20,9 @@ type ScanStatus struct {
 	StartedDate   string `xml:"startedDate,attr" json:"startedDate"`
 	Type          string `xml:"type,attr" json:"type"`
 	CompletedDate string `xml:"completedDate,attr" json:"completedDate"`
+	Phase         string `xml:"phase,attr,omitempty" json:"phase,omitempty"`
+	// Processed and Total count files until the art phase, then albums and artists
+	Processed               int    `xml:"processed,attr" json:"processed"`
+	Total                   int    `xml:"total,attr" json:"total"`
+	EstimatedCompletionDate string `xml:"estimatedCompletionDate,attr,omitempty" json:"estimatedCompletionDate,omitempty"`
 }
@@ -0,0 +1,19 @@
+package types
+
+type ScheduledJobs struct {
+	ScheduledJob []ScheduledJob `xml:"scheduledJob" json:"scheduledJob"`
+}
+
+// ScheduledJob is a background job and the result of its last run. LastDuration is in milliseconds,
+// and NextRun is empty when the job is paused or only runs at startup and when triggered.
+type ScheduledJob struct {
+	Name         string `xml:"name,attr" json:"name"`
+	Description  string `xml:"description,attr" json:"description"`
+	Schedule     string `xml:"schedule,attr" json:"schedule"`
+	Paused       bool   `xml:"paused,attr" json:"paused"`
+	Running      bool   `xml:"running,attr" json:"running"`
+	LastRun      string `xml:"lastRun,attr,omitempty" json:"lastRun,omitempty"`
+	LastDuration int64  `xml:"lastDuration,attr" json:"lastDuration"`
+	LastError    string `xml:"lastError,attr,omitempty" json:"lastError,omitempty"`
+	NextRun      string `xml:"nextRun,attr,omitempty" json:"nextRun,omitempty"`
+}
@@ -0,0 +1,29 @@
+package types
+
+// SongVersion is one media file of a song. The same release can be in more than one music folder, eg as FLAC in one folder
+// and as MP3 in another, so a song id can have several versions and the version streamed is chosen for each request.
+type SongVersion struct {
+	Id              string    `xml:"id,attr" json:"id"`
+	MusicFolderId   int       `xml:"musicFolderId,attr" json:"musicFolderId"`
+	MusicFolderName string    `xml:"musicFolderName,attr" json:"musicFolderName"`
+	Path            string    `xml:"path,attr" json:"path"`
+	Suffix          string    `xml:"suffix,attr,omitempty" json:"suffix,omitempty"`
+	Codec           string    `xml:"codec,attr,omitempty" json:"codec,omitempty"`
+	Size            int       `xml:"size,attr,omitempty" json:"size,omitempty"`
+	BitRate         int       `xml:"bitRate,attr,omitempty" json:"bitRate,omitempty"`
+	BitDepth        int       `xml:"bitDepth,attr,omitempty" json:"bitDepth,omitempty"`
+	SamplingRate    int       `xml:"samplingRate,attr,omitempty" json:"samplingRate,omitempty"`
+	ChannelCount    int       `xml:"channelCount,attr,omitempty" json:"channelCount,omitempty"`
+	Lossless        bool      `xml:"lossless,attr" json:"lossless"`
+	Selected        bool      `xml:"selected,attr" json:"selected"`
+	MediaFile       MediaFile `xml:"-" json:"-"`
+}
+
+// VersionPreference is what a stream or download request asks for, used to choose between the versions of a song.
+// A VersionId asks for one version, otherwise a version that already has the requested format and fits the bit rate is preferred,
+// then the best quality version.
+type VersionPreference struct {
+	VersionId  string
+	Format     string
+	MaxBitRate int
+}
@@ -15,12 +15,20 @@ type SubsonicStandard struct {
 	Error                  *SubsonicError             `xml:"error,omitempty" json:"error,omitempty"`
 	License                *LicenseInfo               `xml:"license,omitempty" json:"license,omitempty"`
 	ScanStatus             *ScanStatus                `xml:"scanStatus,omitempty" json:"scanStatus,omitempty"`
+	ScanResults            *ScanResults               `xml:"scanResults,omitempty" json:"scanResults,omitempty"`
+	ScheduledJobs          *ScheduledJobs             `xml:"scheduledJobs,omitempty" json:"scheduledJobs,omitempty"`
 	OpenSubsonicExtensions []*OpenSubsonicExtensions  `xml:"openSubsonicExtensions,omitempty" json:"openSubsonicExtensions,omitempty"`
 	User                   *SubsonicUser              `xml:"user,omitempty" json:"user,omitempty"`
 	Users                  *SubsonicUsers             `xml:"users,omitempty" json:"users,omitempty"`
 	Lyrics                 *SubsonicLyrics            `xml:"lyrics,omitempty" json:"lyrics,omitempty"`
 	LyricsList             *SubsonicLyricsList        `xml:"lyricsList,omitempty" json:"lyricsList,omitempty"`
 	MusicFolders           *MusicFolders              `xml:"musicFolders,omitempty" json:"musicFolders,omitempty"`
+	MusicFolderSettings    *MusicFolderSettingsList   `xml:"musicFolderSettings,omitempty" json:"musicFolderSettings,omitempty"`
+	Players                *Players                   `xml:"players,omitempty" json:"players,omitempty"`
+	TranscodeSettings      *TranscodeSettings         `xml:"transcodeSettings,omitempty" json:"transcodeSettings,omitempty"`
+	Transcodes             *Transcodes                `xml:"transcodes,omitempty" json:"transcodes,omitempty"`
+	TranscodeDecision      *TranscodeDecision         `xml:"transcodeDecision,omitempty" json:"transcodeDecision,omitempty"`
+	Waveform               *Waveform                  `xml:"waveform,omitempty" json:"waveform,omitempty"`
 	Genres                 *Genres                    `xml:"genres,omitempty" json:"genres,omitempty"`
 	CoverArt               *CoverArt                  `xml:"coverArt,omitempty" json:"coverArt,omitempty"`
 	ChatMessages           *ChatMessages              `xml:"chatMessages,omitempty" json:"chatMessages,omitempty"`
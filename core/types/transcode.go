@@ -0,0 +1,20 @@
+package types
+
+type Transcodes struct {
+	Transcode []Transcode `xml:"transcode" json:"transcode"`
+}
+
+// Transcode is a running or queued ffmpeg transcode. Duration is the number of seconds it has been running, or queued for.
+type Transcode struct {
+	Id        int    `xml:"id,attr" json:"id"`
+	UserId    int    `xml:"userId,attr" json:"userId"`
+	Username  string `xml:"username,attr" json:"username"`
+	Client    string `xml:"client,attr" json:"client"`
+	TrackId   string `xml:"trackId,attr" json:"trackId"`
+	Format    string `xml:"format,attr" json:"format"`
+	BitRate   int    `xml:"bitRate,attr" json:"bitRate"`
+	Status    string `xml:"status,attr" json:"status"`
+	BytesSent int64  `xml:"bytesSent,attr" json:"bytesSent"`
+	Duration  int    `xml:"duration,attr" json:"duration"`
+	StartedAt string `xml:"startedAt,attr" json:"startedAt"`
+}
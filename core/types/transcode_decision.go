@@ -0,0 +1,79 @@
+package types
+
+// ClientInfo is what a client can play, posted to getTranscodeDecision as JSON.
+// Bit rates are in bits per second, and an empty list or a 0 limit means anything is accepted.
+type ClientInfo struct {
+	Name                       string               `json:"name"`
+	Platform                   string               `json:"platform"`
+	MaxAudioBitrate            int                  `json:"maxAudioBitrate"`
+	MaxTranscodingAudioBitrate int                  `json:"maxTranscodingAudioBitrate"`
+	DirectPlayProfiles         []DirectPlayProfile  `json:"directPlayProfiles"`
+	TranscodingProfiles        []TranscodingProfile `json:"transcodingProfiles"`
+	CodecProfiles              []CodecProfile       `json:"codecProfiles"`
+}
+
+type DirectPlayProfile struct {
+	Containers       []string `json:"containers"`
+	AudioCodecs      []string `json:"audioCodecs"`
+	Protocols        []string `json:"protocols"`
+	MaxAudioChannels int      `json:"maxAudioChannels"`
+}
+
+// TranscodingProfile is an output a client can play, in order of preference
+type TranscodingProfile struct {
+	Container        string `json:"container"`
+	AudioCodec       string `json:"audioCodec"`
+	Protocol         string `json:"protocol"`
+	MaxAudioChannels int    `json:"maxAudioChannels"`
+}
+
+// CodecProfile limits the streams of a codec a client can play, eg a maximum sample rate for FLAC
+type CodecProfile struct {
+	Type        string       `json:"type"`
+	Name        string       `json:"name"`
+	Limitations []Limitation `json:"limitations"`
+}
+
+// Limitation compares a stream property (audioChannels, audioBitrate, audioProfile, audioSamplerate or audioBitdepth)
+// with its values, using Equals, NotEquals, LessThanEqual or GreaterThanEqual.
+// A limitation on a property that is not known only fails when it is required.
+type Limitation struct {
+	Name       string   `json:"name"`
+	Comparison string   `json:"comparison"`
+	Values     []string `json:"values"`
+	Required   bool     `json:"required"`
+}
+
+// TranscodeDecision tells a client whether a file will be played as it is or transcoded, and to what.
+// TranscodeParams is passed to getTranscodeStream to stream the file as decided.
+type TranscodeDecision struct {
+	CanDirectPlay   bool           `xml:"canDirectPlay,attr" json:"canDirectPlay"`
+	CanTranscode    bool           `xml:"canTranscode,attr" json:"canTranscode"`
+	TranscodeReason []string       `xml:"transcodeReason,omitempty" json:"transcodeReason,omitempty"`
+	ErrorReason     string         `xml:"errorReason,attr,omitempty" json:"errorReason,omitempty"`
+	TranscodeParams string         `xml:"transcodeParams,attr,omitempty" json:"transcodeParams,omitempty"`
+	SourceStream    *StreamDetails `xml:"sourceStream,omitempty" json:"sourceStream,omitempty"`
+	TranscodeStream *StreamDetails `xml:"transcodeStream,omitempty" json:"transcodeStream,omitempty"`
+}
+
+// StreamDetails describes an audio stream, bit rates are in bits per second
+type StreamDetails struct {
+	Protocol        string `xml:"protocol,attr" json:"protocol"`
+	Container       string `xml:"container,attr" json:"container"`
+	Codec           string `xml:"codec,attr" json:"codec"`
+	AudioChannels   int    `xml:"audioChannels,attr,omitempty" json:"audioChannels,omitempty"`
+	AudioBitrate    int    `xml:"audioBitrate,attr,omitempty" json:"audioBitrate,omitempty"`
+	AudioProfile    string `xml:"audioProfile,attr,omitempty" json:"audioProfile,omitempty"`
+	AudioSamplerate int    `xml:"audioSamplerate,attr,omitempty" json:"audioSamplerate,omitempty"`
+	AudioBitdepth   int    `xml:"audioBitdepth,attr,omitempty" json:"audioBitdepth,omitempty"`
+}
+
+// TranscodeParams is how a file is streamed by getTranscodeStream. A Format of raw streams the file as it is,
+// MaxBitRate is in kbps, a MaxSampleRate of 0 keeps the file's sample rate and Channels of 0 keeps the file's channels.
+type TranscodeParams struct {
+	VersionId     string `json:"v,omitempty"`
+	Format        string `json:"f"`
+	MaxBitRate    int    `json:"b,omitempty"`
+	MaxSampleRate int    `json:"s,omitempty"`
+	Channels      int    `json:"c,omitempty"`
+}
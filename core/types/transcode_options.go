@@ -0,0 +1,16 @@
+package types
+
+// TranscodeOptions is how a media file is transcoded. MaxBitRate is in kbps, with 0 using the encoder's default,
+// a MaxSampleRate of 0 keeps the file's sample rate, and TimeOffset is in seconds from the start of the file.
+// EstimateContentLength sends a Content-Length estimated from the duration and bit rate while transcoding.
+// Normalization, Channels and MaxBitDepth add DSP filters, as described for TranscodeSettings.
+type TranscodeOptions struct {
+	Format                string
+	MaxBitRate            int
+	MaxSampleRate         int
+	TimeOffset            int
+	EstimateContentLength bool
+	Normalization         string
+	Channels              int
+	MaxBitDepth           int
+}
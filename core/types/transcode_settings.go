@@ -0,0 +1,19 @@
+package types
+
+// loudness normalisation modes of a transcode
+const (
+	NormalizationLoudnorm = "loudnorm"
+	NormalizationTrack    = "track"
+	NormalizationAlbum    = "album"
+)
+
+// TranscodeSettings are a user's default DSP filters, used when a stream request does not ask for them.
+// Normalization is empty, loudnorm for EBU R128 loudness normalisation, or track or album to apply ReplayGain.
+// A Channels of 1 or 2 downmixes files with more channels, and 0 values keep the file's channels, sample rate and bit depth.
+type TranscodeSettings struct {
+	UserId        int    `xml:"userId,attr" json:"userId"`
+	Normalization string `xml:"normalization,attr" json:"normalization"`
+	Channels      int    `xml:"channels,attr" json:"channels"`
+	MaxSampleRate int    `xml:"maxSampleRate,attr" json:"maxSampleRate"`
+	MaxBitDepth   int    `xml:"maxBitDepth,attr" json:"maxBitDepth"`
+}
@@ -15,58 +15,71 @@ type FfVersions struct {
 }
 
 type FileMetadata struct {
-	Format              string `json:"format"`
-	Duration            string `json:"duration"`
-	Size                string `json:"size"`
-	Bitrate             string `json:"bitrate"`
-	Title               string `json:"title"`
-	Artist              string `json:"artist"`
-	Album               string `json:"album"`
-	AlbumArtist         string `json:"album_artist"`
-	Genre               string `json:"genre"`
-	TrackNumber         int    `json:"track_number"`
-	TotalTracks         int    `json:"total_tracks"`
-	DiscNumber          int    `json:"disc_number"`
-	TotalDiscs          int    `json:"total_discs"`
-	ReleaseDate         string `json:"release_date"`
-	MusicBrainzArtistID string `json:"musicbrainz_artist_id"`
-	MusicBrainzAlbumID  string `json:"musicbrainz_album_id"`
-	MusicBrainzTrackID  string `json:"musicbrainz_track_id"`
-	Label               string `json:"label"`
-	Codec               string `json:"codec_name"`
-	BitDepth            int    `json:"bits_per_raw_sample"`
-	SampleRate          int    `json:"sample_rate"`
-	Channels            int    `json:"channels"`
+	Format              string         `json:"format"`
+	Duration            string         `json:"duration"`
+	Size                string         `json:"size"`
+	Bitrate             string         `json:"bitrate"`
+	Title               string         `json:"title"`
+	Artist              string         `json:"artist"`
+	Album               string         `json:"album"`
+	AlbumArtist         string         `json:"album_artist"`
+	Genre               string         `json:"genre"`
+	TrackNumber         int            `json:"track_number"`
+	TotalTracks         int            `json:"total_tracks"`
+	DiscNumber          int            `json:"disc_number"`
+	TotalDiscs          int            `json:"total_discs"`
+	ReleaseDate         string         `json:"release_date"`
+	MusicBrainzArtistID string         `json:"musicbrainz_artist_id"`
+	MusicBrainzAlbumID  string         `json:"musicbrainz_album_id"`
+	MusicBrainzTrackID  string         `json:"musicbrainz_track_id"`
+	Label               string         `json:"label"`
+	Codec               string         `json:"codec_name"`
+	BitDepth            int            `json:"bits_per_raw_sample"`
+	SampleRate          int            `json:"sample_rate"`
+	Channels            int            `json:"channels"`
+	Credits             []ArtistCredit `json:"credits"`
+	ReplayGain          ReplayGain     `json:"replay_gain"`
+	Lyrics              []LocalLyrics  `json:"lyrics"`
+	FilePath            string         `json:"file_path"`
+	SourceFilePath      string         `json:"source_file_path"`
+	CueStart            float64        `json:"cue_start"`
+	CueEnd              float64        `json:"cue_end"`
 }
 
 type Metadata struct {
-	FilePath            string `json:"file_path"`
-	FileName            string `json:"file_name"`
-	DateAdded           string `json:"date_added"`
-	DateModified        string `json:"date_modified"`
-	Format              string `json:"format"`
-	Duration            string `json:"duration"`
-	Size                string `json:"size"`
-	Bitrate             string `json:"bitrate"`
-	Title               string `json:"title"`
-	Artist              string `json:"artist"`
-	Album               string `json:"album"`
-	AlbumArtist         string `json:"album_artist"`
-	Genre               string `json:"genre"`
-	TrackNumber         int    `json:"track_number"`
-	TotalTracks         int    `json:"total_tracks"`
-	DiscNumber          int    `json:"disc_number"`
-	TotalDiscs          int    `json:"total_discs"`
-	ReleaseDate         string `json:"release_date"`
-	MusicBrainzArtistID string `json:"musicbrainz_artist_id"`
-	MusicBrainzAlbumID  string `json:"musicbrainz_album_id"`
-	MusicBrainzTrackID  string `json:"musicbrainz_track_id"`
-	Label               string `json:"label"`
-	MusicFolderId       int    `json:"music_folder_id"`
-	Codec               string `json:"codec_name"`
-	BitDepth            int    `json:"bits_per_raw_sample"`
-	SampleRate          int    `json:"sample_rate"`
-	Channels            int    `json:"channels"`
+	FilePath            string         `json:"file_path"`
+	FileName            string         `json:"file_name"`
+	DateAdded           string         `json:"date_added"`
+	DateModified        string         `json:"date_modified"`
+	Format              string         `json:"format"`
+	Duration            string         `json:"duration"`
+	Size                string         `json:"size"`
+	Bitrate             string         `json:"bitrate"`
+	Title               string         `json:"title"`
+	Artist              string         `json:"artist"`
+	Album               string         `json:"album"`
+	AlbumArtist         string         `json:"album_artist"`
+	Genre               string         `json:"genre"`
+	TrackNumber         int            `json:"track_number"`
+	TotalTracks         int            `json:"total_tracks"`
+	DiscNumber          int            `json:"disc_number"`
+	TotalDiscs          int            `json:"total_discs"`
+	ReleaseDate         string         `json:"release_date"`
+	MusicBrainzArtistID string         `json:"musicbrainz_artist_id"`
+	MusicBrainzAlbumID  string         `json:"musicbrainz_album_id"`
+	MusicBrainzTrackID  string         `json:"musicbrainz_track_id"`
+	Label               string         `json:"label"`
+	MusicFolderId       int            `json:"music_folder_id"`
+	Codec               string         `json:"codec_name"`
+	BitDepth            int            `json:"bits_per_raw_sample"`
+	SampleRate          int            `json:"sample_rate"`
+	Channels            int            `json:"channels"`
+	Credits             []ArtistCredit `json:"credits"`
+	ReplayGain          ReplayGain     `json:"replay_gain"`
+	Lyrics              []LocalLyrics  `json:"lyrics"`
+	SourceFilePath      string         `json:"source_file_path"`
+	CueStart            float64        `json:"cue_start"`
+	CueEnd              float64        `json:"cue_end"`
 }
 
 type MetadataWithPlaycounts struct {
@@ -132,9 +145,13 @@ type Playcount struct {
 	LastPlayed         string `json:"last_played"`
 }
 
+// AudioCacheEntry is a cached transcode. SourceVersion identifies the version of the source file it was transcoded from,
+// and is empty for transcodes cached before versions were recorded.
 type AudioCacheEntry struct {
-	CacheKey     string    `json:"cache_key"`
-	LastAccessed time.Time `json:"last_accessed"`
+	CacheKey       string    `json:"cache_key"`
+	LastAccessed   time.Time `json:"last_accessed"`
+	SourceFilePath string    `json:"source_file_path"`
+	SourceVersion  string    `json:"source_version"`
 }
 
 type TopSongRow struct {
@@ -0,0 +1,15 @@
+package types
+
+// Waveform is the peak amplitude of each of Resolution equal slices of a track, from 0 for silence to 255 for full scale
+type Waveform struct {
+	Id         string  `xml:"id,attr" json:"id"`
+	Resolution int     `xml:"resolution,attr" json:"resolution"`
+	Duration   float64 `xml:"duration,attr" json:"duration"`
+	Peaks      []int   `xml:"peak" json:"peaks"`
+}
+
+// WaveformTrack is a version of a track whose waveform is precomputed after a scan, with the file it is computed from
+type WaveformTrack struct {
+	TrackId   string
+	MediaFile MediaFile
+}
@@ -0,0 +1,205 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"zene/core/config"
+	"zene/core/database"
+	"zene/core/logger"
+	"zene/core/scanner"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// refreshRequests is buffered so that Refresh never blocks, and several refreshes before the watch loop runs collapse into one
+var refreshRequests = make(chan struct{}, 1)
+
+// Refresh makes the watcher reload the enabled music folders, after one is created, updated or deleted
+func Refresh() {
+	select {
+	case refreshRequests <- struct{}{}:
+	default:
+	}
+}
+
+// Initialise starts watching every enabled music folder (recursively, using inotify on Linux)
+// and feeds debounced batches of changed paths into an incremental path scan
+func Initialise(ctx context.Context) {
+	if !config.WatchMusicDirs {
+		logger.Println("Watcher: music directory watching is disabled")
+		return
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Printf("Watcher: error creating filesystem watcher: %v", err)
+		return
+	}
+
+	roots, fileTypes := refreshWatches(ctx, fsWatcher, []string{})
+
+	go watchLoop(ctx, fsWatcher, roots, fileTypes)
+}
+
+func watchLoop(ctx context.Context, fsWatcher *fsnotify.Watcher, roots []string, fileTypes []string) {
+	defer fsWatcher.Close()
+
+	debounce := time.Duration(config.WatchDebounceSeconds) * time.Second
+	pendingPaths := map[string]bool{}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	logger.Printf("Watcher: watching music directories with a %s debounce", debounce)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Println("Watcher: stopping music directory watcher")
+			timer.Stop()
+			return
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Printf("Watcher: filesystem watcher error: %v", err)
+		case <-refreshRequests:
+			roots, fileTypes = refreshWatches(ctx, fsWatcher, roots)
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return
+			}
+			if !handleEvent(fsWatcher, event, fileTypes) {
+				continue
+			}
+			pendingPaths[filepath.Clean(event.Name)] = true
+			timer.Reset(debounce)
+		case <-timer.C:
+			paths := collapsePaths(pendingPaths)
+			pendingPaths = map[string]bool{}
+
+			logger.Printf("Watcher: scanning %d changed paths", len(paths))
+			err := scanner.RunPathScan(ctx, paths)
+			if errors.Is(err, scanner.ErrScanInProgress) {
+				// retry once the running scan has had time to finish
+				for _, path := range paths {
+					pendingPaths[path] = true
+				}
+				timer.Reset(debounce)
+			} else if err != nil {
+				logger.Printf("Watcher: error scanning changed paths: %v", err)
+			}
+		}
+	}
+}
+
+// handleEvent adds watches for newly created directories and reports whether the event should trigger a scan
+func handleEvent(fsWatcher *fsnotify.Watcher, event fsnotify.Event, fileTypes []string) bool {
+	switch {
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		// the path is gone, so we cannot tell whether it was a directory or an audio file
+		return true
+	case event.Has(fsnotify.Create), event.Has(fsnotify.Write):
+		fileInfo, err := os.Stat(event.Name)
+		if err != nil {
+			return false
+		}
+		if fileInfo.IsDir() {
+			if event.Has(fsnotify.Create) {
+				addDirectoryRecursive(fsWatcher, event.Name)
+			}
+			return true
+		}
+		return slices.Contains(fileTypes, filepath.Ext(event.Name)) || strings.EqualFold(filepath.Ext(event.Name), ".cue")
+	default:
+		return false
+	}
+}
+
+// refreshWatches watches the enabled music folders that are not watched yet and stops watching the ones that were removed or disabled.
+// It returns the watched folders and the file types scanned in any of them.
+func refreshWatches(ctx context.Context, fsWatcher *fsnotify.Watcher, oldRoots []string) ([]string, []string) {
+	musicFolders, err := database.GetEnabledMusicFolderSettings(ctx)
+	if err != nil {
+		logger.Printf("Watcher: error getting music folders: %v", err)
+		return oldRoots, config.AudioFileTypes
+	}
+
+	roots := []string{}
+	fileTypes := []string{}
+	for _, musicFolder := range musicFolders {
+		roots = append(roots, musicFolder.Path)
+		for _, fileType := range scanner.GetAudioFileTypes(musicFolder) {
+			if !slices.Contains(fileTypes, fileType) {
+				fileTypes = append(fileTypes, fileType)
+			}
+		}
+	}
+
+	for _, oldRoot := range oldRoots {
+		if slices.Contains(roots, oldRoot) {
+			continue
+		}
+		logger.Printf("Watcher: no longer watching %s", oldRoot)
+		for _, watchedPath := range fsWatcher.WatchList() {
+			if watchedPath == oldRoot || strings.HasPrefix(watchedPath, oldRoot+string(filepath.Separator)) {
+				if err := fsWatcher.Remove(watchedPath); err != nil {
+					logger.Printf("Watcher: error removing watch for %s: %v", watchedPath, err)
+				}
+			}
+		}
+	}
+
+	for _, root := range roots {
+		if !slices.Contains(oldRoots, root) {
+			addDirectoryRecursive(fsWatcher, root)
+		}
+	}
+
+	return roots, fileTypes
+}
+
+func addDirectoryRecursive(fsWatcher *fsnotify.Watcher, directory string) {
+	err := filepath.WalkDir(directory, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			logger.Printf("Watcher: error walking %s: %v", path, err)
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if err := fsWatcher.Add(path); err != nil {
+			logger.Printf("Watcher: error watching %s: %v", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Printf("Watcher: error adding watches for %s: %v", directory, err)
+	}
+}
+
+// collapsePaths returns the pending paths sorted, without any path that is inside another pending directory
+func collapsePaths(pendingPaths map[string]bool) []string {
+	paths := make([]string, 0, len(pendingPaths))
+	for path := range pendingPaths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	collapsed := []string{}
+	for _, path := range paths {
+		if len(collapsed) > 0 {
+			parent := collapsed[len(collapsed)-1]
+			if strings.HasPrefix(path, parent+string(filepath.Separator)) {
+				continue
+			}
+		}
+		collapsed = append(collapsed, path)
+	}
+	return collapsed
+}
@@ -0,0 +1,118 @@
+package waveform
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"zene/core/config"
+	"zene/core/ffmpeg"
+	"zene/core/io"
+	"zene/core/logger"
+	"zene/core/types"
+)
+
+// MaxResolution is the number of peaks a waveform is computed and cached with, and the most a client can ask for
+const MaxResolution = 2000
+
+// FileExtension is the extension of cached waveforms, which hold a byte for each of their MaxResolution peaks
+const FileExtension = ".peaks"
+
+// GetPeaks returns the waveform of a version of a track with a number of peaks, computing and caching it at MaxResolution
+// if it is not cached or its file has changed since it was cached
+func GetPeaks(ctx context.Context, trackId string, mediaFile types.MediaFile, resolution int) ([]byte, error) {
+	cachePath := getCachePath(trackId, mediaFile)
+
+	if isCached(cachePath, mediaFile.FilePath) {
+		peaks, err := os.ReadFile(cachePath)
+		if err == nil && len(peaks) == MaxResolution {
+			return downsample(peaks, resolution), nil
+		}
+		logger.Printf("Cached waveform %s cannot be read, computing it again: %v", cachePath, err)
+	}
+
+	peaks, err := computeAndSave(ctx, trackId, cachePath, mediaFile)
+	if err != nil {
+		return nil, err
+	}
+	return downsample(peaks, resolution), nil
+}
+
+// Precompute computes and caches the waveform of a version of a track unless it is already cached, and reports whether it was computed
+func Precompute(ctx context.Context, trackId string, mediaFile types.MediaFile) (bool, error) {
+	cachePath := getCachePath(trackId, mediaFile)
+	if isCached(cachePath, mediaFile.FilePath) {
+		return false, nil
+	}
+	_, err := computeAndSave(ctx, trackId, cachePath, mediaFile)
+	return err == nil, err
+}
+
+// GetCacheId returns the name, without FileExtension, of the cached waveform of a version of a track,
+// as versions of a track can differ in length
+func GetCacheId(trackId string, mediaFile types.MediaFile) string {
+	if mediaFile.VersionId != "" {
+		return trackId + "-" + mediaFile.VersionId
+	}
+	return trackId
+}
+
+func getCachePath(trackId string, mediaFile types.MediaFile) string {
+	return filepath.Join(config.WaveformFolder, GetCacheId(trackId, mediaFile)+FileExtension)
+}
+
+// isCached reports whether a waveform is cached, and was cached after its file last changed
+func isCached(cachePath string, filePath string) bool {
+	cacheInfo, err := os.Stat(cachePath)
+	if err != nil {
+		return false
+	}
+	fileChangedTime, err := io.GetChangedTime(filePath)
+	if err != nil {
+		return false
+	}
+	return cacheInfo.ModTime().After(fileChangedTime)
+}
+
+// computeAndSave computes a waveform at MaxResolution, waiting for a transcode slot, and writes it to a temporary file
+// that is renamed into place, so that a waveform that is being written is never read
+func computeAndSave(ctx context.Context, trackId string, cachePath string, mediaFile types.MediaFile) ([]byte, error) {
+	peaks, err := ffmpeg.GetWaveformPeaks(ctx, trackId, mediaFile, MaxResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	tempFile, err := os.CreateTemp(config.WaveformFolder, filepath.Base(cachePath)+"-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("creating waveform file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(peaks); err != nil {
+		tempFile.Close()
+		return nil, fmt.Errorf("writing waveform file: %v", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return nil, fmt.Errorf("closing waveform file: %v", err)
+	}
+	if err := os.Rename(tempFile.Name(), cachePath); err != nil {
+		return nil, fmt.Errorf("renaming waveform file: %v", err)
+	}
+	return peaks, nil
+}
+
+// downsample reduces peaks to a lower resolution, keeping the highest peak of each slice
+func downsample(peaks []byte, resolution int) []byte {
+	if resolution >= len(peaks) {
+		return peaks
+	}
+	downsampled := make([]byte, resolution)
+	for i := range downsampled {
+		start := i * len(peaks) / resolution
+		end := max((i+1)*len(peaks)/resolution, start+1)
+		for _, peak := range peaks[start:end] {
+			downsampled[i] = max(downsampled[i], peak)
+		}
+	}
+	return downsampled
+}
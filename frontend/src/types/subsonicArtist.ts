@@ -1,4 +1,5 @@
 import type { SubsonicAlbum } from '~/types/subsonicAlbum'
+import type { SubsonicSong } from '~/types/subsonicSong'
 
 export interface SubsonicIndexArtist {
   id: string
@@ -28,6 +29,7 @@ export interface SubsonicArtist {
   artistImageUrl: string
   albumCount: number
   album: SubsonicAlbum[]
+  appearsOn?: SubsonicSong[]
   musicBrainzId: string
   sortName: string
   userRating: number
@@ -5,6 +5,7 @@ go 1.26.4
 require (
 	github.com/NYTimes/gziphandler v1.1.1
 	github.com/djherbis/times v1.6.0
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	github.com/mmcdole/gofeed v1.3.0
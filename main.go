@@ -16,6 +16,7 @@ import (
 	"zene/core/io"
 	"zene/core/logger"
 	"zene/core/scheduler"
+	"zene/core/watcher"
 )
 
 func main() {
@@ -40,6 +41,7 @@ func main() {
 	ffmpeg.InitializeFfmpeg(ctx)
 
 	scheduler.Initialise(ctx)
+	watcher.Initialise(ctx)
 
 	server := StartServer()
 	go func() {
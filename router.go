@@ -68,6 +68,8 @@ func StartServer() *http.Server {
 	apiRouter.Handle("/rest/getartistlist", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetArtistList)))
 	apiRouter.Handle("/rest/getpodcastssse", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetPodcastsServerSentEvents)))
 	apiRouter.Handle("/rest/deleteaudiocache", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleDeleteAudioCache)))
+	apiRouter.Handle("/rest/gettranscodes", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetTranscodes)))
+	apiRouter.Handle("/rest/killtranscode", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleKillTranscode)))
 	apiRouter.Handle("/rest/getbutterchurnpresets", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetButterchurnPresets)))
 	apiRouter.Handle("/rest/getffversions", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetFfVersions)))
 	apiRouter.Handle("/rest/downloadffbinaries", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleDownloadFfBinaries)))
@@ -117,6 +119,11 @@ func StartServer() *http.Server {
 	// Media retrieval
 	apiRouter.Handle("/rest/stream", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleStream)))
 	apiRouter.Handle("/rest/download", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleDownload)))
+	apiRouter.Handle("/rest/gettranscodedecision", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetTranscodeDecision)))
+	apiRouter.Handle("/rest/gettranscodestream", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetTranscodeStream)))
+	apiRouter.Handle("/rest/hls.m3u8", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleHls)))
+	apiRouter.Handle("/rest/hlssegment", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleHlsSegment)))
+	apiRouter.Handle("/rest/getwaveform", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetWaveform)))
 	apiRouter.Handle("/rest/getcaptions", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetCaptions)))
 	apiRouter.Handle("/rest/getcoverart", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetCoverArt)))
 	apiRouter.Handle("/rest/getlyrics", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetLyrics)))
@@ -161,6 +168,11 @@ func StartServer() *http.Server {
 	apiRouter.Handle("/rest/createapikey", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleCreateApiKey)))
 	apiRouter.Handle("/rest/getapikeys", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetApiKeys)))
 	apiRouter.Handle("/rest/deleteapikey", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleDeleteApiKey)))
+	apiRouter.Handle("/rest/getplayers", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetPlayers)))
+	apiRouter.Handle("/rest/updateplayer", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleUpdatePlayer)))
+	apiRouter.Handle("/rest/deleteplayer", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleDeletePlayer)))
+	apiRouter.Handle("/rest/gettranscodesettings", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetTranscodeSettings)))
+	apiRouter.Handle("/rest/updatetranscodesettings", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleUpdateTranscodeSettings)))
 	// Bookmarks
 	apiRouter.Handle("/rest/createbookmark", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleCreateBookmark)))
 	apiRouter.Handle("/rest/getbookmarks", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetBookmarks)))
@@ -170,8 +182,19 @@ func StartServer() *http.Server {
 	apiRouter.Handle("/rest/getplayqueue", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetPlayqueue)))
 	apiRouter.Handle("/rest/getplayqueuebyindex", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetPlayqueueByIndex)))
 	apiRouter.Handle("/rest/getscanstatus", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetScanStatus)))
+	apiRouter.Handle("/rest/getscanresults", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetScanResults)))
 	// Media library scanning
 	apiRouter.Handle("/rest/startscan", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleStartScan)))
+	apiRouter.Handle("/rest/cancelscan", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleCancelScan)))
+	apiRouter.Handle("/rest/getmusicfoldersettings", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetMusicFolderSettings)))
+	apiRouter.Handle("/rest/createmusicfolder", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleCreateMusicFolder)))
+	apiRouter.Handle("/rest/updatemusicfolder", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleUpdateMusicFolder)))
+	apiRouter.Handle("/rest/deletemusicfolder", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleDeleteMusicFolder)))
+	// Scheduled jobs
+	apiRouter.Handle("/rest/getscheduledjobs", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleGetScheduledJobs)))
+	apiRouter.Handle("/rest/runscheduledjob", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleRunScheduledJob)))
+	apiRouter.Handle("/rest/pausescheduledjob", auth.AuthMiddleware(http.HandlerFunc(handlers.HandlePauseScheduledJob)))
+	apiRouter.Handle("/rest/resumescheduledjob", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleResumeScheduledJob)))
 	apiRouter.Handle("/rest/{unknownEndpoint}", auth.AuthMiddleware(http.HandlerFunc(handlers.HandleNotFound)))
 	/* cSpell:enable */
 